@@ -4,6 +4,7 @@ import (
 	"gin-demo/config"
 	"gin-demo/database"
 	"gin-demo/model/tool"
+	"gin-demo/pkg/encryption"
 	"gin-demo/pkg/logger"
 	"gin-demo/pkg/migration"
 )
@@ -16,11 +17,16 @@ func main() {
 	// 初始化日志
 	logger.InitLogger(cfg)
 
+	// 初始化字段加密
+	if err := encryption.Init(cfg); err != nil {
+		logger.Fatal("Failed to initialize encryption", logger.Err(err))
+	}
+
 	// 初始化数据库
 	database.InitDB()
 
 	// 创建迁移管理器
-	manager := migration.NewManager(tool.Registry)
+	manager := migration.NewManager(tool.Registry, tool.Seeders, tool.Migrations)
 
 	// 运行迁移命令
 	manager.RunCommand()
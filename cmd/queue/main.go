@@ -0,0 +1,30 @@
+package main
+
+import (
+	"gin-demo/config"
+	"gin-demo/database"
+	"gin-demo/pkg/logger"
+	"gin-demo/pkg/queue"
+)
+
+func main() {
+	// 初始化配置
+	config.InitConfig()
+	cfg := config.GetConfig()
+
+	// 初始化日志
+	logger.InitLogger(cfg)
+
+	// 初始化数据库和Redis
+	database.InitDB()
+
+	// 创建队列管理器（仅发布，不启动消费者）
+	manager, err := queue.NewManager(cfg.Queue)
+	if err != nil {
+		logger.Fatal("Failed to initialize queue manager", logger.Err(err))
+	}
+	defer manager.Close()
+
+	// 运行队列命令
+	manager.RunCommand()
+}
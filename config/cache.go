@@ -0,0 +1,14 @@
+package config
+
+import "time"
+
+// CacheConfig 仓储读缓存配置（Redis cache-aside）
+type CacheConfig struct {
+	Enabled     bool                     `mapstructure:"enabled"`
+	Prefix      string                   `mapstructure:"prefix"`       // 缓存键前缀
+	Version     string                   `mapstructure:"version"`      // 缓存命名空间版本，修改后全部缓存失效；为空时使用 app.version
+	DefaultTTL  time.Duration            `mapstructure:"default_ttl"`  // 默认缓存时间
+	NegativeTTL time.Duration            `mapstructure:"negative_ttl"` // 记录不存在时的缓存时间
+	LoadTimeout time.Duration            `mapstructure:"load_timeout"` // 未命中时从数据库加载的超时时间
+	TTLs        map[string]time.Duration `mapstructure:"ttls"`         // 按表名配置缓存时间
+}
@@ -10,12 +10,15 @@ import (
 
 // Config 主配置结构体
 type Config struct {
-	Server   *ServerConfig   `mapstructure:"server"`
-	Database *DatabaseConfig `mapstructure:"database"`
-	App      *AppConfig      `mapstructure:"app"`
-	JWT      *JWTConfig      `mapstructure:"jwt"`
-	Log      *LogConfig      `mapstructure:"log"`
-	Queue    *QueueConfig    `mapstructure:"queue"` // 添加这一行
+	Server     *ServerConfig     `mapstructure:"server"`
+	Database   *DatabaseConfig   `mapstructure:"database"`
+	App        *AppConfig        `mapstructure:"app"`
+	JWT        *JWTConfig        `mapstructure:"jwt"`
+	Log        *LogConfig        `mapstructure:"log"`
+	Queue      *QueueConfig      `mapstructure:"queue"` // 添加这一行
+	Cache      *CacheConfig      `mapstructure:"cache"`
+	Encryption *EncryptionConfig `mapstructure:"encryption"`
+	Retention  *RetentionConfig  `mapstructure:"retention"`
 }
 
 // Cfg 全局配置变量
@@ -2,13 +2,85 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
+// 支持的数据库驱动
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
-	MySQL *MySQLConfig `mapstructure:"mysql"`
-	Redis *RedisConfig `mapstructure:"redis"`
+	Driver      string             `mapstructure:"driver"` // 数据库驱动：mysql/postgres/sqlite，默认mysql
+	MySQL       *MySQLConfig       `mapstructure:"mysql"`
+	Postgres    *PostgresConfig    `mapstructure:"postgres"`
+	SQLite      *SQLiteConfig      `mapstructure:"sqlite"`
+	Redis       *RedisConfig       `mapstructure:"redis"`
+	Transaction *TransactionConfig `mapstructure:"transaction"`
+	Replicas    *ReplicaConfig     `mapstructure:"replicas"`
+	Migration   *MigrationConfig   `mapstructure:"migration"`
+}
+
+// MigrationConfig 数据库迁移配置
+type MigrationConfig struct {
+	AutoMigrate bool          `mapstructure:"auto_migrate"` // 启动时执行GORM AutoMigrate，生产环境建议关闭并使用 cmd/migrate up
+	Dir         string        `mapstructure:"dir"`          // 版本化迁移文件目录（create命令生成文件的位置），默认migrations
+	LockTimeout time.Duration `mapstructure:"lock_timeout"` // 多实例启动时等待迁移锁的超时时间，默认5分钟
+}
+
+// ShouldAutoMigrate 启动时是否执行AutoMigrate，未配置时保持开启
+func (c *DatabaseConfig) ShouldAutoMigrate() bool {
+	return c.Migration == nil || c.Migration.AutoMigrate
+}
+
+// GetMigrationLockTimeout 获取迁移锁等待超时时间
+func (c *DatabaseConfig) GetMigrationLockTimeout() time.Duration {
+	if c.Migration == nil || c.Migration.LockTimeout <= 0 {
+		return 5 * time.Minute
+	}
+	return c.Migration.LockTimeout
+}
+
+// GetMigrationDir 获取版本化迁移文件目录
+func (c *DatabaseConfig) GetMigrationDir() string {
+	if c.Migration == nil || c.Migration.Dir == "" {
+		return "migrations"
+	}
+	return c.Migration.Dir
+}
+
+// GetDriver 获取数据库驱动，默认mysql
+func (c *DatabaseConfig) GetDriver() string {
+	if c.Driver == "" {
+		return DriverMySQL
+	}
+	return strings.ToLower(c.Driver)
+}
+
+// ReplicaConfig MySQL只读副本配置（读写分离）
+type ReplicaConfig struct {
+	Sources             []ReplicaSourceConfig `mapstructure:"sources"`
+	Policy              string                `mapstructure:"policy"`                // 负载均衡策略：random/round_robin
+	HealthCheckInterval time.Duration         `mapstructure:"health_check_interval"` // 健康检查间隔
+	HealthCheckTimeout  time.Duration         `mapstructure:"health_check_timeout"`  // 单次健康检查超时
+}
+
+// ReplicaSourceConfig 只读副本连接配置，未设置的字段继承主库配置
+type ReplicaSourceConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	Database string `mapstructure:"database"`
+}
+
+// TransactionConfig 事务配置
+type TransactionConfig struct {
+	IsolationLevel string `mapstructure:"isolation_level"` // 默认隔离级别：read_committed/repeatable_read/serializable，为空使用数据库默认
 }
 
 // MySQLConfig MySQL配置
@@ -30,20 +102,55 @@ type MySQLConfig struct {
 	WriteTimeout    time.Duration `mapstructure:"write_timeout"`
 }
 
-// RedisConfig Redis配置
-type RedisConfig struct {
+// PostgresConfig PostgreSQL配置
+type PostgresConfig struct {
 	Host            string        `mapstructure:"host"`
 	Port            int           `mapstructure:"port"`
+	Username        string        `mapstructure:"username"`
 	Password        string        `mapstructure:"password"`
-	DB              int           `mapstructure:"db"`
-	PoolSize        int           `mapstructure:"pool_size"`
-	MinIdleConns    int           `mapstructure:"min_idle_conns"`
-	MaxRetries      int           `mapstructure:"max_retries"`
-	PoolTimeout     time.Duration `mapstructure:"pool_timeout"`
+	Database        string        `mapstructure:"database"`
+	SSLMode         string        `mapstructure:"ssl_mode"`
+	TimeZone        string        `mapstructure:"time_zone"`
+	MaxIdleConns    int           `mapstructure:"max_idle_conns"`
+	MaxOpenConns    int           `mapstructure:"max_open_conns"`
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
 	ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time"`
 	DialTimeout     time.Duration `mapstructure:"dial_timeout"`
-	ReadTimeout     time.Duration `mapstructure:"read_timeout"`
-	WriteTimeout    time.Duration `mapstructure:"write_timeout"`
+}
+
+// SQLiteConfig SQLite配置（适合本地开发和CI）
+type SQLiteConfig struct {
+	Path         string        `mapstructure:"path"`         // 数据库文件路径，":memory:" 为内存数据库
+	BusyTimeout  time.Duration `mapstructure:"busy_timeout"` // 数据库被锁定时的等待时间
+	MaxOpenConns int           `mapstructure:"max_open_conns"`
+}
+
+// Redis部署模式
+const (
+	RedisModeSingle   = "single"
+	RedisModeSentinel = "sentinel"
+	RedisModeCluster  = "cluster"
+)
+
+// RedisConfig Redis配置
+type RedisConfig struct {
+	Mode             string        `mapstructure:"mode"`              // 部署模式：single/sentinel/cluster，默认single
+	Host             string        `mapstructure:"host"`              // single模式地址
+	Port             int           `mapstructure:"port"`              // single模式端口
+	MasterName       string        `mapstructure:"master_name"`       // sentinel模式主节点名称
+	SentinelAddrs    []string      `mapstructure:"sentinel_addrs"`    // sentinel节点地址列表
+	SentinelPassword string        `mapstructure:"sentinel_password"` // sentinel节点密码
+	ClusterNodes     []string      `mapstructure:"cluster_nodes"`     // cluster模式节点地址列表
+	Password         string        `mapstructure:"password"`
+	DB               int           `mapstructure:"db"`
+	PoolSize         int           `mapstructure:"pool_size"`
+	MinIdleConns     int           `mapstructure:"min_idle_conns"`
+	MaxRetries       int           `mapstructure:"max_retries"`
+	PoolTimeout      time.Duration `mapstructure:"pool_timeout"`
+	ConnMaxIdleTime  time.Duration `mapstructure:"conn_max_idle_time"`
+	DialTimeout      time.Duration `mapstructure:"dial_timeout"`
+	ReadTimeout      time.Duration `mapstructure:"read_timeout"`
+	WriteTimeout     time.Duration `mapstructure:"write_timeout"`
 }
 
 // GetDSN 获取MySQL DSN
@@ -53,6 +160,74 @@ func (c *MySQLConfig) GetDSN() string {
 		c.DialTimeout, c.ReadTimeout, c.WriteTimeout)
 }
 
+// ForReplica 基于主库配置生成副本连接配置
+func (c *MySQLConfig) ForReplica(replica ReplicaSourceConfig) *MySQLConfig {
+	cfg := *c
+	cfg.Host = replica.Host
+	if replica.Port != 0 {
+		cfg.Port = replica.Port
+	}
+	if replica.Username != "" {
+		cfg.Username = replica.Username
+	}
+	if replica.Password != "" {
+		cfg.Password = replica.Password
+	}
+	if replica.Database != "" {
+		cfg.Database = replica.Database
+	}
+	return &cfg
+}
+
+// GetAddr 获取MySQL地址
+func (c *MySQLConfig) GetAddr() string {
+	return fmt.Sprintf("%s:%d", c.Host, c.Port)
+}
+
+// GetDSN 获取PostgreSQL DSN
+func (c *PostgresConfig) GetDSN() string {
+	sslMode := c.SSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		c.Host, c.Port, c.Username, c.Password, c.Database, sslMode)
+	if c.TimeZone != "" {
+		dsn += " TimeZone=" + c.TimeZone
+	}
+	if c.DialTimeout > 0 {
+		dsn += fmt.Sprintf(" connect_timeout=%d", int(c.DialTimeout.Seconds()))
+	}
+	return dsn
+}
+
+// GetDSN 获取SQLite DSN，开启外键约束和WAL模式
+func (c *SQLiteConfig) GetDSN() string {
+	path := c.Path
+	if path == "" {
+		path = "gin-demo.db"
+	}
+	busyTimeout := c.BusyTimeout
+	if busyTimeout <= 0 {
+		busyTimeout = 5 * time.Second
+	}
+
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return fmt.Sprintf("%s%s_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(%d)",
+		path, separator, busyTimeout.Milliseconds())
+}
+
+// GetMode 获取Redis部署模式，默认single
+func (c *RedisConfig) GetMode() string {
+	if c.Mode == "" {
+		return RedisModeSingle
+	}
+	return strings.ToLower(c.Mode)
+}
+
 // GetAddr 获取Redis地址
 func (c *RedisConfig) GetAddr() string {
 	return fmt.Sprintf("%s:%d", c.Host, c.Port)
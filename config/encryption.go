@@ -0,0 +1,10 @@
+package config
+
+// EncryptionConfig 字段级加密配置（AES-256-GCM）
+type EncryptionConfig struct {
+	Enabled       bool              `mapstructure:"enabled"`
+	ActiveKey     string            `mapstructure:"active_key"`      // 加密新数据使用的密钥ID
+	Keys          map[string]string `mapstructure:"keys"`            // 密钥ID -> base64编码的32字节密钥，轮换后保留旧密钥用于解密
+	BlindIndexKey string            `mapstructure:"blind_index_key"` // 盲索引HMAC密钥（base64），与加密密钥独立，不随加密密钥轮换
+	KeyFile       string            `mapstructure:"key_file"`        // 密钥文件（YAML/JSON，字段同上），配置后覆盖上面的密钥配置
+}
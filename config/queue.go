@@ -4,10 +4,28 @@ import (
 	"time"
 )
 
+// 队列后端驱动
+const (
+	QueueDriverRMQ    = "rmq"    // 基于Redis的rmq（默认）
+	QueueDriverMemory = "memory" // 进程内内存队列，用于单元测试和本地开发，重启后消息丢失
+)
+
 // QueueConfig 队列配置
 type QueueConfig struct {
+	Driver string                     `mapstructure:"driver" yaml:"driver"` // rmq / memory，默认rmq
 	RMQ    RMQConfig                  `mapstructure:"rmq" yaml:"rmq"`
 	Queues map[string]QueueItemConfig `mapstructure:"queues" yaml:"queues"`
+	Outbox OutboxConfig               `mapstructure:"outbox" yaml:"outbox"`
+}
+
+// OutboxConfig 事务发件箱投递配置
+type OutboxConfig struct {
+	PollInterval time.Duration `mapstructure:"poll_interval" yaml:"poll_interval"` // 轮询间隔（事务提交后也会立即唤醒）
+	BatchSize    int           `mapstructure:"batch_size" yaml:"batch_size"`       // 每批投递数量
+	MaxAttempts  int           `mapstructure:"max_attempts" yaml:"max_attempts"`   // 最大投递次数，超过后标记为failed
+	RetryBackoff time.Duration `mapstructure:"retry_backoff" yaml:"retry_backoff"` // 投递失败后的重试间隔（按次数线性增长）
+	LockTTL      time.Duration `mapstructure:"lock_ttl" yaml:"lock_ttl"`           // 投递锁有效期，保证同一时间只有一个实例投递
+	Retention    time.Duration `mapstructure:"retention" yaml:"retention"`         // 已投递消息保留时间
 }
 
 // RMQConfig RMQ配置
@@ -19,11 +37,100 @@ type RMQConfig struct {
 	ReportBatchSize int           `mapstructure:"report_batch_size" yaml:"report_batch_size"`
 	RetryLimit      int           `mapstructure:"retry_limit" yaml:"retry_limit"`
 	RetryDelay      time.Duration `mapstructure:"retry_delay" yaml:"retry_delay"`
+
+	DelayPollInterval time.Duration `mapstructure:"delay_poll_interval" yaml:"delay_poll_interval"` // 延迟消息到期检查间隔，默认1s
 }
 
 // QueueItemConfig 单个队列配置
 type QueueItemConfig struct {
-	Name          string `mapstructure:"name" yaml:"name"`
-	NumConsumers  int    `mapstructure:"num_consumers" yaml:"num_consumers"`
-	PrefetchLimit int    `mapstructure:"prefetch_limit" yaml:"prefetch_limit"`
+	Name          string             `mapstructure:"name" yaml:"name"`
+	NumConsumers  int                `mapstructure:"num_consumers" yaml:"num_consumers"`
+	PrefetchLimit int                `mapstructure:"prefetch_limit" yaml:"prefetch_limit"`
+	Retry         *RetryPolicyConfig `mapstructure:"retry" yaml:"retry"`     // 为空时使用 rmq.retry_limit / rmq.retry_delay
+	Timeout       time.Duration      `mapstructure:"timeout" yaml:"timeout"` // 单条消息处理超时，默认30s
+	Dedup         *DedupConfig       `mapstructure:"dedup" yaml:"dedup"`     // 消息去重，为空时不去重
+
+	// Priorities 优先级通道及消费权重（如 high: 10, normal: 3, low: 1），积压时按权重比例消费；为空时只有一个通道
+	Priorities map[string]int `mapstructure:"priorities" yaml:"priorities"`
+}
+
+const (
+	// DefaultPriority 默认优先级通道，未指定优先级的消息进入该通道
+	DefaultPriority = "normal"
+	// defaultPriorityWeight 未配置默认通道时的权重
+	defaultPriorityWeight = 1
+)
+
+// GetPriorities 获取优先级通道权重，配置了其他通道时总是包含默认的 normal 通道；未配置时返回nil
+func (c QueueItemConfig) GetPriorities() map[string]int {
+	if len(c.Priorities) == 0 {
+		return nil
+	}
+	priorities := make(map[string]int, len(c.Priorities)+1)
+	for priority, weight := range c.Priorities {
+		priorities[priority] = weight
+	}
+	if _, exists := priorities[DefaultPriority]; !exists {
+		priorities[DefaultPriority] = defaultPriorityWeight
+	}
+	return priorities
+}
+
+// DedupConfig 消息去重配置：按幂等键（默认消息ID）在Redis中记录处理状态
+type DedupConfig struct {
+	TTL         time.Duration `mapstructure:"ttl" yaml:"ttl"`                   // 处理完成状态的保存时长，默认24h
+	LockTimeout time.Duration `mapstructure:"lock_timeout" yaml:"lock_timeout"` // 处理中状态的超时时间，默认为处理超时
+}
+
+// defaultHandlerTimeout 默认消息处理超时
+const defaultHandlerTimeout = 30 * time.Second
+
+// GetTimeout 获取单条消息处理超时，默认30s
+func (c QueueItemConfig) GetTimeout() time.Duration {
+	if c.Timeout <= 0 {
+		return defaultHandlerTimeout
+	}
+	return c.Timeout
+}
+
+// defaultDedupTTL 默认处理完成状态的保存时长
+const defaultDedupTTL = 24 * time.Hour
+
+// GetDedupTTL 获取处理完成状态的保存时长，默认24h
+func (c QueueItemConfig) GetDedupTTL() time.Duration {
+	if c.Dedup == nil || c.Dedup.TTL <= 0 {
+		return defaultDedupTTL
+	}
+	return c.Dedup.TTL
+}
+
+// GetDedupLockTimeout 获取处理中状态的超时时间，默认与处理超时一致（处理器超时后其他消费者可以接手）
+func (c QueueItemConfig) GetDedupLockTimeout() time.Duration {
+	if c.Dedup == nil || c.Dedup.LockTimeout <= 0 {
+		return c.GetTimeout()
+	}
+	return c.Dedup.LockTimeout
+}
+
+// 重试退避策略
+const (
+	BackoffFixed             = "fixed"              // 固定间隔
+	BackoffExponential       = "exponential"        // 指数增长：delay * 2^n
+	BackoffExponentialJitter = "exponential_jitter" // 指数增长并在 [0, delay) 内随机（full jitter）
+)
+
+// RetryPolicyConfig 队列重试策略配置
+type RetryPolicyConfig struct {
+	MaxAttempts int           `mapstructure:"max_attempts" yaml:"max_attempts"` // 最大处理次数（含首次），1表示不重试
+	Backoff     string        `mapstructure:"backoff" yaml:"backoff"`           // fixed / exponential / exponential_jitter，默认fixed
+	Delay       time.Duration `mapstructure:"delay" yaml:"delay"`               // 首次重试间隔
+	MaxDelay    time.Duration `mapstructure:"max_delay" yaml:"max_delay"`       // 重试间隔上限，0表示不限制
+}
+
+// GetDriver 获取队列后端驱动，默认rmq
+func (c *QueueConfig) GetDriver() string {
+	if c.Driver == "" {
+		return QueueDriverRMQ
+	}
+	return c.Driver
 }
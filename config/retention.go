@@ -0,0 +1,39 @@
+package config
+
+import "time"
+
+// 保留策略动作
+const (
+	RetentionActionPurge     = "purge"     // 永久删除
+	RetentionActionAnonymize = "anonymize" // 保留记录，清除个人信息
+)
+
+// RetentionConfig 软删除记录保留策略配置
+type RetentionConfig struct {
+	Enabled   bool                       `mapstructure:"enabled"`
+	Schedule  string                     `mapstructure:"schedule"`   // 执行时间（cron表达式）
+	BatchSize int                        `mapstructure:"batch_size"` // 每批处理的记录数
+	Policies  map[string]RetentionPolicy `mapstructure:"policies"`   // 按表名配置保留策略
+}
+
+// RetentionPolicy 单表保留策略：软删除超过 After 的记录执行 Action
+type RetentionPolicy struct {
+	Action string        `mapstructure:"action"` // purge 或 anonymize
+	After  time.Duration `mapstructure:"after"`  // 软删除后的保留时间
+}
+
+// GetSchedule 获取执行时间，默认每天凌晨3点
+func (c *RetentionConfig) GetSchedule() string {
+	if c.Schedule == "" {
+		return "0 3 * * *"
+	}
+	return c.Schedule
+}
+
+// GetBatchSize 获取每批处理的记录数，默认500
+func (c *RetentionConfig) GetBatchSize() int {
+	if c.BatchSize <= 0 {
+		return 500
+	}
+	return c.BatchSize
+}
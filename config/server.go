@@ -10,6 +10,12 @@ type RateLimitConfig struct {
 	Window time.Duration `mapstructure:"window"`
 }
 
+// IdempotencyConfig 幂等键配置
+type IdempotencyConfig struct {
+	TTL         time.Duration `mapstructure:"ttl"`          // 响应缓存时长
+	LockTimeout time.Duration `mapstructure:"lock_timeout"` // 处理中状态的锁超时时间
+}
+
 // HTTP2Config HTTP/2配置
 type HTTP2Config struct {
 	MaxConcurrentStreams         uint32        `mapstructure:"max_concurrent_streams"`
@@ -23,15 +29,16 @@ type HTTP2Config struct {
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
-	Port            int           `mapstructure:"port"`
-	Mode            string        `mapstructure:"mode"`
-	ReadTimeout     time.Duration `mapstructure:"read_timeout"`
-	WriteTimeout    time.Duration `mapstructure:"write_timeout"`
-	EnableH2C       bool          `mapstructure:"enable_h2c"`
-	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
-	MaxHeaderBytes  int           `mapstructure:"max_header_bytes"`
-	MaxRequestSize  int64         `mapstructure:"max_request_size"`
-	HTTP2           HTTP2Config   `mapstructure:"http2"`
+	Port            int               `mapstructure:"port"`
+	Mode            string            `mapstructure:"mode"`
+	ReadTimeout     time.Duration     `mapstructure:"read_timeout"`
+	WriteTimeout    time.Duration     `mapstructure:"write_timeout"`
+	EnableH2C       bool              `mapstructure:"enable_h2c"`
+	ShutdownTimeout time.Duration     `mapstructure:"shutdown_timeout"`
+	MaxHeaderBytes  int               `mapstructure:"max_header_bytes"`
+	MaxRequestSize  int64             `mapstructure:"max_request_size"`
+	HTTP2           HTTP2Config       `mapstructure:"http2"`
+	Idempotency     IdempotencyConfig `mapstructure:"idempotency"`
 	RateLimit       struct {
 		Global RateLimitConfig `mapstructure:"global"`
 		Auth   RateLimitConfig `mapstructure:"auth"`
@@ -32,11 +32,55 @@ func (c *ServerConfig) Validate() error {
 
 // Validate 验证数据库配置
 func (c *DatabaseConfig) Validate() error {
-	if c.MySQL.Host == "" {
-		return errors.New("mysql host is required")
+	switch c.GetDriver() {
+	case DriverMySQL:
+		if c.MySQL == nil || c.MySQL.Host == "" {
+			return errors.New("mysql host is required")
+		}
+		if c.MySQL.Database == "" {
+			return errors.New("mysql database name is required")
+		}
+	case DriverPostgres:
+		if c.Postgres == nil || c.Postgres.Host == "" {
+			return errors.New("postgres host is required")
+		}
+		if c.Postgres.Database == "" {
+			return errors.New("postgres database name is required")
+		}
+	case DriverSQLite:
+		if c.SQLite == nil || c.SQLite.Path == "" {
+			return errors.New("sqlite path is required")
+		}
+	default:
+		return fmt.Errorf("unsupported database driver: %s", c.Driver)
 	}
-	if c.MySQL.Database == "" {
-		return errors.New("mysql database name is required")
+
+	if c.Redis != nil {
+		return c.Redis.Validate()
+	}
+	return nil
+}
+
+// Validate 验证Redis配置
+func (c *RedisConfig) Validate() error {
+	switch c.GetMode() {
+	case RedisModeSingle:
+		if c.Host == "" {
+			return errors.New("redis host is required")
+		}
+	case RedisModeSentinel:
+		if c.MasterName == "" {
+			return errors.New("redis master name is required in sentinel mode")
+		}
+		if len(c.SentinelAddrs) == 0 {
+			return errors.New("redis sentinel addrs are required in sentinel mode")
+		}
+	case RedisModeCluster:
+		if len(c.ClusterNodes) == 0 {
+			return errors.New("redis cluster nodes are required in cluster mode")
+		}
+	default:
+		return fmt.Errorf("unsupported redis mode: %s", c.Mode)
 	}
 	return nil
 }
@@ -1,13 +1,14 @@
 package controller
 
 import (
+	"errors"
 	"gin-demo/model"
 	"gin-demo/model/tool"
-	"gin-demo/pkg/auth"
 	"gin-demo/service"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type AuthController struct {
@@ -28,7 +29,7 @@ func (c *AuthController) Register(ctx *gin.Context) {
 		return
 	}
 
-	response, err := c.authService.Register(&req)
+	response, err := c.authService.Register(ctx.Request.Context(), &req)
 	if err != nil {
 		if err.Error() == "email already exists" {
 			ctx.JSON(http.StatusConflict, tool.ErrorResponse("邮箱已存在"))
@@ -49,7 +50,7 @@ func (c *AuthController) Login(ctx *gin.Context) {
 		return
 	}
 
-	response, err := c.authService.Login(&req)
+	response, err := c.authService.Login(ctx.Request.Context(), &req)
 	if err != nil {
 		if err.Error() == "invalid email or password" {
 			ctx.JSON(http.StatusUnauthorized, tool.ErrorResponse("邮箱或密码错误"))
@@ -70,7 +71,7 @@ func (c *AuthController) GetProfile(ctx *gin.Context) {
 		return
 	}
 
-	user, err := c.authService.GetCurrentUser(userID.(uint))
+	user, err := c.authService.GetCurrentUser(ctx.Request.Context(), userID.(uint))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, tool.ErrorResponse("获取用户信息失败: "+err.Error()))
 		return
@@ -87,15 +88,13 @@ func (c *AuthController) RefreshToken(ctx *gin.Context) {
 		return
 	}
 
-	userEmail, exists := ctx.Get("user_email")
-	if !exists {
-		ctx.JSON(http.StatusUnauthorized, tool.ErrorResponse("用户邮箱信息缺失"))
-		return
-	}
-
-	// 生成新的Token
-	token, expiresAt, err := auth.GenerateToken(userID.(uint), userEmail.(string))
+	// 生成新的Token（重新读取用户角色）
+	token, expiresAt, err := c.authService.RefreshToken(ctx.Request.Context(), userID.(uint))
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusUnauthorized, tool.ErrorResponse("用户不存在"))
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, tool.ErrorResponse("生成Token失败"))
 		return
 	}
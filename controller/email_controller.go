@@ -1,7 +1,9 @@
 package controller
 
 import (
+	"errors"
 	"gin-demo/model/tool"
+	"gin-demo/pkg/queue"
 	"gin-demo/service"
 	"net/http"
 	"strconv"
@@ -11,11 +13,13 @@ import (
 
 type EmailController struct {
 	emailService *service.EmailService
+	queueService *service.QueueService
 }
 
-func NewEmailController(emailService *service.EmailService) *EmailController {
+func NewEmailController(emailService *service.EmailService, queueService *service.QueueService) *EmailController {
 	return &EmailController{
 		emailService: emailService,
+		queueService: queueService,
 	}
 }
 
@@ -38,7 +42,7 @@ func (c *EmailController) SendEmail(ctx *gin.Context) {
 	}
 
 	// 发送邮件到队列
-	err := c.emailService.SendEmail(req.To, req.Subject, req.Body, req.IsHTML)
+	err := c.emailService.SendEmail(ctx.Request.Context(), req.To, req.Subject, req.Body, req.IsHTML)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, tool.ErrorResponse(err.Error()))
 		return
@@ -58,7 +62,7 @@ func (c *EmailController) SendTestEmail(ctx *gin.Context) {
 	isHTML, _ := strconv.ParseBool(isHTMLStr)
 
 	// 发送测试邮件
-	err := c.emailService.SendEmail([]string{to}, subject, body, isHTML)
+	err := c.emailService.SendEmail(ctx.Request.Context(), []string{to}, subject, body, isHTML)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, tool.ErrorResponse(err.Error()))
 		return
@@ -72,11 +76,17 @@ func (c *EmailController) SendTestEmail(ctx *gin.Context) {
 	}))
 }
 
-// GetEmailStatus 获取邮件队列状态（用于调试）
+// GetEmailStatus 获取邮件队列状态（待处理、处理中、已拒绝和死信数量，消费者数量，是否暂停）
 func (c *EmailController) GetEmailStatus(ctx *gin.Context) {
-	// 这里可以添加队列状态查询逻辑
-	ctx.JSON(http.StatusOK, tool.SuccessResponse("邮件队列状态", gin.H{
-		"status": "running",
-		"queue":  "email_queue",
-	}))
+	status, err := c.queueService.GetQueue(ctx.Request.Context(), queue.Email)
+	if err != nil {
+		if errors.Is(err, service.ErrQueueUnavailable) {
+			ctx.JSON(http.StatusServiceUnavailable, tool.ErrorResponse("队列服务不可用"))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, tool.ErrorResponse("获取邮件队列状态失败"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tool.SuccessResponse("邮件队列状态", status))
 }
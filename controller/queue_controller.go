@@ -0,0 +1,182 @@
+package controller
+
+import (
+	"errors"
+	"gin-demo/model/tool"
+	"gin-demo/pkg/queue"
+	"gin-demo/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type QueueController struct {
+	queueService *service.QueueService
+}
+
+func NewQueueController(queueService *service.QueueService) *QueueController {
+	return &QueueController{
+		queueService: queueService,
+	}
+}
+
+// ListQueues 获取所有队列的状态
+func (qc *QueueController) ListQueues(c *gin.Context) {
+	queues, err := qc.queueService.ListQueues(c.Request.Context())
+	if err != nil {
+		qc.handleError(c, err, "获取队列列表失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, tool.SuccessResponse("获取队列列表成功", queues))
+}
+
+// GetQueue 获取单个队列的状态
+func (qc *QueueController) GetQueue(c *gin.Context) {
+	status, err := qc.queueService.GetQueue(c.Request.Context(), c.Param("queue"))
+	if err != nil {
+		qc.handleError(c, err, "获取队列状态失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, tool.SuccessResponse("获取队列状态成功", status))
+}
+
+// PauseQueue 暂停队列消费（发布不受影响）
+func (qc *QueueController) PauseQueue(c *gin.Context) {
+	if err := qc.queueService.PauseQueue(c.Request.Context(), c.Param("queue")); err != nil {
+		qc.handleError(c, err, "暂停队列失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, tool.SuccessResponse("队列已暂停消费", nil))
+}
+
+// ResumeQueue 恢复队列消费
+func (qc *QueueController) ResumeQueue(c *gin.Context) {
+	if err := qc.queueService.ResumeQueue(c.Request.Context(), c.Param("queue")); err != nil {
+		qc.handleError(c, err, "恢复队列失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, tool.SuccessResponse("队列已恢复消费", nil))
+}
+
+// PeekMessages 分页查看队列中的消息（不出队），state 为 ready（默认）或 rejected，priority 指定优先级通道
+func (qc *QueueController) PeekMessages(c *gin.Context) {
+	var pagination tool.PaginationRequest
+	if err := c.ShouldBindQuery(&pagination); err != nil {
+		c.JSON(http.StatusBadRequest, tool.ErrorResponse("分页参数格式错误"))
+		return
+	}
+
+	state := c.DefaultQuery("state", queue.MessageStateReady)
+	result, err := qc.queueService.PeekMessages(c.Request.Context(), c.Param("queue"), state, c.Query("priority"), &pagination)
+	if err != nil {
+		qc.handleError(c, err, "获取队列消息失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, tool.PaginationSuccessResponse("获取队列消息成功", result.Data, result.Meta))
+}
+
+// PurgeQueue 清空队列中指定状态的消息，必须通过 state 指定 ready 或 rejected
+func (qc *QueueController) PurgeQueue(c *gin.Context) {
+	purged, err := qc.queueService.PurgeQueue(c.Request.Context(), c.Param("queue"), c.Query("state"))
+	if err != nil {
+		qc.handleError(c, err, "清空队列失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, tool.SuccessResponse("队列消息已清空", gin.H{"purged": purged}))
+}
+
+// GetDeadLetters 分页获取队列的死信
+func (qc *QueueController) GetDeadLetters(c *gin.Context) {
+	var pagination tool.PaginationRequest
+	if err := c.ShouldBindQuery(&pagination); err != nil {
+		c.JSON(http.StatusBadRequest, tool.ErrorResponse("分页参数格式错误"))
+		return
+	}
+
+	result, err := qc.queueService.GetDeadLetters(c.Request.Context(), c.Param("queue"), &pagination)
+	if err != nil {
+		qc.handleError(c, err, "获取死信列表失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, tool.PaginationSuccessResponse("获取死信列表成功", result.Data, result.Meta))
+}
+
+// GetDeadLetter 获取单条死信详情（失败原因、调用栈和重试历史）
+func (qc *QueueController) GetDeadLetter(c *gin.Context) {
+	message, err := qc.queueService.GetDeadLetter(c.Request.Context(), c.Param("queue"), c.Param("id"))
+	if err != nil {
+		qc.handleError(c, err, "获取死信失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, tool.SuccessResponse("获取死信成功", message))
+}
+
+// ReplayDeadLetter 重放单条死信
+func (qc *QueueController) ReplayDeadLetter(c *gin.Context) {
+	if err := qc.queueService.ReplayDeadLetter(c.Request.Context(), c.Param("queue"), c.Param("id")); err != nil {
+		qc.handleError(c, err, "重放死信失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, tool.SuccessResponse("死信已重新发布", nil))
+}
+
+// ReplayDeadLetters 重放队列的所有死信
+func (qc *QueueController) ReplayDeadLetters(c *gin.Context) {
+	replayed, err := qc.queueService.ReplayDeadLetters(c.Request.Context(), c.Param("queue"))
+	if err != nil {
+		qc.handleError(c, err, "重放死信失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, tool.SuccessResponse("死信已重新发布", gin.H{"replayed": replayed}))
+}
+
+// DeleteDeadLetter 删除单条死信
+func (qc *QueueController) DeleteDeadLetter(c *gin.Context) {
+	if err := qc.queueService.DeleteDeadLetter(c.Request.Context(), c.Param("queue"), c.Param("id")); err != nil {
+		qc.handleError(c, err, "删除死信失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, tool.SuccessResponse("死信已删除", nil))
+}
+
+// PurgeDeadLetters 清空队列的死信
+func (qc *QueueController) PurgeDeadLetters(c *gin.Context) {
+	purged, err := qc.queueService.PurgeDeadLetters(c.Request.Context(), c.Param("queue"))
+	if err != nil {
+		qc.handleError(c, err, "清空死信失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, tool.SuccessResponse("死信已清空", gin.H{"purged": purged}))
+}
+
+// handleError 将队列错误映射为HTTP状态码
+func (qc *QueueController) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrQueueNotFound):
+		c.JSON(http.StatusNotFound, tool.ErrorResponse("队列不存在"))
+	case errors.Is(err, queue.ErrDeadLetterNotFound):
+		c.JSON(http.StatusNotFound, tool.ErrorResponse("死信不存在"))
+	case errors.Is(err, queue.ErrInvalidMessageState):
+		c.JSON(http.StatusBadRequest, tool.ErrorResponse("消息状态只能是 ready 或 rejected"))
+	case errors.Is(err, queue.ErrUnknownPriority):
+		c.JSON(http.StatusBadRequest, tool.ErrorResponse("队列未配置该优先级"))
+	case errors.Is(err, queue.ErrNotReplayable):
+		c.JSON(http.StatusConflict, tool.ErrorResponse("消息无法解析，不可重放"))
+	case errors.Is(err, service.ErrQueueUnavailable):
+		c.JSON(http.StatusServiceUnavailable, tool.ErrorResponse("队列服务不可用"))
+	default:
+		c.JSON(http.StatusInternalServerError, tool.ErrorResponse(message))
+	}
+}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"gin-demo/model"
 	"gin-demo/model/tool"
 	"gin-demo/service"
@@ -8,6 +9,7 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type UserController struct {
@@ -29,7 +31,7 @@ func (uc *UserController) CreateUser(c *gin.Context) {
 	}
 
 	// 调用服务层创建用户
-	user, err := uc.userService.CreateUser(&req)
+	user, err := uc.userService.CreateUser(c.Request.Context(), &req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, tool.ErrorResponse("创建用户失败"))
 		return
@@ -47,7 +49,7 @@ func (uc *UserController) GetUser(c *gin.Context) {
 		return
 	}
 
-	user, err := uc.userService.GetUser(uint(id))
+	user, err := uc.userService.GetUser(c.Request.Context(), uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, tool.ErrorResponse("用户不存在"))
 		return
@@ -58,7 +60,7 @@ func (uc *UserController) GetUser(c *gin.Context) {
 
 // GetAllUsers 获取所有用户
 func (uc *UserController) GetAllUsers(c *gin.Context) {
-	users, err := uc.userService.GetAllUsers()
+	users, err := uc.userService.GetAllUsers(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, tool.ErrorResponse("获取用户列表失败"))
 		return
@@ -88,10 +90,10 @@ func (uc *UserController) GetUsersWithPagination(c *gin.Context) {
 
 	if keyword != "" {
 		// 如果有搜索关键词，使用搜索分页
-		result, err = uc.userService.SearchUsersWithPagination(&pagination, keyword)
+		result, err = uc.userService.SearchUsersWithPagination(c.Request.Context(), &pagination, keyword)
 	} else {
 		// 普通分页查询
-		result, err = uc.userService.GetUsersWithPagination(&pagination)
+		result, err = uc.userService.GetUsersWithPagination(c.Request.Context(), &pagination)
 	}
 
 	if err != nil {
@@ -117,7 +119,7 @@ func (uc *UserController) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	user, err := uc.userService.UpdateUser(uint(id), &req)
+	user, err := uc.userService.UpdateUser(c.Request.Context(), uint(id), &req)
 	if err != nil {
 		c.JSON(http.StatusNotFound, tool.ErrorResponse("用户不存在或更新失败"))
 		return
@@ -135,7 +137,7 @@ func (uc *UserController) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	err = uc.userService.DeleteUser(uint(id))
+	err = uc.userService.DeleteUser(c.Request.Context(), uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, tool.ErrorResponse("用户不存在或删除失败"))
 		return
@@ -143,3 +145,88 @@ func (uc *UserController) DeleteUser(c *gin.Context) {
 
 	c.JSON(http.StatusOK, tool.SuccessResponse("用户删除成功", nil))
 }
+
+// BatchUsers 批量创建/更新/删除用户
+func (uc *UserController) BatchUsers(c *gin.Context) {
+	var req model.BatchUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, tool.ErrorResponse("请求参数格式错误: "+err.Error()))
+		return
+	}
+
+	result, err := uc.userService.BatchUsers(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, tool.ErrorResponse("批量操作失败"))
+		return
+	}
+
+	if !result.Committed {
+		c.JSON(http.StatusUnprocessableEntity, tool.APIResponse{
+			Status:  "error",
+			Message: "批量操作失败，已全部回滚",
+			Data:    result,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, tool.SuccessResponse("批量操作完成", result))
+}
+
+// GetTrashedUsers 分页获取回收站中的用户
+func (uc *UserController) GetTrashedUsers(c *gin.Context) {
+	var pagination tool.PaginationRequest
+	if err := c.ShouldBindQuery(&pagination); err != nil {
+		c.JSON(http.StatusBadRequest, tool.ErrorResponse("分页参数格式错误"))
+		return
+	}
+
+	result, err := uc.userService.GetTrashedUsers(c.Request.Context(), &pagination)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, tool.ErrorResponse("获取回收站用户失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, tool.PaginationSuccessResponse("获取回收站用户成功", result.Data, result.Meta))
+}
+
+// RestoreUser 从回收站恢复用户
+func (uc *UserController) RestoreUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, tool.ErrorResponse("用户ID格式错误"))
+		return
+	}
+
+	user, err := uc.userService.RestoreUser(c.Request.Context(), uint(id))
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, tool.SuccessResponse("用户恢复成功", user))
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, tool.ErrorResponse("回收站中不存在该用户"))
+	case errors.Is(err, service.ErrUserConflict):
+		c.JSON(http.StatusConflict, tool.ErrorResponse("邮箱或手机号已被其他用户使用"))
+	case errors.Is(err, service.ErrUserAnonymized):
+		c.JSON(http.StatusConflict, tool.ErrorResponse("用户已匿名化，无法恢复"))
+	default:
+		c.JSON(http.StatusInternalServerError, tool.ErrorResponse("恢复用户失败"))
+	}
+}
+
+// ForceDeleteUser 永久删除回收站中的用户
+func (uc *UserController) ForceDeleteUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, tool.ErrorResponse("用户ID格式错误"))
+		return
+	}
+
+	err = uc.userService.ForceDeleteUser(c.Request.Context(), uint(id))
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, tool.SuccessResponse("用户已永久删除", nil))
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, tool.ErrorResponse("回收站中不存在该用户"))
+	default:
+		c.JSON(http.StatusInternalServerError, tool.ErrorResponse("永久删除用户失败"))
+	}
+}
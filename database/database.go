@@ -5,23 +5,28 @@ import (
 	"fmt"
 	"gin-demo/config"
 	"gin-demo/pkg/logger"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/glebarez/sqlite"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 var (
 	DB  *gorm.DB
-	RDB *redis.Client
+	RDB redis.UniversalClient
 )
 
 func InitDB() {
 	cfg := config.GetConfig()
 
-	// 初始化MySQL
-	initMySQL(cfg)
+	// 初始化关系型数据库（MySQL/PostgreSQL/SQLite）
+	initSQL(cfg)
 
 	// 初始化Redis
 	initRedis(cfg)
@@ -29,28 +34,87 @@ func InitDB() {
 	logger.Info("All databases initialized successfully")
 }
 
-func initMySQL(cfg *config.Config) {
+// poolConfig 连接池配置
+type poolConfig struct {
+	maxIdleConns    int
+	maxOpenConns    int
+	connMaxLifetime time.Duration
+	connMaxIdleTime time.Duration
+}
+
+// openDialector 根据驱动配置创建GORM方言及连接池配置
+func openDialector(cfg *config.DatabaseConfig) (gorm.Dialector, poolConfig, error) {
+	switch driver := cfg.GetDriver(); driver {
+	case config.DriverMySQL:
+		logger.Info("Connecting to MySQL",
+			logger.String("host", cfg.MySQL.Host),
+			logger.Int("port", cfg.MySQL.Port),
+			logger.String("database", cfg.MySQL.Database),
+		)
+		return mysql.Open(cfg.MySQL.GetDSN()), poolConfig{
+			maxIdleConns:    cfg.MySQL.MaxIdleConns,
+			maxOpenConns:    cfg.MySQL.MaxOpenConns,
+			connMaxLifetime: cfg.MySQL.ConnMaxLifetime,
+			connMaxIdleTime: cfg.MySQL.ConnMaxIdleTime,
+		}, nil
+
+	case config.DriverPostgres:
+		logger.Info("Connecting to PostgreSQL",
+			logger.String("host", cfg.Postgres.Host),
+			logger.Int("port", cfg.Postgres.Port),
+			logger.String("database", cfg.Postgres.Database),
+		)
+		return postgres.Open(cfg.Postgres.GetDSN()), poolConfig{
+			maxIdleConns:    cfg.Postgres.MaxIdleConns,
+			maxOpenConns:    cfg.Postgres.MaxOpenConns,
+			connMaxLifetime: cfg.Postgres.ConnMaxLifetime,
+			connMaxIdleTime: cfg.Postgres.ConnMaxIdleTime,
+		}, nil
+
+	case config.DriverSQLite:
+		logger.Info("Opening SQLite", logger.String("path", cfg.SQLite.Path))
+		// 确保数据库文件所在目录存在
+		if path := cfg.SQLite.Path; path != "" && !strings.HasPrefix(path, ":memory:") && !strings.HasPrefix(path, "file:") {
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return nil, poolConfig{}, fmt.Errorf("failed to create sqlite directory: %w", err)
+			}
+		}
+		// SQLite 同一时间只允许一个写连接，默认限制为1个连接避免 database is locked
+		maxOpenConns := cfg.SQLite.MaxOpenConns
+		if maxOpenConns <= 0 {
+			maxOpenConns = 1
+		}
+		return sqlite.Open(cfg.SQLite.GetDSN()), poolConfig{
+			maxIdleConns: maxOpenConns,
+			maxOpenConns: maxOpenConns,
+		}, nil
+
+	default:
+		return nil, poolConfig{}, fmt.Errorf("unsupported database driver: %s", driver)
+	}
+}
+
+func initSQL(cfg *config.Config) {
 	// 使用我们的GORM日志适配器
 	gormLogger := logger.NewGormLogger(cfg.Log.Database)
+	driver := cfg.Database.GetDriver()
 
-	// 数据库连接
-	dsn := cfg.Database.MySQL.GetDSN()
-	logger.Info("Connecting to MySQL",
-		logger.String("host", cfg.Database.MySQL.Host),
-		logger.Int("port", cfg.Database.MySQL.Port),
-		logger.String("database", cfg.Database.MySQL.Database),
-	)
+	dialector, pool, err := openDialector(cfg.Database)
+	if err != nil {
+		logger.Fatal("Failed to create database dialector", logger.Err(err))
+	}
 
-	var err error
-	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{
+	DB, err = gorm.Open(dialector, &gorm.Config{
 		Logger: gormLogger,
 		// 禁用外键约束检查（提高性能）
 		DisableForeignKeyConstraintWhenMigrating: true,
 		// 预编译语句缓存
 		PrepareStmt: true,
+		// 连接后手动Ping；同时避免副本不可用时打开副本连接失败
+		DisableAutomaticPing: true,
 	})
 	if err != nil {
-		logger.Fatal("Failed to connect to MySQL", logger.Err(err))
+		logger.Fatal("Failed to connect to database", logger.String("driver", driver), logger.Err(err))
 	}
 
 	// 获取底层sql.DB对象进行连接池配置
@@ -60,67 +124,148 @@ func initMySQL(cfg *config.Config) {
 	}
 
 	// 连接池配置
-	sqlDB.SetMaxIdleConns(cfg.Database.MySQL.MaxIdleConns)
-	sqlDB.SetMaxOpenConns(cfg.Database.MySQL.MaxOpenConns)
-	sqlDB.SetConnMaxLifetime(cfg.Database.MySQL.ConnMaxLifetime)
-	sqlDB.SetConnMaxIdleTime(cfg.Database.MySQL.ConnMaxIdleTime)
+	sqlDB.SetMaxIdleConns(pool.maxIdleConns)
+	sqlDB.SetMaxOpenConns(pool.maxOpenConns)
+	sqlDB.SetConnMaxLifetime(pool.connMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(pool.connMaxIdleTime)
 
 	// 测试连接
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	if err := sqlDB.PingContext(ctx); err != nil {
-		logger.Fatal("Failed to ping MySQL", logger.Err(err))
+		logger.Fatal("Failed to ping database", logger.String("driver", driver), logger.Err(err))
+	}
+
+	// 注册只读副本（读写分离，仅支持MySQL）
+	if driver == config.DriverMySQL {
+		if err := registerReplicas(DB, cfg.Database); err != nil {
+			logger.Fatal("Failed to register MySQL replicas", logger.Err(err))
+		}
+	} else if cfg.Database.Replicas != nil && len(cfg.Database.Replicas.Sources) > 0 {
+		logger.Warn("Read replicas are only supported for MySQL, ignored", logger.String("driver", driver))
 	}
 
-	logger.Info("MySQL connected successfully",
-		logger.Int("max_idle_conns", cfg.Database.MySQL.MaxIdleConns),
-		logger.Int("max_open_conns", cfg.Database.MySQL.MaxOpenConns),
+	logger.Info("Database connected successfully",
+		logger.String("driver", driver),
+		logger.Int("max_idle_conns", pool.maxIdleConns),
+		logger.Int("max_open_conns", pool.maxOpenConns),
 	)
 }
 
+// NewRedisClient 按部署模式创建Redis客户端：single/sentinel/cluster
+func NewRedisClient(cfg *config.RedisConfig) (redis.UniversalClient, error) {
+	switch mode := cfg.GetMode(); mode {
+	case config.RedisModeSingle:
+		return redis.NewClient(&redis.Options{
+			Addr:            cfg.GetAddr(),
+			Password:        cfg.Password,
+			DB:              cfg.DB,
+			PoolSize:        cfg.PoolSize,
+			MinIdleConns:    cfg.MinIdleConns,
+			MaxRetries:      cfg.MaxRetries,
+			PoolTimeout:     cfg.PoolTimeout,
+			ConnMaxIdleTime: cfg.ConnMaxIdleTime,
+			DialTimeout:     cfg.DialTimeout,
+			ReadTimeout:     cfg.ReadTimeout,
+			WriteTimeout:    cfg.WriteTimeout,
+		}), nil
+
+	case config.RedisModeSentinel:
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       cfg.MasterName,
+			SentinelAddrs:    cfg.SentinelAddrs,
+			SentinelPassword: cfg.SentinelPassword,
+			Password:         cfg.Password,
+			DB:               cfg.DB,
+			PoolSize:         cfg.PoolSize,
+			MinIdleConns:     cfg.MinIdleConns,
+			MaxRetries:       cfg.MaxRetries,
+			PoolTimeout:      cfg.PoolTimeout,
+			ConnMaxIdleTime:  cfg.ConnMaxIdleTime,
+			DialTimeout:      cfg.DialTimeout,
+			ReadTimeout:      cfg.ReadTimeout,
+			WriteTimeout:     cfg.WriteTimeout,
+		}), nil
+
+	case config.RedisModeCluster:
+		// Cluster模式不支持选择DB
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:           cfg.ClusterNodes,
+			Password:        cfg.Password,
+			PoolSize:        cfg.PoolSize,
+			MinIdleConns:    cfg.MinIdleConns,
+			MaxRetries:      cfg.MaxRetries,
+			PoolTimeout:     cfg.PoolTimeout,
+			ConnMaxIdleTime: cfg.ConnMaxIdleTime,
+			DialTimeout:     cfg.DialTimeout,
+			ReadTimeout:     cfg.ReadTimeout,
+			WriteTimeout:    cfg.WriteTimeout,
+		}), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported redis mode: %s", mode)
+	}
+}
+
+// IsRedisCluster 判断当前Redis是否为Cluster模式（多key操作需使用hash tag保证落在同一slot）
+func IsRedisCluster() bool {
+	_, ok := RDB.(*redis.ClusterClient)
+	return ok
+}
+
 func initRedis(cfg *config.Config) {
-	RDB = redis.NewClient(&redis.Options{
-		Addr:            fmt.Sprintf("%s:%d", cfg.Database.Redis.Host, cfg.Database.Redis.Port),
-		Password:        cfg.Database.Redis.Password,
-		DB:              cfg.Database.Redis.DB,
-		PoolSize:        cfg.Database.Redis.PoolSize,
-		MinIdleConns:    cfg.Database.Redis.MinIdleConns,
-		MaxRetries:      cfg.Database.Redis.MaxRetries,
-		PoolTimeout:     cfg.Database.Redis.PoolTimeout,
-		ConnMaxIdleTime: cfg.Database.Redis.ConnMaxIdleTime,
-		DialTimeout:     cfg.Database.Redis.DialTimeout,
-		ReadTimeout:     cfg.Database.Redis.ReadTimeout,
-		WriteTimeout:    cfg.Database.Redis.WriteTimeout,
-	})
+	redisCfg := cfg.Database.Redis
 
-	logger.Info("Connecting to Redis",
-		logger.String("host", cfg.Database.Redis.Host),
-		logger.Int("port", cfg.Database.Redis.Port),
-		logger.Int("db", cfg.Database.Redis.DB),
-	)
+	var err error
+	RDB, err = NewRedisClient(redisCfg)
+	if err != nil {
+		logger.Fatal("Failed to create Redis client", logger.Err(err))
+	}
+
+	switch redisCfg.GetMode() {
+	case config.RedisModeSentinel:
+		logger.Info("Connecting to Redis",
+			logger.String("mode", config.RedisModeSentinel),
+			logger.String("master_name", redisCfg.MasterName),
+			logger.Any("sentinel_addrs", redisCfg.SentinelAddrs),
+			logger.Int("db", redisCfg.DB),
+		)
+	case config.RedisModeCluster:
+		logger.Info("Connecting to Redis",
+			logger.String("mode", config.RedisModeCluster),
+			logger.Any("cluster_nodes", redisCfg.ClusterNodes),
+		)
+	default:
+		logger.Info("Connecting to Redis",
+			logger.String("mode", config.RedisModeSingle),
+			logger.String("host", redisCfg.Host),
+			logger.Int("port", redisCfg.Port),
+			logger.Int("db", redisCfg.DB),
+		)
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if cfg.Database.Redis.Password != "" {
-		if err := RDB.Do(ctx, "AUTH", cfg.Database.Redis.Password).Err(); err != nil {
-			logger.Fatal("Redis authentication failed", logger.Err(err))
-		}
-		logger.Info("Redis authentication successful")
-	}
-
+	// 密码通过客户端选项在建立连接时自动认证
 	if _, err := RDB.Ping(ctx).Result(); err != nil {
 		logger.Fatal("Failed to ping Redis", logger.Err(err))
 	}
 
 	logger.Info("Redis connected successfully",
-		logger.Int("pool_size", cfg.Database.Redis.PoolSize),
-		logger.Int("min_idle_conns", cfg.Database.Redis.MinIdleConns),
+		logger.Int("pool_size", redisCfg.PoolSize),
+		logger.Int("min_idle_conns", redisCfg.MinIdleConns),
 	)
 }
 
-func GetRedis() *redis.Client {
+// GetDB 获取数据库实例（用于依赖注入）
+func GetDB() *gorm.DB {
+	return DB
+}
+
+// GetRedis 获取Redis客户端（single/sentinel/cluster 统一接口）
+func GetRedis() redis.UniversalClient {
 	return RDB
 }
 
@@ -136,17 +281,22 @@ func CloseDB() error {
 		}
 	}
 
-	// 关闭MySQL连接
+	// 停止副本健康检查
+	if stopHealthCheck != nil {
+		stopHealthCheck()
+	}
+
+	// 关闭数据库连接
 	if DB != nil {
 		sqlDB, err := DB.DB()
 		if err != nil {
 			return err
 		}
 		if err := sqlDB.Close(); err != nil {
-			logger.Error("Error closing MySQL", logger.Err(err))
+			logger.Error("Error closing database", logger.Err(err))
 			return err
 		}
-		logger.Info("MySQL connection closed")
+		logger.Info("Database connection closed")
 	}
 
 	return nil
@@ -0,0 +1,196 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"gin-demo/config"
+	"gin-demo/pkg/logger"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+const (
+	// ReplicaPolicyRandom 随机选择副本
+	ReplicaPolicyRandom = "random"
+	// ReplicaPolicyRoundRobin 轮询选择副本
+	ReplicaPolicyRoundRobin = "round_robin"
+
+	defaultHealthCheckInterval = 10 * time.Second
+	defaultHealthCheckTimeout  = 2 * time.Second
+)
+
+type primaryContextKey struct{}
+
+// WithPrimary 强制后续查询走主库（读自己的写），用于写后立即读取或读后写的场景
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryContextKey{}, true)
+}
+
+// UsePrimary 判断context是否要求查询走主库
+func UsePrimary(ctx context.Context) bool {
+	v, _ := ctx.Value(primaryContextKey{}).(bool)
+	return v
+}
+
+// ReplicaPolicy 副本负载均衡策略：跳过不健康的副本，全部不可用时回退到主库
+type ReplicaPolicy struct {
+	policy    string
+	primary   gorm.ConnPool
+	counter   uint64
+	unhealthy sync.Map // gorm.ConnPool -> struct{}
+}
+
+// NewReplicaPolicy 创建副本负载均衡策略
+func NewReplicaPolicy(policy string, primary gorm.ConnPool) *ReplicaPolicy {
+	return &ReplicaPolicy{policy: policy, primary: primary}
+}
+
+// Resolve 实现 dbresolver.Policy
+func (p *ReplicaPolicy) Resolve(connPools []gorm.ConnPool) gorm.ConnPool {
+	candidates := make([]gorm.ConnPool, 0, len(connPools))
+	for _, pool := range connPools {
+		if pool == p.primary {
+			continue
+		}
+		if _, down := p.unhealthy.Load(pool); !down {
+			candidates = append(candidates, pool)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return p.primary
+	}
+
+	if p.policy == ReplicaPolicyRoundRobin {
+		n := atomic.AddUint64(&p.counter, 1)
+		return candidates[(n-1)%uint64(len(candidates))]
+	}
+	return candidates[rand.Intn(len(candidates))]
+}
+
+// SetHealthy 更新副本健康状态，返回状态是否发生变化
+func (p *ReplicaPolicy) SetHealthy(pool gorm.ConnPool, healthy bool) bool {
+	if healthy {
+		_, loaded := p.unhealthy.LoadAndDelete(pool)
+		return loaded
+	}
+	_, loaded := p.unhealthy.LoadOrStore(pool, struct{}{})
+	return !loaded
+}
+
+var stopHealthCheck context.CancelFunc
+
+// registerReplicas 注册只读副本：读请求按策略路由到副本，写请求和事务走主库
+func registerReplicas(db *gorm.DB, cfg *config.DatabaseConfig) error {
+	replicaCfg := cfg.Replicas
+	if replicaCfg == nil || len(replicaCfg.Sources) == 0 {
+		return nil
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	replicas := make([]gorm.Dialector, 0, len(replicaCfg.Sources)+1)
+	for _, source := range replicaCfg.Sources {
+		// 跳过版本查询，副本不可用时不影响启动，由健康检查剔除
+		replicas = append(replicas, mysql.New(mysql.Config{
+			DSN:                       cfg.MySQL.ForReplica(source).GetDSN(),
+			SkipInitializeWithVersion: true,
+		}))
+	}
+	// 主库作为兜底连接加入副本列表，所有副本不可用时由策略回退到主库
+	replicas = append(replicas, mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}))
+
+	policy := NewReplicaPolicy(replicaCfg.Policy, sqlDB)
+	resolver := dbresolver.Register(dbresolver.Config{
+		Replicas: replicas,
+		Policy:   policy,
+	}).
+		SetMaxIdleConns(cfg.MySQL.MaxIdleConns).
+		SetMaxOpenConns(cfg.MySQL.MaxOpenConns).
+		SetConnMaxLifetime(cfg.MySQL.ConnMaxLifetime).
+		SetConnMaxIdleTime(cfg.MySQL.ConnMaxIdleTime)
+
+	if err := db.Use(resolver); err != nil {
+		return err
+	}
+
+	// 按配置顺序收集副本连接，用于健康检查
+	nodes := make([]replicaNode, 0, len(replicaCfg.Sources))
+	resolver.Call(func(connPool gorm.ConnPool) error {
+		if replica, ok := connPool.(*sql.DB); ok && replica != sqlDB && len(nodes) < len(replicaCfg.Sources) {
+			source := replicaCfg.Sources[len(nodes)]
+			nodes = append(nodes, replicaNode{addr: cfg.MySQL.ForReplica(source).GetAddr(), db: replica})
+		}
+		return nil
+	})
+
+	timeout := replicaCfg.HealthCheckTimeout
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	interval := replicaCfg.HealthCheckInterval
+	if interval <= 0 {
+		interval = defaultHealthCheckInterval
+	}
+
+	// 启动时先检查一次，避免请求路由到不可用的副本
+	ctx, cancel := context.WithCancel(context.Background())
+	stopHealthCheck = cancel
+	checkReplicas(ctx, nodes, policy, timeout)
+	go runReplicaHealthCheck(ctx, nodes, policy, interval, timeout)
+
+	logger.Info("MySQL replicas registered",
+		logger.Int("replicas", len(nodes)),
+		logger.String("policy", replicaCfg.Policy),
+	)
+	return nil
+}
+
+// replicaNode 副本连接
+type replicaNode struct {
+	addr string
+	db   *sql.DB
+}
+
+// runReplicaHealthCheck 定期检查副本连通性，不健康的副本从负载均衡中剔除，恢复后重新加入
+func runReplicaHealthCheck(ctx context.Context, nodes []replicaNode, policy *ReplicaPolicy, interval, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			checkReplicas(ctx, nodes, policy, timeout)
+		}
+	}
+}
+
+// checkReplicas 对所有副本执行一次健康检查
+func checkReplicas(ctx context.Context, nodes []replicaNode, policy *ReplicaPolicy, timeout time.Duration) {
+	for _, node := range nodes {
+		pingCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := node.db.PingContext(pingCtx)
+		cancel()
+
+		if policy.SetHealthy(node.db, err == nil) {
+			if err != nil {
+				logger.Warn("MySQL replica unhealthy, ejected from pool",
+					logger.String("addr", node.addr), logger.Err(err))
+			} else {
+				logger.Info("MySQL replica recovered, rejoined pool",
+					logger.String("addr", node.addr))
+			}
+		}
+	}
+}
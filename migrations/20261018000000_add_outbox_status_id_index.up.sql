@@ -0,0 +1,2 @@
+-- 投递器按 status 过滤并按 id 排序读取待投递消息
+CREATE INDEX idx_outbox_messages_status_id ON outbox_messages (status, id);
@@ -0,0 +1 @@
+-- 盲索引需由应用计算，无需回滚：已删除用户恢复时会重新写入
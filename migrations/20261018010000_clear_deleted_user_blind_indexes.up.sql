@@ -0,0 +1,2 @@
+-- 软删除的用户不再占用邮箱、手机号唯一约束，恢复时由应用重新计算盲索引
+UPDATE users SET email_bidx = NULL, phone_bidx = NULL WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,2 @@
+-- 投递器检查同一聚合中是否存在更早的退避中消息
+CREATE INDEX idx_outbox_messages_aggregate ON outbox_messages (aggregate_type, aggregate_id, id);
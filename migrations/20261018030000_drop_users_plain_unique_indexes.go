@@ -0,0 +1,75 @@
+package migrations
+
+import (
+	"fmt"
+	"gin-demo/pkg/migration"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 初始版本的 users.email、users.phone 使用 unique 标签，自动迁移改为加密列后不会删除原有唯一约束：
+// 约束作用于明文或密文本身，已软删除用户的邮箱、手机号仍被占用（未启用加密时空手机号也会冲突），
+// 唯一性只应由盲索引列 email_bidx / phone_bidx 保证
+func init() {
+	Register(&migration.Migration{
+		Version: "20261018030000",
+		Name:    "drop_users_plain_unique_indexes",
+		Up:      dropUsersPlainUniqueIndexes,
+		Down:    restoreUsersPlainUniqueIndexes,
+	})
+}
+
+// usersPlainUniqueColumns 初始版本带唯一约束的列
+var usersPlainUniqueColumns = []string{"email", "phone"}
+
+// usersPlainUniqueName 初始版本唯一约束的名称：MySQL 以列名命名唯一索引，PostgreSQL 命名为 {表}_{列}_key
+// SQLite 的列级唯一约束为自动索引，自动迁移修改列时已随重建表删除，这里只处理按列名命名的唯一索引
+func usersPlainUniqueName(tx *gorm.DB, column string) string {
+	if tx.Dialector.Name() == "postgres" {
+		return fmt.Sprintf("users_%s_key", column)
+	}
+	return column
+}
+
+func dropUsersPlainUniqueIndexes(tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	for _, column := range usersPlainUniqueColumns {
+		name := usersPlainUniqueName(tx, column)
+		if tx.Dialector.Name() == "postgres" {
+			if migrator.HasConstraint("users", name) {
+				if err := migrator.DropConstraint("users", name); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if migrator.HasIndex("users", name) {
+			if err := migrator.DropIndex("users", name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func restoreUsersPlainUniqueIndexes(tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	for _, column := range usersPlainUniqueColumns {
+		name := usersPlainUniqueName(tx, column)
+		if tx.Dialector.Name() == "postgres" {
+			if !migrator.HasConstraint("users", name) {
+				if err := tx.Exec("ALTER TABLE users ADD CONSTRAINT ? UNIQUE (?)", clause.Column{Name: name}, clause.Column{Name: column}).Error; err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if !migrator.HasIndex("users", name) {
+			if err := tx.Exec("CREATE UNIQUE INDEX ? ON users (?)", clause.Column{Name: name}, clause.Column{Name: column}).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+// Package migrations 版本化迁移
+// SQL迁移文件命名为 {version}_{name}[.{driver}].{up|down}.sql，使用 go run cmd/migrate/main.go create <name> 生成；
+// 需要复杂数据转换时可在本包中通过 Register 注册Go迁移
+package migrations
+
+import (
+	"embed"
+	"gin-demo/pkg/migration"
+)
+
+// FS 嵌入的SQL迁移文件
+//
+//go:embed *.sql
+var FS embed.FS
+
+// goMigrations 本包中注册的Go迁移
+var goMigrations []*migration.Migration
+
+// Register 注册Go迁移（在init中调用）
+func Register(m *migration.Migration) {
+	goMigrations = append(goMigrations, m)
+}
+
+// Load 将SQL迁移和Go迁移加载到迁移执行器
+func Load(migrator *migration.Migrator) error {
+	for _, m := range goMigrations {
+		migrator.Register(m)
+	}
+	return migrator.LoadFS(FS)
+}
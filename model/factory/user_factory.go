@@ -0,0 +1,175 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+	"gin-demo/model"
+	"gin-demo/pkg/auth"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultPassword 工厂生成用户的明文密码（满足注册密码规则）
+const DefaultPassword = "Password123!"
+
+// familyNames 常见姓氏及拼音
+var familyNames = []struct{ hanzi, pinyin string }{
+	{"王", "wang"}, {"李", "li"}, {"张", "zhang"}, {"刘", "liu"}, {"陈", "chen"},
+	{"杨", "yang"}, {"赵", "zhao"}, {"黄", "huang"}, {"周", "zhou"}, {"吴", "wu"},
+	{"徐", "xu"}, {"孙", "sun"}, {"胡", "hu"}, {"朱", "zhu"}, {"高", "gao"},
+}
+
+// givenNames 常见名字及拼音
+var givenNames = []struct{ hanzi, pinyin string }{
+	{"伟", "wei"}, {"芳", "fang"}, {"娜", "na"}, {"敏", "min"}, {"静", "jing"},
+	{"强", "qiang"}, {"磊", "lei"}, {"洋", "yang"}, {"艳", "yan"}, {"勇", "yong"},
+	{"杰", "jie"}, {"娟", "juan"}, {"涛", "tao"}, {"明", "ming"}, {"超", "chao"},
+	{"秀英", "xiuying"}, {"建华", "jianhua"}, {"志强", "zhiqiang"}, {"海燕", "haiyan"}, {"晓东", "xiaodong"},
+}
+
+// emailDomains 邮箱域名（保留域名，不会投递到真实邮箱）
+var emailDomains = []string{"example.com", "example.org", "example.net"}
+
+// phonePrefixes 手机号段
+var phonePrefixes = []string{"130", "135", "138", "139", "150", "158", "166", "177", "186", "199"}
+
+var (
+	random   = rand.New(rand.NewSource(time.Now().UnixNano()))
+	randomMu sync.Mutex
+
+	// sequence 进程内递增序号，保证同一进程生成的邮箱和手机号唯一
+	// 起始值随机，降低多次执行填充时与已有数据冲突的概率
+	sequence = random.Int63n(1_000_000)
+
+	passwordOnce sync.Once
+	passwordHash string
+	passwordErr  error
+)
+
+// intn 并发安全的随机数
+func intn(n int) int {
+	randomMu.Lock()
+	defer randomMu.Unlock()
+	return random.Intn(n)
+}
+
+// hashedDefaultPassword 默认密码的哈希（bcrypt开销较大，只计算一次）
+func hashedDefaultPassword() (string, error) {
+	passwordOnce.Do(func() {
+		passwordHash, passwordErr = auth.HashPassword(DefaultPassword)
+	})
+	return passwordHash, passwordErr
+}
+
+// NewUser 生成一个未保存的随机用户，overrides 按顺序覆盖字段
+// 邮箱和11位手机号在进程内唯一，密码为 DefaultPassword 的哈希
+func NewUser(overrides ...func(*model.User)) (*model.User, error) {
+	password, err := hashedDefaultPassword()
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash default password: %w", err)
+	}
+
+	seq := atomic.AddInt64(&sequence, 1)
+	family := familyNames[intn(len(familyNames))]
+	given := givenNames[intn(len(givenNames))]
+
+	user := &model.User{
+		Name:     family.hanzi + given.hanzi,
+		Email:    fmt.Sprintf("%s.%s%d@%s", given.pinyin, family.pinyin, seq, emailDomains[intn(len(emailDomains))]),
+		Password: password,
+		Age:      18 + intn(48),
+		Phone:    fmt.Sprintf("%s%08d", phonePrefixes[intn(len(phonePrefixes))], seq%100_000_000),
+		Role:     model.RoleUser,
+	}
+
+	for _, override := range overrides {
+		override(user)
+	}
+	return user, nil
+}
+
+// NewUsers 生成 count 个未保存的随机用户
+func NewUsers(count int, overrides ...func(*model.User)) ([]*model.User, error) {
+	users := make([]*model.User, 0, count)
+	for i := 0; i < count; i++ {
+		user, err := NewUser(overrides...)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// CreateUser 生成并保存一个随机用户
+func CreateUser(ctx context.Context, db *gorm.DB, overrides ...func(*model.User)) (*model.User, error) {
+	users, err := CreateUsers(ctx, db, 1, overrides...)
+	if err != nil {
+		return nil, err
+	}
+	return users[0], nil
+}
+
+// CreateUsers 生成并批量保存 count 个随机用户
+func CreateUsers(ctx context.Context, db *gorm.DB, count int, overrides ...func(*model.User)) ([]*model.User, error) {
+	users, err := NewUsers(count, overrides...)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return users, nil
+	}
+	if err := db.WithContext(ctx).CreateInBatches(users, 100).Error; err != nil {
+		return nil, fmt.Errorf("failed to create users: %w", err)
+	}
+	return users, nil
+}
+
+// WithName 覆盖用户名
+func WithName(name string) func(*model.User) {
+	return func(u *model.User) {
+		u.Name = name
+	}
+}
+
+// WithEmail 覆盖邮箱
+func WithEmail(email string) func(*model.User) {
+	return func(u *model.User) {
+		u.Email = email
+	}
+}
+
+// WithPhone 覆盖手机号
+func WithPhone(phone string) func(*model.User) {
+	return func(u *model.User) {
+		u.Phone = phone
+	}
+}
+
+// WithAge 覆盖年龄
+func WithAge(age int) func(*model.User) {
+	return func(u *model.User) {
+		u.Age = age
+	}
+}
+
+// WithRole 覆盖角色
+func WithRole(role string) func(*model.User) {
+	return func(u *model.User) {
+		u.Role = role
+	}
+}
+
+// WithPassword 使用指定明文密码（每次调用都会计算bcrypt哈希，超过72字节时保留默认密码）
+func WithPassword(password string) func(*model.User) {
+	hashed, err := auth.HashPassword(password)
+	return func(u *model.User) {
+		if err == nil {
+			u.Password = hashed
+		}
+	}
+}
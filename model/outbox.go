@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+)
+
+// 发件箱消息状态
+const (
+	OutboxStatusPending = "pending" // 待投递
+	OutboxStatusSent    = "sent"    // 已投递
+	OutboxStatusFailed  = "failed"  // 超过最大重试次数
+)
+
+// OutboxMessage 事务发件箱消息：与业务数据在同一事务中写入，由 outbox.Relay 异步投递到队列
+type OutboxMessage struct {
+	ID            uint64     `json:"id" gorm:"primaryKey"`
+	AggregateType string     `json:"aggregate_type" gorm:"size:64;not null;default:'';comment:聚合类型"`
+	AggregateID   string     `json:"aggregate_id" gorm:"size:64;not null;default:'';comment:聚合ID，同一聚合的消息按写入顺序投递"`
+	Queue         string     `json:"queue" gorm:"size:64;not null;comment:目标队列"`
+	MessageType   string     `json:"message_type" gorm:"size:128;not null;comment:消息类型"`
+	Payload       string     `json:"payload" gorm:"type:text;not null;comment:队列消息JSON"`
+	Status        string     `json:"status" gorm:"size:16;not null;default:pending;index;comment:投递状态"`
+	Attempts      int        `json:"attempts" gorm:"not null;default:0;comment:投递次数"`
+	LastError     string     `json:"last_error" gorm:"type:text;comment:最近一次投递错误"`
+	AvailableAt   time.Time  `json:"available_at" gorm:"not null;comment:可投递时间（失败重试退避）"`
+	SentAt        *time.Time `json:"sent_at" gorm:"index;comment:投递时间"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"comment:创建时间"`
+}
+
+func (OutboxMessage) TableName() string {
+	return "outbox_messages"
+}
@@ -0,0 +1,54 @@
+package seeder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gin-demo/model"
+	"gin-demo/model/factory"
+	"gin-demo/pkg/encryption"
+
+	"gorm.io/gorm"
+)
+
+// AdminEmail 填充的管理员账号邮箱，密码为 factory.DefaultPassword
+const AdminEmail = "admin@example.com"
+
+// UserSeeder 用户填充器：一个固定的管理员账号和若干随机用户
+type UserSeeder struct {
+	Count int
+}
+
+// NewUserSeeder 创建用户填充器
+func NewUserSeeder(count int) *UserSeeder {
+	return &UserSeeder{Count: count}
+}
+
+func (s *UserSeeder) Name() string {
+	return "UserSeeder"
+}
+
+func (s *UserSeeder) Run(ctx context.Context, db *gorm.DB) error {
+	// 管理员账号已存在时只确保其角色，允许重复执行（邮箱加密存储，按盲索引匹配）
+	var admin model.User
+	err := db.WithContext(ctx).Where("email_bidx IN ?", encryption.BlindIndexes(AdminEmail)).First(&admin).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if _, err := factory.CreateUser(ctx, db,
+			factory.WithName("管理员"),
+			factory.WithEmail(AdminEmail),
+			factory.WithPhone("13800000000"),
+			factory.WithRole(model.RoleAdmin),
+		); err != nil {
+			return fmt.Errorf("failed to create admin user: %w", err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to check admin user: %w", err)
+	} else if !admin.IsAdmin() {
+		if err := db.WithContext(ctx).Model(&admin).Update("role", model.RoleAdmin).Error; err != nil {
+			return fmt.Errorf("failed to grant admin role: %w", err)
+		}
+	}
+
+	_, err = factory.CreateUsers(ctx, db, s.Count)
+	return err
+}
@@ -1,19 +1,42 @@
 package tool
 
 import (
+	"gin-demo/migrations"
 	"gin-demo/model"
+	"gin-demo/model/seeder"
 	"gin-demo/pkg/migration"
 )
 
 var Registry *migration.ModelRegistry
 
+// Seeders 数据填充器注册表
+var Seeders *migration.SeederRegistry
+
+// Migrations 版本化迁移（migrations 目录中的SQL文件和Go迁移）
+var Migrations *migration.Migrator
+
 // init 初始化模型注册表
 func init() {
 	Registry = migration.NewModelRegistry()
 	registerModels()
+
+	Seeders = migration.NewSeederRegistry()
+	registerSeeders()
+
+	// 迁移文件在编译时嵌入，加载失败属于代码错误
+	Migrations = migration.NewMigrator()
+	if err := migrations.Load(Migrations); err != nil {
+		panic(err)
+	}
 }
 
 // registerModels 注册需要自动迁移的模型
 func registerModels() {
 	Registry.Register(&model.User{})
+	Registry.Register(&model.OutboxMessage{})
+}
+
+// registerSeeders 注册数据填充器（seed 不指定 --class 时按注册顺序执行）
+func registerSeeders() {
+	Seeders.Register(seeder.NewUserSeeder(20))
 }
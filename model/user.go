@@ -1,6 +1,9 @@
 package model
 
 import (
+	"encoding/json"
+	"fmt"
+	"gin-demo/pkg/encryption"
 	"gin-demo/pkg/types"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
@@ -13,10 +16,18 @@ import (
 type User struct {
 	ID       uint   `json:"id" gorm:"primaryKey"`
 	Name     string `json:"name" gorm:"not null"`
-	Email    string `json:"email" gorm:"unique;not null"`
+	Email    string `json:"email" gorm:"size:255;not null;serializer:encrypted;comment:邮箱（加密）"`
 	Password string `json:"-" gorm:"not null"` // 密码字段，JSON序列化时忽略
 	Age      int    `json:"age"`
-	Phone    string `json:"phone" gorm:"type:varchar(11);unique;comment:手机号码;default:''"`
+	Phone    string `json:"phone" gorm:"size:255;serializer:encrypted;comment:手机号码（加密）;default:''"`
+	Role     string `json:"role" gorm:"size:16;not null;default:user;comment:角色"`
+
+	// 盲索引：加密列无法直接比较，精确匹配和唯一约束使用规范化值的HMAC，由 BeforeSave 维护
+	// 软删除的记录盲索引为NULL，不占用唯一约束，邮箱和手机号可重新注册
+	EmailBidx *string `json:"-" gorm:"size:255;uniqueIndex:idx_users_email_bidx;comment:邮箱盲索引"`
+	PhoneBidx *string `json:"-" gorm:"size:255;uniqueIndex:idx_users_phone_bidx;comment:手机号码盲索引"`
+
+	AnonymizedAt *time.Time `json:"-" gorm:"comment:匿名化时间"` // 保留策略清除个人信息的时间
 
 	// GORM默认字段放在最后，使用自定义序列化方法
 	CreatedAt types.JSONTime `json:"created_at" gorm:"comment:创建时间"`
@@ -28,6 +39,46 @@ func (User) TableName() string {
 	return "users"
 }
 
+// 用户角色
+const (
+	RoleUser  = "user"  // 普通用户（注册的默认角色）
+	RoleAdmin = "admin" // 管理员，可访问 /api/admin 下的接口
+)
+
+// IsAdmin 是否为管理员
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// BeforeSave 写入前更新盲索引
+func (u *User) BeforeSave(tx *gorm.DB) error {
+	if u.DeletedAt.Valid {
+		u.EmailBidx, u.PhoneBidx = nil, nil
+		return nil
+	}
+	u.EmailBidx = encryption.BlindIndex(u.Email)
+	u.PhoneBidx = encryption.BlindIndex(u.Phone)
+	return nil
+}
+
+// SanitizeForCache 写入读缓存前清除密码哈希，密码校验始终查询数据库
+func (u *User) SanitizeForCache() {
+	u.Password = ""
+}
+
+// AnonymizedName 匿名化后的用户名
+const AnonymizedName = "已注销用户"
+
+// Anonymize 清除个人信息，由保留策略对超期的软删除用户执行
+func (u *User) Anonymize() {
+	u.Name = AnonymizedName
+	u.Email = fmt.Sprintf("deleted-%d@anonymized.invalid", u.ID)
+	u.Phone = ""
+	u.Age = 0
+	u.Password = ""
+	u.EmailBidx, u.PhoneBidx = nil, nil
+}
+
 // RegisterRequest 注册请求
 type RegisterRequest struct {
 	Name     string `json:"name" binding:"required,min=2,max=50,alphaunicode"`
@@ -86,6 +137,13 @@ type UpdateUserRequest struct {
 	Phone string `json:"phone" binding:"omitempty,len=11"`
 }
 
+// TrashedUserResponse 回收站用户
+type TrashedUserResponse struct {
+	UserResponse
+	DeletedAt  types.JSONTime `json:"deleted_at"`
+	Anonymized bool           `json:"anonymized"` // 已匿名化的用户不可恢复
+}
+
 type UserResponse struct {
 	ID        uint           `json:"id"`
 	Name      string         `json:"name"`
@@ -99,5 +157,80 @@ type UserResponse struct {
 type JWTClaims struct {
 	UserID uint   `json:"user_id"`
 	Email  string `json:"email"`
+	Role   string `json:"role"`
 	jwt.RegisteredClaims
 }
+
+// 批量操作类型
+const (
+	BatchOpCreate = "create"
+	BatchOpUpdate = "update"
+	BatchOpDelete = "delete"
+)
+
+// 批量操作模式
+const (
+	BatchModeAllOrNothing = "all_or_nothing" // 任一操作失败则全部回滚
+	BatchModeBestEffort   = "best_effort"    // 失败的操作单独回滚，其余照常提交
+)
+
+// 批量操作结果状态
+const (
+	BatchStatusSuccess    = "success"
+	BatchStatusFailed     = "failed"
+	BatchStatusRolledBack = "rolled_back"
+	BatchStatusSkipped    = "skipped"
+)
+
+// BatchUserOperation 批量操作项，data 按操作类型解析为 CreateUserRequest 或 UpdateUserRequest
+type BatchUserOperation struct {
+	Op   string          `json:"op" binding:"required,oneof=create update delete"`
+	ID   uint            `json:"id"`
+	Data json.RawMessage `json:"data"`
+}
+
+// BatchUserRequest 批量操作请求
+type BatchUserRequest struct {
+	Mode       string               `json:"mode" binding:"omitempty,oneof=all_or_nothing best_effort"`
+	Operations []BatchUserOperation `json:"operations" binding:"required,min=1,max=500,dive"`
+}
+
+// GetMode 获取批量操作模式，默认为 all_or_nothing
+func (r *BatchUserRequest) GetMode() string {
+	if r.Mode == "" {
+		return BatchModeAllOrNothing
+	}
+	return r.Mode
+}
+
+// BatchUserResult 单个操作的执行结果
+type BatchUserResult struct {
+	Index  int           `json:"index"`
+	Op     string        `json:"op"`
+	ID     uint          `json:"id,omitempty"`
+	Status string        `json:"status"`
+	Error  string        `json:"error,omitempty"`
+	User   *UserResponse `json:"user,omitempty"`
+}
+
+// BatchUserResponse 批量操作响应
+type BatchUserResponse struct {
+	Mode      string            `json:"mode"`
+	Committed bool              `json:"committed"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Results   []BatchUserResult `json:"results"`
+}
+
+// CountResults 统计成功与失败的操作数量
+func (r *BatchUserResponse) CountResults() {
+	r.Succeeded, r.Failed = 0, 0
+	for _, result := range r.Results {
+		switch result.Status {
+		case BatchStatusSuccess:
+			r.Succeeded++
+		case BatchStatusFailed:
+			r.Failed++
+		}
+	}
+}
@@ -6,10 +6,15 @@ import (
 	"gin-demo/config"
 	"gin-demo/database"
 	"gin-demo/model/tool"
+	"gin-demo/pkg/cache"
 	"gin-demo/pkg/cron"
+	"gin-demo/pkg/encryption"
 	"gin-demo/pkg/logger"
 	"gin-demo/pkg/middleware"
+	"gin-demo/pkg/migration"
+	"gin-demo/pkg/outbox"
 	"gin-demo/pkg/queue"
+	"gin-demo/pkg/retention"
 	"gin-demo/pkg/server"
 	"gin-demo/router"
 	"github.com/gin-gonic/gin"
@@ -55,12 +60,28 @@ func (a *App) Initialize() error {
 		zap.Int("cpu_cores", runtime.NumCPU()),
 	)
 
+	// 初始化字段加密（须在读写数据库之前）
+	if err := encryption.Init(a.config); err != nil {
+		return fmt.Errorf("failed to initialize encryption: %w", err)
+	}
+
 	// 初始化数据库
 	database.InitDB()
 
-	// 自动迁移
-	if err := tool.Registry.AutoMigrate(); err != nil {
-		return fmt.Errorf("auto migration failed: %w", err)
+	// 自动迁移（生产环境可关闭 database.migration.auto_migrate，发布时执行 cmd/migrate up）
+	// 多实例滚动发布时通过数据库咨询锁保证只有一个实例执行DDL
+	if a.config.Database.ShouldAutoMigrate() {
+		if err := migration.MigrateWithLock(context.Background(), tool.Registry, tool.Migrations,
+			a.config.Database.GetMigrationLockTimeout()); err != nil {
+			return fmt.Errorf("auto migration failed: %w", err)
+		}
+	} else {
+		logger.Info("Auto migration disabled")
+	}
+
+	// 初始化仓储读缓存
+	if err := cache.Init(a.config); err != nil {
+		return fmt.Errorf("failed to initialize cache: %w", err)
 	}
 
 	// 初始化队列
@@ -68,6 +89,11 @@ func (a *App) Initialize() error {
 		return err
 	}
 
+	// 初始化数据保留策略（由定时任务执行）
+	if err := retention.Init(a.config, database.DB, tool.Registry.Models()); err != nil {
+		return fmt.Errorf("failed to initialize retention policies: %w", err)
+	}
+
 	// 初始化定时任务
 	cron.Init()
 
@@ -86,6 +112,9 @@ func (a *App) initQueue() error {
 		return fmt.Errorf("failed to register queue handlers: %w", err)
 	}
 
+	// 启动发件箱投递器
+	outbox.Init(a.config, queue.GetManager())
+
 	return nil
 }
 
@@ -133,6 +162,9 @@ func (a *App) shutdown() {
 	// 停止定时任务
 	cron.Stop()
 
+	// 停止发件箱投递器（需在队列管理器关闭前）
+	outbox.Stop()
+
 	// 关闭队列管理器
 	if queueManager := queue.GetManager(); queueManager != nil {
 		if err := queueManager.Close(); err != nil {
@@ -9,8 +9,8 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// GenerateToken 生成JWT Token
-func GenerateToken(userID uint, email string) (string, time.Time, error) {
+// GenerateToken 生成JWT Token，角色写入令牌供 RequireAdmin 校验
+func GenerateToken(userID uint, email, role string) (string, time.Time, error) {
 	cfg := config.GetConfig()
 
 	expiresAt := time.Now().Add(time.Duration(cfg.JWT.ExpiresHours) * time.Hour)
@@ -18,6 +18,7 @@ func GenerateToken(userID uint, email string) (string, time.Time, error) {
 	claims := &model.JWTClaims{
 		UserID: userID,
 		Email:  email,
+		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -73,7 +74,7 @@ func ValidateToken(tokenString string) (*model.JWTClaims, error) {
 }
 
 // GenerateRefreshToken 生成刷新Token
-func GenerateRefreshToken(userID uint, email string) (string, time.Time, error) {
+func GenerateRefreshToken(userID uint, email, role string) (string, time.Time, error) {
 	cfg := config.GetConfig()
 
 	expiresAt := time.Now().Add(time.Duration(cfg.JWT.RefreshExpiresHours) * time.Hour)
@@ -81,6 +82,7 @@ func GenerateRefreshToken(userID uint, email string) (string, time.Time, error)
 	claims := &model.JWTClaims{
 		UserID: userID,
 		Email:  email,
+		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -0,0 +1,284 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"gin-demo/config"
+	"gin-demo/database"
+	"gin-demo/pkg/encryption"
+	"gin-demo/pkg/logger"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+)
+
+const (
+	// notFoundMarker 记录不存在时写入的占位值（负缓存）
+	notFoundMarker = "\x00"
+
+	defaultPrefix      = "cache"
+	defaultTTL         = 10 * time.Minute
+	defaultNegativeTTL = 30 * time.Second
+	defaultLoadTimeout = 5 * time.Second
+)
+
+// Cache Redis cache-aside 缓存
+// 键格式：{prefix}:{version}:{table}:v{tableVersion}:{id}
+// version 为部署级命名空间（修改后全部失效），tableVersion 用于无法确定主键时整表失效
+type Cache struct {
+	rdb         redis.Cmdable
+	prefix      string
+	version     string
+	defaultTTL  time.Duration
+	negativeTTL time.Duration
+	loadTimeout time.Duration
+	ttls        map[string]time.Duration
+	group       singleflight.Group
+}
+
+var defaultCache *Cache
+
+// Sanitizer 实体实现后在写入缓存前调用，清除不应进入缓存的字段（如密码哈希）
+// 从缓存读取的实体不含这些字段，读后写须使用 database.WithPrimary 跳过缓存
+type Sanitizer interface {
+	SanitizeForCache()
+}
+
+// New 创建缓存实例
+func New(cfg *config.CacheConfig, version string, rdb redis.Cmdable) *Cache {
+	c := &Cache{
+		rdb:         rdb,
+		prefix:      cfg.Prefix,
+		version:     cfg.Version,
+		defaultTTL:  cfg.DefaultTTL,
+		negativeTTL: cfg.NegativeTTL,
+		loadTimeout: cfg.LoadTimeout,
+		ttls:        cfg.TTLs,
+	}
+	if c.prefix == "" {
+		c.prefix = defaultPrefix
+	}
+	if c.version == "" {
+		c.version = version
+	}
+	if c.defaultTTL <= 0 {
+		c.defaultTTL = defaultTTL
+	}
+	if c.negativeTTL <= 0 {
+		c.negativeTTL = defaultNegativeTTL
+	}
+	if c.loadTimeout <= 0 {
+		c.loadTimeout = defaultLoadTimeout
+	}
+	return c
+}
+
+// Init 初始化全局缓存并注册GORM失效回调，未启用时仓储直接查询数据库
+func Init(cfg *config.Config) error {
+	if cfg.Cache == nil || !cfg.Cache.Enabled {
+		defaultCache = nil
+		logger.Info("Repository cache disabled")
+		return nil
+	}
+
+	var appVersion string
+	if cfg.App != nil {
+		appVersion = cfg.App.Version
+	}
+	defaultCache = New(cfg.Cache, appVersion, database.GetRedis())
+
+	if err := RegisterCallbacks(database.GetDB()); err != nil {
+		return fmt.Errorf("failed to register cache callbacks: %w", err)
+	}
+
+	logger.Info("Repository cache initialized",
+		logger.String("prefix", defaultCache.prefix),
+		logger.String("version", defaultCache.version),
+		logger.Duration("default_ttl", defaultCache.defaultTTL),
+	)
+	return nil
+}
+
+// SetCache 设置全局缓存实例（nil表示禁用）
+func SetCache(c *Cache) {
+	defaultCache = c
+}
+
+// GetCache 获取全局缓存实例，未启用时返回nil
+func GetCache() *Cache {
+	return defaultCache
+}
+
+// TTL 获取表的缓存时间
+func (c *Cache) TTL(table string) time.Duration {
+	if ttl, ok := c.ttls[table]; ok && ttl > 0 {
+		return ttl
+	}
+	return c.defaultTTL
+}
+
+// namespace 表的缓存命名空间
+func (c *Cache) namespace(table string) string {
+	return fmt.Sprintf("%s:%s:%s", c.prefix, c.version, table)
+}
+
+// key 生成记录的缓存键
+func (c *Cache) key(ctx context.Context, table string, id interface{}) (string, error) {
+	tableVersion, err := c.rdb.Get(ctx, c.namespace(table)+":version").Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return "", err
+	}
+	return fmt.Sprintf("%s:v%d:%v", c.namespace(table), tableVersion, id), nil
+}
+
+// Invalidate 删除指定主键的缓存
+func (c *Cache) Invalidate(ctx context.Context, table string, ids ...interface{}) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	// 逐个删除：不同主键的键可能位于Cluster的不同slot，不能使用多key DEL
+	pipe := c.rdb.Pipeline()
+	for _, id := range ids {
+		key, err := c.key(ctx, table, id)
+		if err != nil {
+			return err
+		}
+		pipe.Del(ctx, key)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// InvalidateTable 递增表版本号，使该表的全部缓存失效
+func (c *Cache) InvalidateTable(ctx context.Context, table string) error {
+	return c.rdb.Incr(ctx, c.namespace(table)+":version").Err()
+}
+
+// Fetch 按主键读取缓存，未命中时通过loader加载并回填
+// 同一键的并发加载通过singleflight合并；loader返回 gorm.ErrRecordNotFound 时写入负缓存
+// 回填的加载读主库（database.WithPrimary），避免写入后立即读到落后副本的旧数据并缓存整个TTL；
+// 加载使用不随调用方取消的context和独立超时，某个调用方取消不影响等待同一键的其他调用方
+// Redis不可用时降级为直接调用loader
+func Fetch[T any](ctx context.Context, c *Cache, table string, id interface{}, loader func(ctx context.Context) (*T, error)) (*T, error) {
+	if c == nil {
+		return loader(ctx)
+	}
+
+	key, err := c.key(ctx, table, id)
+	if err != nil {
+		logger.Warn("Cache unavailable, falling back to database", logger.String("table", table), logger.Err(err))
+		return loader(ctx)
+	}
+
+	data, err := c.rdb.Get(ctx, key).Bytes()
+	if err == nil {
+		entity, err := decode[T](data)
+		if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity, err
+		}
+		// 结构变化导致无法解码时视为未命中，重新加载覆盖
+		logger.Warn("Cache decode failed, reloading", logger.String("key", key), logger.Err(err))
+	} else if !errors.Is(err, redis.Nil) {
+		logger.Warn("Cache read failed, falling back to database", logger.String("key", key), logger.Err(err))
+		return loader(ctx)
+	}
+
+	results := c.group.DoChan(key, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.loadTimeout)
+		defer cancel()
+
+		entity, err := loader(database.WithPrimary(ctx))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.store(ctx, key, []byte(notFoundMarker), c.negativeTTL)
+			return []byte(notFoundMarker), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if sanitizer, ok := any(entity).(Sanitizer); ok {
+			sanitizer.SanitizeForCache()
+		}
+		data, err := encode(entity)
+		if err != nil {
+			return nil, err
+		}
+		c.store(ctx, key, data, c.TTL(table))
+		return data, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-results:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		// 每个调用方各自解码，避免共享同一实例
+		return decode[T](result.Val.([]byte))
+	}
+}
+
+// store 写入缓存，失败只记录日志
+func (c *Cache) store(ctx context.Context, key string, data []byte, ttl time.Duration) {
+	if err := c.rdb.Set(ctx, key, data, ttl).Err(); err != nil {
+		logger.Warn("Cache write failed", logger.String("key", key), logger.Err(err))
+	}
+}
+
+// encode 使用gob序列化（保留完整时间精度）；启用字段加密时使用当前密钥加密整个缓存值，
+// 避免已解密的个人信息以明文形式存放在Redis中
+func encode(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+		return nil, err
+	}
+
+	keyring := encryption.GetKeyring()
+	if keyring == nil {
+		return buf.Bytes(), nil
+	}
+	ciphertext, err := keyring.Encrypt(base64.StdEncoding.EncodeToString(buf.Bytes()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt cache value: %w", err)
+	}
+	return []byte(ciphertext), nil
+}
+
+// decode 反序列化缓存值，负缓存返回 gorm.ErrRecordNotFound
+// 启用字段加密时拒绝明文缓存值（加密启用前写入），由调用方重新加载覆盖
+func decode[T any](data []byte) (*T, error) {
+	if string(data) == notFoundMarker {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	keyring := encryption.GetKeyring()
+	switch {
+	case encryption.IsEncrypted(string(data)):
+		if keyring == nil {
+			return nil, errors.New("encrypted cache value but field encryption is disabled")
+		}
+		plaintext, _, err := keyring.Decrypt(string(data))
+		if err != nil {
+			return nil, err
+		}
+		if data, err = base64.StdEncoding.DecodeString(plaintext); err != nil {
+			return nil, err
+		}
+	case keyring != nil:
+		return nil, errors.New("unencrypted cache value")
+	}
+
+	var entity T
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entity); err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
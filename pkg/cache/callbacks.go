@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"context"
+	"gin-demo/pkg/logger"
+	"gin-demo/pkg/transaction"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// RegisterCallbacks 注册GORM回调：创建、更新、删除后自动失效对应缓存
+func RegisterCallbacks(db *gorm.DB) error {
+	if err := db.Callback().Create().After("gorm:create").Register("cache:invalidate_create", invalidateCallback); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().After("gorm:update").Register("cache:invalidate_update", invalidateCallback); err != nil {
+		return err
+	}
+	return db.Callback().Delete().After("gorm:delete").Register("cache:invalidate_delete", invalidateCallback)
+}
+
+// invalidateCallback 按主键失效缓存，无法确定主键时递增表版本号
+// 在事务中执行时，提交后会再失效一次，避免提交前被并发读回填旧数据
+func invalidateCallback(db *gorm.DB) {
+	c := GetCache()
+	stmt := db.Statement
+	if c == nil || db.Error != nil || db.RowsAffected == 0 || stmt.Schema == nil || stmt.Table == "" {
+		return
+	}
+
+	table := stmt.Table
+	ids, ok := primaryKeys(stmt)
+	invalidate := func(ctx context.Context) {
+		var err error
+		if ok {
+			err = c.Invalidate(ctx, table, ids...)
+		} else {
+			err = c.InvalidateTable(ctx, table)
+		}
+		if err != nil {
+			logger.Warn("Cache invalidation failed", logger.String("table", table), logger.Err(err))
+		}
+	}
+
+	invalidate(stmt.Context)
+	if transaction.InTransaction(stmt.Context) {
+		transaction.AfterCommit(stmt.Context, invalidate)
+	}
+}
+
+// primaryKeys 从模型值或WHERE条件中提取受影响记录的主键
+func primaryKeys(stmt *gorm.Statement) ([]interface{}, bool) {
+	field := stmt.Schema.PrioritizedPrimaryField
+	if field == nil {
+		return nil, false
+	}
+
+	// 从模型值中提取
+	var ids []interface{}
+	switch rv := reflect.Indirect(stmt.ReflectValue); rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			value, zero := field.ValueOf(stmt.Context, reflect.Indirect(rv.Index(i)))
+			if zero {
+				ids = nil
+				break
+			}
+			ids = append(ids, value)
+		}
+	case reflect.Struct:
+		if value, zero := field.ValueOf(stmt.Context, rv); !zero {
+			ids = append(ids, value)
+		}
+	}
+	if len(ids) > 0 {
+		return ids, true
+	}
+
+	// 从WHERE条件中提取：顶层条件为AND关系，任一主键条件即可限定受影响范围
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok {
+			for _, expr := range where.Exprs {
+				switch e := expr.(type) {
+				case clause.IN:
+					if isPrimaryColumn(e.Column, field) && len(e.Values) > 0 {
+						return e.Values, true
+					}
+				case clause.Eq:
+					if isPrimaryColumn(e.Column, field) {
+						return []interface{}{e.Value}, true
+					}
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+// isPrimaryColumn 判断条件列是否为主键
+func isPrimaryColumn(column interface{}, field *schema.Field) bool {
+	switch col := column.(type) {
+	case clause.Column:
+		return col.Name == clause.PrimaryKey || col.Name == field.DBName
+	case string:
+		return col == field.DBName
+	}
+	return false
+}
@@ -1,7 +1,10 @@
 package cron
 
 import (
+	"context"
+	"gin-demo/config"
 	"gin-demo/pkg/logger"
+	"gin-demo/pkg/retention"
 	"time"
 
 	"github.com/robfig/cron/v3"
@@ -53,6 +56,14 @@ func addJobs() error {
 		return err
 	}
 
+	// 按保留策略清理软删除记录
+	if cfg := config.GetConfig(); cfg != nil && cfg.Retention != nil && cfg.Retention.Enabled {
+		if _, err := scheduler.AddFunc(cfg.Retention.GetSchedule(), enforceRetention); err != nil {
+			logger.Error("添加数据保留任务失败", zap.Error(err))
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -70,6 +81,13 @@ func statistics() {
 	logger.Info("数据统计完成")
 }
 
+// enforceRetention 数据保留任务：永久删除或匿名化超过保留时间的软删除记录
+func enforceRetention() {
+	logger.Info("开始执行数据保留策略")
+	retention.Run(context.Background())
+	logger.Info("数据保留策略执行完成")
+}
+
 // healthCheck 健康检查任务
 func healthCheck() {
 	logger.Info("系统健康检查", zap.String("time", time.Now().Format("15:04:05")))
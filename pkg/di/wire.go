@@ -6,6 +6,8 @@ package di
 import (
 	"fmt"
 	"gin-demo/controller"
+	"gin-demo/database"
+	"gin-demo/pkg/transaction"
 	"gin-demo/repository"
 	"gin-demo/service"
 	"github.com/google/wire"
@@ -13,7 +15,9 @@ import (
 
 // RepositorySet Repository 层的 Provider 集合
 var RepositorySet = wire.NewSet(
+	database.GetDB,
 	ProvideUserRepositoryWithLog,
+	transaction.NewManager,
 )
 
 // ServiceSet Service 层的 Provider 集合
@@ -21,6 +25,7 @@ var ServiceSet = wire.NewSet(
 	ProvideUserServiceWithLog,
 	service.NewAuthService,
 	service.NewEmailService,
+	service.NewQueueService,
 )
 
 // ControllerSet Controller 层的 Provider 集合
@@ -28,6 +33,7 @@ var ControllerSet = wire.NewSet(
 	ProvideUserControllerWithLog,
 	controller.NewAuthController,
 	controller.NewEmailController,
+	controller.NewQueueController,
 )
 
 // AllSet 所有 Provider 的集合
@@ -42,9 +48,11 @@ type Container struct {
 	UserController  *controller.UserController
 	AuthController  *controller.AuthController
 	EmailController *controller.EmailController
+	QueueController *controller.QueueController
 	UserService     *service.UserService
 	AuthService     *service.AuthService
 	EmailService    *service.EmailService
+	QueueService    *service.QueueService
 	UserRepository  *repository.UserRepository
 }
 
@@ -8,6 +8,8 @@ package di
 
 import (
 	"gin-demo/controller"
+	"gin-demo/database"
+	"gin-demo/pkg/transaction"
 	"gin-demo/repository"
 	"gin-demo/service"
 	"github.com/google/wire"
@@ -17,21 +19,27 @@ import (
 
 // InitializeContainer 初始化应用容器
 func InitializeContainer() *Container {
-	userRepository := repository.NewUserRepository()
+	db := database.GetDB()
+	userRepository := repository.NewUserRepository(db)
 	userService := service.NewUserService(userRepository)
 	userController := controller.NewUserController(userService)
-	authService := service.NewAuthService(userRepository)
+	manager := transaction.NewManager(db)
+	authService := service.NewAuthService(userRepository, manager)
 	authController := controller.NewAuthController(authService)
 	emailService := service.NewEmailService()
-	emailController := controller.NewEmailController(emailService)
+	queueService := service.NewQueueService()
+	emailController := controller.NewEmailController(emailService, queueService)
 	redisBasicService := service.NewRedisBasicService()
+	queueController := controller.NewQueueController(queueService)
 	container := &Container{
 		UserController:  userController,
 		AuthController:  authController,
 		EmailController: emailController,
+		QueueController: queueController,
 		UserService:     userService,
 		AuthService:     authService,
 		EmailService:    emailService,
+		QueueService:    queueService,
 		RedisService:    redisBasicService,
 		UserRepository:  userRepository,
 	}
@@ -41,13 +49,13 @@ func InitializeContainer() *Container {
 // wire.go:
 
 // RepositorySet Repository 层的 Provider 集合
-var RepositorySet = wire.NewSet(repository.NewUserRepository)
+var RepositorySet = wire.NewSet(database.GetDB, repository.NewUserRepository, transaction.NewManager)
 
 // ServiceSet Service 层的 Provider 集合
-var ServiceSet = wire.NewSet(service.NewUserService, service.NewAuthService, service.NewEmailService, service.NewRedisBasicService)
+var ServiceSet = wire.NewSet(service.NewUserService, service.NewAuthService, service.NewEmailService, service.NewRedisBasicService, service.NewQueueService)
 
 // ControllerSet Controller 层的 Provider 集合
-var ControllerSet = wire.NewSet(controller.NewUserController, controller.NewAuthController, controller.NewEmailController)
+var ControllerSet = wire.NewSet(controller.NewUserController, controller.NewAuthController, controller.NewEmailController, controller.NewQueueController)
 
 // AllSet 所有 Provider 的集合
 var AllSet = wire.NewSet(
@@ -61,9 +69,11 @@ type Container struct {
 	UserController  *controller.UserController
 	AuthController  *controller.AuthController
 	EmailController *controller.EmailController
+	QueueController *controller.QueueController
 	UserService     *service.UserService
 	AuthService     *service.AuthService
 	EmailService    *service.EmailService
+	QueueService    *service.QueueService
 	RedisService    *service.RedisBasicService
 	UserRepository  *repository.UserRepository
 }
@@ -0,0 +1,276 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gin-demo/config"
+	"gin-demo/pkg/logger"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ciphertextPrefix 密文前缀，格式：enc:v1:{keyID}:{base64(nonce|ciphertext)}
+// 不带前缀的值视为加密启用前写入的明文
+const ciphertextPrefix = "enc:v1:"
+
+// keySize AES-256 密钥长度
+const keySize = 32
+
+// ErrUnknownKey 密文使用的密钥ID未配置
+var ErrUnknownKey = errors.New("unknown encryption key")
+
+// 密钥环境变量，设置后覆盖配置文件中的对应项
+const (
+	EnvKeyFile       = "ENCRYPTION_KEY_FILE"
+	EnvActiveKey     = "ENCRYPTION_ACTIVE_KEY"
+	EnvKeys          = "ENCRYPTION_KEYS" // 格式：k1=base64,k2=base64
+	EnvBlindIndexKey = "ENCRYPTION_BLIND_INDEX_KEY"
+)
+
+// Keyring 加密密钥环：使用 active 密钥加密，按密文中的密钥ID解密
+type Keyring struct {
+	active     string
+	aeads      map[string]cipher.AEAD
+	blindIndex []byte
+}
+
+var defaultKeyring *Keyring
+
+// NewKeyring 根据配置创建密钥环，配置了 key_file 时从文件读取密钥
+func NewKeyring(cfg *config.EncryptionConfig) (*Keyring, error) {
+	if cfg.KeyFile != "" {
+		fileCfg, err := loadKeyFile(cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg = fileCfg
+	}
+
+	if len(cfg.Keys) == 0 {
+		return nil, fmt.Errorf("no encryption keys configured, set encryption.key_file, %s or %s", EnvKeyFile, EnvKeys)
+	}
+	if cfg.BlindIndexKey == "" {
+		return nil, fmt.Errorf("no blind index key configured, set encryption.key_file, %s or %s", EnvKeyFile, EnvBlindIndexKey)
+	}
+	if _, ok := cfg.Keys[cfg.ActiveKey]; !ok {
+		return nil, fmt.Errorf("active key %q not found in keys", cfg.ActiveKey)
+	}
+
+	k := &Keyring{active: cfg.ActiveKey, aeads: make(map[string]cipher.AEAD, len(cfg.Keys))}
+	for id, encoded := range cfg.Keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid key id %q", id)
+		}
+		key, err := decodeKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", id, err)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", id, err)
+		}
+		k.aeads[id] = aead
+	}
+
+	blindIndex, err := decodeKey(cfg.BlindIndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid blind index key: %w", err)
+	}
+	k.blindIndex = blindIndex
+	return k, nil
+}
+
+// withEnv 使用环境变量覆盖密钥配置，返回副本
+func withEnv(cfg *config.EncryptionConfig) (*config.EncryptionConfig, error) {
+	resolved := *cfg
+	if path := os.Getenv(EnvKeyFile); path != "" {
+		resolved.KeyFile = path
+	}
+	if active := os.Getenv(EnvActiveKey); active != "" {
+		resolved.ActiveKey = active
+	}
+	if blindIndexKey := os.Getenv(EnvBlindIndexKey); blindIndexKey != "" {
+		resolved.BlindIndexKey = blindIndexKey
+	}
+	if keys := os.Getenv(EnvKeys); keys != "" {
+		resolved.Keys = make(map[string]string)
+		for _, pair := range strings.Split(keys, ",") {
+			id, key, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid %s entry %q, expected id=base64key", EnvKeys, pair)
+			}
+			resolved.Keys[id] = key
+		}
+	}
+	return &resolved, nil
+}
+
+// loadKeyFile 读取密钥文件（格式由扩展名决定）
+func loadKeyFile(path string) (*config.EncryptionConfig, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	var cfg config.EncryptionConfig
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to decode key file: %w", err)
+	}
+	return &cfg, nil
+}
+
+// decodeKey 解码base64密钥并校验长度
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", keySize, len(key))
+	}
+	return key, nil
+}
+
+// Init 初始化全局密钥环，未启用时字段以明文存储；启用但未配置密钥时返回错误
+// 密钥来源优先级：环境变量 > key_file > 配置文件
+func Init(cfg *config.Config) error {
+	if cfg.Encryption == nil || !cfg.Encryption.Enabled {
+		defaultKeyring = nil
+		logger.Warn("Field encryption disabled, PII columns are stored in plaintext")
+		return nil
+	}
+
+	encryptionCfg, err := withEnv(cfg.Encryption)
+	if err != nil {
+		return err
+	}
+	keyring, err := NewKeyring(encryptionCfg)
+	if err != nil {
+		return err
+	}
+	defaultKeyring = keyring
+
+	logger.Info("Field encryption initialized",
+		logger.String("active_key", keyring.active),
+		logger.Int("keys", len(keyring.aeads)),
+	)
+	return nil
+}
+
+// SetKeyring 设置全局密钥环（nil表示禁用）
+func SetKeyring(k *Keyring) {
+	defaultKeyring = k
+}
+
+// GetKeyring 获取全局密钥环，未启用时返回nil
+func GetKeyring() *Keyring {
+	return defaultKeyring
+}
+
+// ActiveKey 当前加密使用的密钥ID
+func (k *Keyring) ActiveKey() string {
+	return k.active
+}
+
+// Encrypt 使用当前密钥加密，空字符串不加密
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	aead := k.aeads[k.active]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(k.active))
+	return ciphertextPrefix + k.active + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密，返回明文和加密所用的密钥ID；不带密文前缀的值按明文原样返回（keyID为空）
+func (k *Keyring) Decrypt(value string) (string, string, error) {
+	if !IsEncrypted(value) {
+		return value, "", nil
+	}
+
+	keyID, encoded, ok := strings.Cut(strings.TrimPrefix(value, ciphertextPrefix), ":")
+	if !ok {
+		return "", "", errors.New("malformed ciphertext")
+	}
+	aead, exists := k.aeads[keyID]
+	if !exists {
+		return "", keyID, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", keyID, fmt.Errorf("malformed ciphertext: %w", err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", keyID, errors.New("malformed ciphertext")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(keyID))
+	if err != nil {
+		return "", keyID, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return string(plaintext), keyID, nil
+}
+
+// BlindIndex 计算盲索引：规范化（去空格、小写）后的 HMAC-SHA256，用于加密列的精确匹配
+func (k *Keyring) BlindIndex(value string) string {
+	mac := hmac.New(sha256.New, k.blindIndex)
+	mac.Write([]byte(normalize(value)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// IsEncrypted 判断值是否为密文
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, ciphertextPrefix)
+}
+
+// BlindIndex 使用全局密钥环计算盲索引；空值返回nil（数据库中为NULL，不参与唯一约束）
+// 未启用加密时使用规范化的明文作为索引，启用后需执行 cmd/migrate reencrypt 重建
+func BlindIndex(value string) *string {
+	if value == "" {
+		return nil
+	}
+	var index string
+	if defaultKeyring == nil {
+		index = normalize(value)
+	} else {
+		index = defaultKeyring.BlindIndex(value)
+	}
+	return &index
+}
+
+// BlindIndexes 返回精确匹配时需比较的盲索引：启用加密时同时包含规范化明文，
+// 以匹配加密启用后尚未执行 reencrypt 的存量记录
+func BlindIndexes(value string) []string {
+	index := BlindIndex(value)
+	if index == nil {
+		return nil
+	}
+	if defaultKeyring == nil {
+		return []string{*index}
+	}
+	return []string{*index, normalize(value)}
+}
+
+// normalize 盲索引的规范化：邮箱等字段大小写不敏感
+func normalize(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
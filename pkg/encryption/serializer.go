@@ -0,0 +1,58 @@
+package encryption
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/schema"
+)
+
+// SerializerName 加密字段的GORM序列化器名称，用法：gorm:"serializer:encrypted"
+const SerializerName = "encrypted"
+
+func init() {
+	schema.RegisterSerializer(SerializerName, EncryptedSerializer{})
+}
+
+// EncryptedSerializer 加密字符串序列化器：写入时使用当前密钥加密，读取时按密钥ID解密
+// 字段在Go中仍为 string，对服务层透明；未启用加密时按明文读写
+type EncryptedSerializer struct{}
+
+// Scan 解密数据库中的值
+func (EncryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into encrypted field %s", dbValue, field.Name)
+	}
+
+	if value != "" && defaultKeyring != nil {
+		plaintext, _, err := defaultKeyring.Decrypt(value)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt field %s: %w", field.Name, err)
+		}
+		value = plaintext
+	} else if IsEncrypted(value) {
+		return fmt.Errorf("field %s is encrypted but encryption is disabled", field.Name)
+	}
+
+	return field.Set(ctx, dst, value)
+}
+
+// Value 加密字段值
+func (EncryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("encrypted field %s must be a string, got %T", field.Name, fieldValue)
+	}
+	if defaultKeyring == nil {
+		return value, nil
+	}
+	return defaultKeyring.Encrypt(value)
+}
@@ -2,10 +2,12 @@ package logger
 
 import (
 	"context"
+	"errors"
 	"gin-demo/config"
 	"time"
 
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
@@ -110,7 +112,7 @@ func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql
 	// 记录到数据库日志文件
 	if l.ZapLogger != nil {
 		switch {
-		case err != nil && l.LogLevel >= logger.Error && (!l.IgnoreRecordNotFoundError || err.Error() != "record not found"):
+		case err != nil && l.LogLevel >= logger.Error && (!l.IgnoreRecordNotFoundError || !errors.Is(err, gorm.ErrRecordNotFound)):
 			l.ZapLogger.Error("SQL Error", append(fields, zap.Error(err))...)
 		case elapsed > l.SlowThreshold && l.SlowThreshold != 0 && l.LogLevel >= logger.Warn:
 			l.ZapLogger.Warn("Slow SQL", append(fields, zap.Duration("threshold", l.SlowThreshold))...)
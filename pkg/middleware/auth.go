@@ -1,12 +1,17 @@
 package middleware
 
 import (
+	"errors"
+	"gin-demo/database"
+	"gin-demo/model"
 	"gin-demo/model/tool"
 	"gin-demo/pkg/auth"
+	"gin-demo/repository"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // JWTAuthMiddleware JWT认证中间件
@@ -48,11 +53,38 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 		// 将用户信息存储到上下文中
 		c.Set("user_id", claims.UserID)
 		c.Set("user_email", claims.Email)
+		c.Set("user_role", claims.Role)
 
 		c.Next()
 	}
 }
 
+// RequireAdmin 管理员权限中间件，须在 JWTAuthMiddleware 之后使用
+// 令牌中的角色只用于快速拒绝，最终以数据库中的角色为准（经用户读缓存，角色变更或用户删除时缓存随之失效），
+// 被降级或删除的管理员在令牌过期前也无法继续访问
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("user_role") != model.RoleAdmin {
+			c.JSON(http.StatusForbidden, tool.ErrorResponse("需要管理员权限"))
+			c.Abort()
+			return
+		}
+
+		user, err := repository.NewUserRepository(database.GetDB()).GetByID(c.Request.Context(), c.GetUint("user_id"))
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, tool.ErrorResponse("校验管理员权限失败"))
+			c.Abort()
+			return
+		}
+		if err != nil || user.Role != model.RoleAdmin {
+			c.JSON(http.StatusForbidden, tool.ErrorResponse("需要管理员权限"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
 // OptionalJWTAuthMiddleware 可选的JWT认证中间件（不强制要求认证）
 func OptionalJWTAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -64,6 +96,7 @@ func OptionalJWTAuthMiddleware() gin.HandlerFunc {
 				if claims, err := auth.ValidateToken(token); err == nil {
 					c.Set("user_id", claims.UserID)
 					c.Set("user_email", claims.Email)
+					c.Set("user_role", claims.Role)
 				}
 			}
 		}
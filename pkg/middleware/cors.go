@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// CORSMiddleware 跨域中间件，允许幂等键请求头并暴露幂等重放标记
+func CORSMiddleware() gin.HandlerFunc {
+	return cors.New(cors.Config{
+		AllowOrigins:     []string{"*"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", IdempotencyKeyHeader},
+		ExposeHeaders:    []string{"Content-Length", IdempotencyReplayedHeader},
+		AllowCredentials: true,
+		MaxAge:           12 * time.Hour,
+	})
+}
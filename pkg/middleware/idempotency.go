@@ -0,0 +1,261 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin-demo/config"
+	"gin-demo/database"
+	"gin-demo/model/tool"
+	"gin-demo/pkg/logger"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// IdempotencyKeyHeader 客户端传入的幂等键请求头
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotencyReplayedHeader 标记响应来自幂等缓存重放
+	IdempotencyReplayedHeader = "Idempotency-Replayed"
+
+	idempotencyStatusProcessing = "processing"
+	idempotencyStatusCompleted  = "completed"
+
+	defaultIdempotencyTTL         = 24 * time.Hour
+	defaultIdempotencyLockTimeout = 30 * time.Second
+	maxIdempotencyKeyLength       = 255
+)
+
+// idempotencySkipHeaders 重放时不保存的响应头（由下游中间件重新计算）
+var idempotencySkipHeaders = map[string]bool{
+	"Content-Length":    true,
+	"Content-Encoding":  true,
+	"Transfer-Encoding": true,
+	"Connection":        true,
+	"Date":              true,
+	"Vary":              true,
+}
+
+// idempotencyRecord 幂等记录（保存在Redis中）
+type idempotencyRecord struct {
+	Status      string              `json:"status"`
+	Fingerprint string              `json:"fingerprint"`
+	StatusCode  int                 `json:"status_code,omitempty"`
+	Headers     map[string][]string `json:"headers,omitempty"`
+	Body        []byte              `json:"body,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+}
+
+// idempotencyResponseWriter 捕获响应内容的Writer
+type idempotencyResponseWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *idempotencyResponseWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyResponseWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyMiddleware 幂等键中间件（仅对POST/PATCH请求生效）
+// 首次请求的响应（状态码、响应头、响应体）会按 用户+幂等键 保存到Redis，
+// 重试时直接重放；并发重复请求返回409，同一幂等键携带不同请求内容返回422
+func IdempotencyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodPost && c.Request.Method != http.MethodPatch {
+			c.Next()
+			return
+		}
+
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, tool.ErrorResponse("幂等键长度不能超过255个字符"))
+			c.Abort()
+			return
+		}
+
+		rdb := database.GetRedis()
+		if rdb == nil {
+			// Redis不可用时退化为普通请求
+			c.Next()
+			return
+		}
+
+		fingerprint, err := requestFingerprint(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, tool.ErrorResponse("读取请求体失败"))
+			c.Abort()
+			return
+		}
+
+		ttl, lockTimeout := getIdempotencyTTL()
+		ctx := c.Request.Context()
+		redisKey := idempotencyRedisKey(c, key)
+
+		// 抢占处理权：只有第一个请求能写入processing状态
+		processing := &idempotencyRecord{
+			Status:      idempotencyStatusProcessing,
+			Fingerprint: fingerprint,
+			CreatedAt:   time.Now(),
+		}
+		data, _ := json.Marshal(processing)
+		acquired, err := rdb.SetNX(ctx, redisKey, data, lockTimeout).Result()
+		if err != nil {
+			logger.Error("Idempotency lock failed", logger.Err(err), logger.String("key", redisKey))
+			c.Next()
+			return
+		}
+
+		if !acquired {
+			handleExistingIdempotencyRecord(c, rdb, redisKey, fingerprint)
+			return
+		}
+
+		writer := &idempotencyResponseWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = writer
+
+		c.Next()
+
+		statusCode := writer.Status()
+		// 服务端错误不缓存，允许客户端使用同一幂等键重试
+		if statusCode >= http.StatusInternalServerError {
+			if err := rdb.Del(context.Background(), redisKey).Err(); err != nil {
+				logger.Error("Failed to release idempotency key", logger.Err(err), logger.String("key", redisKey))
+			}
+			return
+		}
+
+		completed := &idempotencyRecord{
+			Status:      idempotencyStatusCompleted,
+			Fingerprint: fingerprint,
+			StatusCode:  statusCode,
+			Headers:     filterIdempotencyHeaders(writer.Header()),
+			Body:        writer.body.Bytes(),
+			CreatedAt:   processing.CreatedAt,
+		}
+		data, _ = json.Marshal(completed)
+		if err := rdb.Set(context.Background(), redisKey, data, ttl).Err(); err != nil {
+			logger.Error("Failed to store idempotent response", logger.Err(err), logger.String("key", redisKey))
+		}
+	}
+}
+
+// handleExistingIdempotencyRecord 处理已存在的幂等记录：重放、冲突或参数不一致
+func handleExistingIdempotencyRecord(c *gin.Context, rdb redis.UniversalClient, redisKey, fingerprint string) {
+	raw, err := rdb.Get(c.Request.Context(), redisKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		// 记录恰好过期或被释放，提示客户端重试
+		c.JSON(http.StatusConflict, tool.ErrorResponse("请求正在处理中，请稍后重试"))
+		c.Abort()
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to load idempotency record", logger.Err(err), logger.String("key", redisKey))
+		c.JSON(http.StatusInternalServerError, tool.ErrorResponse("服务器内部错误"))
+		c.Abort()
+		return
+	}
+
+	var record idempotencyRecord
+	if err := json.Unmarshal(raw, &record); err != nil {
+		logger.Error("Invalid idempotency record", logger.Err(err), logger.String("key", redisKey))
+		c.JSON(http.StatusInternalServerError, tool.ErrorResponse("服务器内部错误"))
+		c.Abort()
+		return
+	}
+
+	if record.Fingerprint != fingerprint {
+		c.JSON(http.StatusUnprocessableEntity, tool.ErrorResponse("幂等键已被用于不同的请求内容"))
+		c.Abort()
+		return
+	}
+
+	if record.Status != idempotencyStatusCompleted {
+		c.JSON(http.StatusConflict, tool.ErrorResponse("相同幂等键的请求正在处理中"))
+		c.Abort()
+		return
+	}
+
+	// 重放首次响应
+	for name, values := range record.Headers {
+		for _, value := range values {
+			c.Writer.Header().Add(name, value)
+		}
+	}
+	c.Header(IdempotencyReplayedHeader, "true")
+	c.Data(record.StatusCode, c.Writer.Header().Get("Content-Type"), record.Body)
+	c.Abort()
+}
+
+// requestFingerprint 计算请求指纹（方法 + 路径 + 请求体）
+func requestFingerprint(c *gin.Context) (string, error) {
+	var body []byte
+	if c.Request.Body != nil {
+		var err error
+		body, err = io.ReadAll(c.Request.Body)
+		if err != nil {
+			return "", err
+		}
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(c.Request.Method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(c.Request.URL.RequestURI()))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// idempotencyRedisKey 幂等键的Redis键：按用户隔离，未登录时按客户端IP隔离
+func idempotencyRedisKey(c *gin.Context, key string) string {
+	subject := "ip:" + c.ClientIP()
+	if userID, exists := c.Get("user_id"); exists {
+		subject = fmt.Sprintf("user:%v", userID)
+	}
+	return fmt.Sprintf("idempotency:%s:%s", subject, key)
+}
+
+// filterIdempotencyHeaders 过滤需要保存的响应头
+func filterIdempotencyHeaders(header http.Header) map[string][]string {
+	headers := make(map[string][]string, len(header))
+	for name, values := range header {
+		if idempotencySkipHeaders[name] {
+			continue
+		}
+		headers[name] = append([]string(nil), values...)
+	}
+	return headers
+}
+
+// getIdempotencyTTL 获取幂等记录的缓存时长和锁超时时间
+func getIdempotencyTTL() (time.Duration, time.Duration) {
+	ttl, lockTimeout := defaultIdempotencyTTL, defaultIdempotencyLockTimeout
+	if cfg := config.GetConfig(); cfg != nil && cfg.Server != nil {
+		if cfg.Server.Idempotency.TTL > 0 {
+			ttl = cfg.Server.Idempotency.TTL
+		}
+		if cfg.Server.Idempotency.LockTimeout > 0 {
+			lockTimeout = cfg.Server.Idempotency.LockTimeout
+		}
+	}
+	return ttl, lockTimeout
+}
@@ -6,12 +6,14 @@ import (
 	"gin-demo/config"
 	"gin-demo/database"
 	"gin-demo/model/tool"
+	"math/rand"
 	"net/http"
 	"strconv"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 )
 
 // RedisRateLimiter Redis限流器
@@ -114,42 +116,52 @@ func CustomRateLimitMiddleware(limit int, window time.Duration) gin.HandlerFunc
 	}
 }
 
+// rateLimitScript 滑动窗口限流脚本，单key操作，兼容Redis Cluster
+var rateLimitScript = redis.NewScript(`
+	local key = KEYS[1]
+	local window_start = tonumber(ARGV[1])
+	local now = tonumber(ARGV[2])
+	local limit = tonumber(ARGV[3])
+	local member = ARGV[4]
+	local ttl = tonumber(ARGV[5])
+
+	-- 清理过期数据
+	redis.call('ZREMRANGEBYSCORE', key, 0, window_start)
+
+	-- 获取当前计数
+	local current = redis.call('ZCARD', key)
+
+	if current < limit then
+		-- 添加当前请求
+		redis.call('ZADD', key, now, member)
+		redis.call('PEXPIRE', key, ttl)
+		return 1
+	else
+		return 0
+	end
+`)
+
+// rateLimitKey 限流Redis键，使用hash tag保证同一客户端的键落在同一slot
+func rateLimitKey(key string) string {
+	return fmt.Sprintf("rate_limit:{%s}", key)
+}
+
 // Allow 检查是否允许请求（使用Redis滑动窗口算法）
 // 优化限流算法，使用滑动窗口
 func (rl *RedisRateLimiter) Allow(ctx context.Context, key string) bool {
-	now := time.Now().Unix()
-	windowStart := now - int64(rl.window.Seconds())
+	now := time.Now().UnixMilli()
+	windowStart := now - rl.window.Milliseconds()
+	// 同一毫秒内的多个请求需要不同的成员
+	member := fmt.Sprintf("%d-%d", now, rand.Int63())
 
 	// 使用Lua脚本保证原子性
-	luaScript := `
-		local key = KEYS[1]
-		local window_start = ARGV[1]
-		local now = ARGV[2]
-		local limit = tonumber(ARGV[3])
-		
-		-- 清理过期数据
-		redis.call('ZREMRANGEBYSCORE', key, 0, window_start)
-		
-		-- 获取当前计数
-		local current = redis.call('ZCARD', key)
-		
-		if current < limit then
-			-- 添加当前请求
-			redis.call('ZADD', key, now, now)
-			redis.call('EXPIRE', key, 3600)
-			return 1
-		else
-			return 0
-		end
-	`
-
-	result, err := database.GetRedis().Eval(ctx, luaScript, []string{key},
-		windowStart, now, rl.limit).Result()
+	result, err := rateLimitScript.Run(ctx, database.GetRedis(), []string{rateLimitKey(key)},
+		windowStart, now, rl.limit, member, rl.window.Milliseconds()).Int64()
 	if err != nil {
 		return false
 	}
 
-	return result.(int64) == 1
+	return result == 1
 }
 
 // GetRemainingRequests 获取剩余请求次数
@@ -160,7 +172,7 @@ func (rl *RedisRateLimiter) GetRemainingRequests(ctx context.Context, key string
 		return rl.limit // Redis未初始化时返回最大值
 	}
 
-	redisKey := fmt.Sprintf("rate_limit:%s", key)
+	redisKey := rateLimitKey(key)
 
 	// 当前时间戳（毫秒）
 	now := time.Now().UnixMilli()
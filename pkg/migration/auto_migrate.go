@@ -9,6 +9,7 @@ import (
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
 )
 
 // ModelInterface 模型接口
@@ -34,7 +35,8 @@ func (mr *ModelRegistry) getDB() *gorm.DB {
 		logger.Error("数据库未初始化")
 		return nil
 	}
-	return database.DB
+	// 迁移和表结构检查始终走主库
+	return database.DB.Clauses(dbresolver.Write)
 }
 
 // Register 注册模型
@@ -43,6 +45,11 @@ func (mr *ModelRegistry) Register(model interface{}) {
 	logger.Info("注册模型", zap.String("model", getModelName(model)))
 }
 
+// Models 获取已注册的模型
+func (mr *ModelRegistry) Models() []interface{} {
+	return mr.models
+}
+
 // AutoMigrate 自动迁移所有注册的模型
 func (mr *ModelRegistry) AutoMigrate() error {
 	db := mr.getDB()
@@ -111,13 +118,13 @@ func (mr *ModelRegistry) createIndexes(model interface{}, db *gorm.DB) error {
 
 		// 检查是否需要创建索引
 		if strings.Contains(gormTag, "index") {
-			fieldName := getFieldName(field)
-			if !db.Migrator().HasIndex(model, fieldName) {
+			// GORM按结构体字段名查找该字段上声明的索引
+			if !db.Migrator().HasIndex(model, field.Name) {
 				logger.Info("创建索引",
 					zap.String("table", getTableName(model)),
-					zap.String("field", fieldName))
+					zap.String("field", field.Name))
 
-				if err := db.Migrator().CreateIndex(model, fieldName); err != nil {
+				if err := db.Migrator().CreateIndex(model, field.Name); err != nil {
 					return err
 				}
 			}
@@ -156,26 +163,17 @@ func (mr *ModelRegistry) checkModelChanges(model interface{}, db *gorm.DB) error
 		return nil
 	}
 
-	// 检查字段变化
-	modelType := reflect.TypeOf(model)
-	if modelType.Kind() == reflect.Ptr {
-		modelType = modelType.Elem()
+	// 检查字段变化（列名使用GORM的命名策略解析）
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return err
 	}
 
-	for i := 0; i < modelType.NumField(); i++ {
-		field := modelType.Field(i)
-		fieldName := getFieldName(field)
-
-		// 跳过嵌入的gorm.Model字段
-		if field.Anonymous && field.Type.Name() == "Model" {
-			continue
-		}
-
-		// 检查字段是否存在
-		if !db.Migrator().HasColumn(model, fieldName) {
+	for _, dbName := range stmt.Schema.DBNames {
+		if !db.Migrator().HasColumn(model, dbName) {
 			logger.Info("发现新字段",
 				zap.String("model", modelName),
-				zap.String("field", fieldName))
+				zap.String("field", dbName))
 		}
 	}
 
@@ -263,33 +261,3 @@ func getTableName(model interface{}) string {
 	modelName := getModelName(model)
 	return strings.ToLower(modelName) + "s"
 }
-
-// getFieldName 获取字段名
-func getFieldName(field reflect.StructField) string {
-	gormTag := field.Tag.Get("gorm")
-
-	// 从gorm标签中提取column名
-	if strings.Contains(gormTag, "column:") {
-		parts := strings.Split(gormTag, ";")
-		for _, part := range parts {
-			if strings.HasPrefix(part, "column:") {
-				return strings.TrimPrefix(part, "column:")
-			}
-		}
-	}
-
-	// 使用字段名的snake_case形式
-	return toSnakeCase(field.Name)
-}
-
-// toSnakeCase 转换为snake_case
-func toSnakeCase(str string) string {
-	var result strings.Builder
-	for i, r := range str {
-		if i > 0 && r >= 'A' && r <= 'Z' {
-			result.WriteRune('_')
-		}
-		result.WriteRune(r)
-	}
-	return strings.ToLower(result.String())
-}
@@ -0,0 +1,177 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gin-demo/database"
+	"gin-demo/pkg/logger"
+	"hash/fnv"
+	"math"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// MigrationLockName 启动迁移使用的数据库咨询锁名称
+const MigrationLockName = "gin-demo:migration"
+
+// ErrLockTimeout 等待咨询锁超时
+var ErrLockTimeout = errors.New("等待迁移锁超时")
+
+// postgresLockRetryInterval PostgreSQL尝试获取咨询锁的间隔
+const postgresLockRetryInterval = 500 * time.Millisecond
+
+// AcquireLock 获取数据库级咨询锁，返回释放函数
+// MySQL 使用 GET_LOCK，PostgreSQL 使用 pg_try_advisory_lock，锁绑定在独占连接上，连接断开时自动释放；
+// SQLite 为单机数据库，不加锁
+func AcquireLock(ctx context.Context, db *gorm.DB, name string, timeout time.Duration) (func(), error) {
+	driver := db.Dialector.Name()
+	if driver != "mysql" && driver != "postgres" {
+		return func() {}, nil
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("获取数据库连接失败: %w", err)
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取数据库连接失败: %w", err)
+	}
+
+	var release func(context.Context) error
+	if driver == "mysql" {
+		release, err = acquireMySQLLock(ctx, conn, name, timeout)
+	} else {
+		release, err = acquirePostgresLock(ctx, conn, name, timeout)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return func() {
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := release(releaseCtx); err != nil {
+			logger.Warn("释放迁移锁失败", zap.String("lock", name), zap.Error(err))
+		}
+		conn.Close()
+	}, nil
+}
+
+// acquireMySQLLock 使用 GET_LOCK 获取锁，超时返回0
+// GET_LOCK 的超时单位为秒，向上取整，避免不足1秒的超时变为0而不等待
+func acquireMySQLLock(ctx context.Context, conn *sql.Conn, name string, timeout time.Duration) (func(context.Context) error, error) {
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", name, int(math.Ceil(timeout.Seconds()))).Scan(&acquired); err != nil {
+		return nil, fmt.Errorf("获取迁移锁失败: %w", err)
+	}
+	if !acquired.Valid || acquired.Int64 != 1 {
+		return nil, ErrLockTimeout
+	}
+
+	return func(ctx context.Context) error {
+		_, err := conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", name)
+		return err
+	}, nil
+}
+
+// acquirePostgresLock 轮询 pg_try_advisory_lock 直到获取或超时
+func acquirePostgresLock(ctx context.Context, conn *sql.Conn, name string, timeout time.Duration) (func(context.Context) error, error) {
+	hash := fnv.New64a()
+	hash.Write([]byte(name))
+	key := int64(hash.Sum64())
+
+	deadline := time.Now().Add(timeout)
+	for {
+		var acquired bool
+		if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil {
+			return nil, fmt.Errorf("获取迁移锁失败: %w", err)
+		}
+		if acquired {
+			break
+		}
+		if time.Now().After(deadline) {
+			return nil, ErrLockTimeout
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(postgresLockRetryInterval):
+		}
+	}
+
+	return func(ctx context.Context) error {
+		_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", key)
+		return err
+	}, nil
+}
+
+// UpToDate 判断模型结构和版本化迁移是否都已是最新（只读检查）
+func UpToDate(ctx context.Context, registry *ModelRegistry, migrator *Migrator) (bool, error) {
+	plans, err := registry.Plan(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, plan := range plans {
+		if plan.HasDrift() {
+			return false, nil
+		}
+	}
+
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		return false, err
+	}
+	return pending == 0, nil
+}
+
+// MigrateWithLock 在分布式锁内执行启动迁移（AutoMigrate + 版本化迁移）
+// 结构已是最新时直接返回；多实例同时启动时只有获得锁的实例执行迁移，
+// 其余实例等待锁释放后重新检查，此时通常已是最新而直接跳过
+func MigrateWithLock(ctx context.Context, registry *ModelRegistry, migrator *Migrator, timeout time.Duration) error {
+	current, err := UpToDate(ctx, registry, migrator)
+	if err != nil {
+		return err
+	}
+	if current {
+		logger.Info("数据库结构已是最新，跳过迁移")
+		return nil
+	}
+
+	if database.DB == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+
+	start := time.Now()
+	logger.Info("等待迁移锁", zap.String("lock", MigrationLockName), zap.Duration("timeout", timeout))
+	release, err := AcquireLock(ctx, database.DB, MigrationLockName, timeout)
+	if err != nil {
+		return err
+	}
+	defer release()
+	logger.Info("已获取迁移锁", zap.Duration("waited", time.Since(start)))
+
+	// 等待期间其他实例可能已完成迁移
+	current, err = UpToDate(ctx, registry, migrator)
+	if err != nil {
+		return err
+	}
+	if current {
+		logger.Info("其他实例已完成迁移，跳过")
+		return nil
+	}
+
+	if err := registry.AutoMigrate(); err != nil {
+		return err
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		return err
+	}
+	return nil
+}
@@ -1,9 +1,14 @@
 package migration
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"gin-demo/config"
 	"gin-demo/pkg/logger"
 	"os"
+	"strconv"
+	"strings"
 
 	"go.uber.org/zap"
 )
@@ -11,12 +16,16 @@ import (
 // Manager 迁移管理器
 type Manager struct {
 	registry *ModelRegistry
+	seeders  *SeederRegistry
+	migrator *Migrator
 }
 
 // NewManager 创建迁移管理器
-func NewManager(registry *ModelRegistry) *Manager {
+func NewManager(registry *ModelRegistry, seeders *SeederRegistry, migrator *Migrator) *Manager {
 	return &Manager{
 		registry: registry,
+		seeders:  seeders,
+		migrator: migrator,
 	}
 }
 
@@ -29,6 +38,13 @@ func (m *Manager) RunCommand() {
 
 	command := os.Args[1]
 
+	// 命令参数：seed --class=UserSeeder、fresh --seed、down 2、create add_users_nickname
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	class := flags.String("class", "", "指定填充器，多个用逗号分隔")
+	withSeed := flags.Bool("seed", false, "重建表后执行数据填充")
+	batchSize := flags.Int("batch", defaultReencryptBatchSize, "重新加密每批处理的记录数")
+	_ = flags.Parse(os.Args[2:])
+
 	switch command {
 	case "migrate":
 		if err := m.registry.AutoMigrate(); err != nil {
@@ -44,6 +60,19 @@ func (m *Manager) RunCommand() {
 		}
 		fmt.Println("✅ 表结构检查完成")
 
+	case "plan":
+		// 只打印DDL不执行，存在差异时返回非零退出码，供发布流水线检查
+		plans, err := m.registry.Plan(context.Background())
+		if err != nil {
+			logger.Error("生成迁移计划失败", zap.Error(err))
+			os.Exit(1)
+		}
+		if printPlan(plans) {
+			fmt.Println("❌ 模型与数据库结构存在差异")
+			os.Exit(2)
+		}
+		fmt.Println("✅ 模型与数据库结构一致")
+
 	case "status":
 		m.showStatus()
 
@@ -52,6 +81,10 @@ func (m *Manager) RunCommand() {
 			logger.Error("删除表失败", zap.Error(err))
 			os.Exit(1)
 		}
+		if err := m.migrator.Reset(context.Background()); err != nil {
+			logger.Error("重置迁移记录失败", zap.Error(err))
+			os.Exit(1)
+		}
 		fmt.Println("✅ 所有表已删除")
 
 	case "fresh":
@@ -64,13 +97,111 @@ func (m *Manager) RunCommand() {
 			logger.Error("重新创建表失败", zap.Error(err))
 			os.Exit(1)
 		}
+		// 表已重建，版本化迁移需要全部重新执行
+		if err := m.migrator.Reset(context.Background()); err != nil {
+			logger.Error("重置迁移记录失败", zap.Error(err))
+			os.Exit(1)
+		}
+		m.runUp()
 		fmt.Println("✅ 数据库已重置")
 
+		if *withSeed {
+			m.runSeeders("")
+		}
+
+	case "seed":
+		m.runSeeders(*class)
+
+	case "up":
+		m.runUp()
+
+	case "down":
+		steps := 1
+		if flags.NArg() > 0 {
+			n, err := strconv.Atoi(flags.Arg(0))
+			if err != nil || n <= 0 {
+				fmt.Println("❌ 回滚数量必须为正整数")
+				os.Exit(1)
+			}
+			steps = n
+		}
+		rolledBack, err := m.migrator.Down(context.Background(), steps)
+		if err != nil {
+			logger.Error("回滚迁移失败", zap.Error(err))
+			os.Exit(1)
+		}
+		fmt.Printf("✅ 已回滚 %d 个迁移\n", rolledBack)
+
+	case "redo":
+		if err := m.migrator.Redo(context.Background()); err != nil {
+			logger.Error("重做迁移失败", zap.Error(err))
+			os.Exit(1)
+		}
+		fmt.Println("✅ 最近一次迁移已重做")
+
+	case "create":
+		if flags.NArg() == 0 {
+			fmt.Println("❌ 请指定迁移名称，例如: create add_users_nickname")
+			os.Exit(1)
+		}
+		files, err := CreateMigration(getMigrationDir(), flags.Arg(0))
+		if err != nil {
+			logger.Error("创建迁移文件失败", zap.Error(err))
+			os.Exit(1)
+		}
+		for _, file := range files {
+			fmt.Println("✅ 已创建", file)
+		}
+
+	case "reencrypt":
+		results, err := m.registry.Reencrypt(context.Background(), *batchSize)
+		if err != nil {
+			logger.Error("重新加密失败", zap.Error(err))
+			os.Exit(1)
+		}
+		for _, result := range results {
+			fmt.Printf("✅ %s: 已重新加密 %d 条记录 (%s)\n", result.TableName, result.Rows, strings.Join(result.Fields, ", "))
+		}
+
 	default:
 		m.printUsage()
 	}
 }
 
+// runUp 执行所有待执行的版本化迁移
+func (m *Manager) runUp() {
+	applied, err := m.migrator.Up(context.Background())
+	if err != nil {
+		logger.Error("执行迁移失败", zap.Error(err))
+		os.Exit(1)
+	}
+	fmt.Printf("✅ 已执行 %d 个迁移\n", applied)
+}
+
+// getMigrationDir 获取迁移文件目录
+func getMigrationDir() string {
+	if cfg := config.GetConfig(); cfg != nil && cfg.Database != nil {
+		return cfg.Database.GetMigrationDir()
+	}
+	return "migrations"
+}
+
+// runSeeders 执行数据填充，class 为空时执行全部填充器
+func (m *Manager) runSeeders(class string) {
+	var names []string
+	for _, name := range strings.Split(class, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	if err := m.seeders.Run(context.Background(), names...); err != nil {
+		logger.Error("数据填充失败", zap.Error(err))
+		os.Exit(1)
+	}
+	fmt.Println("✅ 数据填充完成")
+}
+
 // showStatus 显示表状态
 func (m *Manager) showStatus() {
 	status := m.registry.GetTableStatus()
@@ -92,6 +223,36 @@ func (m *Manager) showStatus() {
 			statusStr, 
 			s.RecordCount)
 	}
+
+	migrations, err := m.migrator.Status(context.Background())
+	if err != nil {
+		logger.Error("获取迁移状态失败", zap.Error(err))
+		return
+	}
+
+	labels := map[string]string{
+		MigrationStatusApplied:  "✅ 已执行",
+		MigrationStatusPending:  "⏳ 待执行",
+		MigrationStatusModified: "⚠️ 已修改",
+		MigrationStatusMissing:  "❓ 文件缺失",
+	}
+	pending := 0
+	fmt.Println("")
+	fmt.Println("📜 版本化迁移状态:")
+	fmt.Println("========================================")
+	fmt.Printf("%-16s %-40s %-12s %-20s\n", "版本", "名称", "状态", "执行时间")
+	fmt.Println("----------------------------------------")
+	for _, s := range migrations {
+		appliedAt := "-"
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		if s.Status == MigrationStatusPending {
+			pending++
+		}
+		fmt.Printf("%-16s %-40s %-12s %-20s\n", s.Version, s.Name, labels[s.Status], appliedAt)
+	}
+	fmt.Printf("共 %d 个迁移，%d 个待执行\n", len(migrations), pending)
 }
 
 // printUsage 打印使用说明
@@ -101,11 +262,28 @@ func (m *Manager) printUsage() {
 	fmt.Println("命令:")
 	fmt.Println("  migrate  - 自动迁移所有模型")
 	fmt.Println("  check    - 检查表结构变化")
-	fmt.Println("  status   - 显示所有表状态")
+	fmt.Println("  plan     - 打印migrate将执行的DDL（不执行），存在差异时退出码为2")
+	fmt.Println("  status   - 显示所有表状态和待执行的版本化迁移")
 	fmt.Println("  drop     - 删除所有表")
-	fmt.Println("  fresh    - 删除并重新创建所有表")
+	fmt.Println("  fresh    - 删除并重新创建所有表（--seed 重建后填充数据）")
+	fmt.Println("  seed     - 执行所有数据填充器（--class=UserSeeder 指定填充器）")
+	fmt.Println("  up       - 执行所有待执行的版本化迁移")
+	fmt.Println("  down N   - 回滚最近N个版本化迁移（默认1）")
+	fmt.Println("  redo     - 回滚并重新执行最近一个版本化迁移")
+	fmt.Println("  create   - 创建SQL迁移文件")
+	fmt.Println("  reencrypt - 使用当前密钥重新加密加密字段并重建盲索引（--batch=500）")
 	fmt.Println("")
 	fmt.Println("示例:")
 	fmt.Println("  go run cmd/migrate/main.go migrate")
 	fmt.Println("  go run cmd/migrate/main.go status")
+	fmt.Println("  go run cmd/migrate/main.go plan")
+	fmt.Println("  go run cmd/migrate/main.go seed --class=UserSeeder")
+	fmt.Println("  go run cmd/migrate/main.go fresh --seed")
+	fmt.Println("  go run cmd/migrate/main.go create add_users_nickname")
+	fmt.Println("  go run cmd/migrate/main.go down 2")
+	fmt.Println("  go run cmd/migrate/main.go reencrypt --batch=1000")
+	if m.seeders != nil {
+		fmt.Println("")
+		fmt.Println("填充器:", strings.Join(m.seeders.Names(), ", "))
+	}
 }
\ No newline at end of file
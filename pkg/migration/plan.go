@@ -0,0 +1,306 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"gin-demo/pkg/logger"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ModelPlan 单个模型的迁移计划
+type ModelPlan struct {
+	ModelName  string   `json:"model_name"`
+	TableName  string   `json:"table_name"`
+	Statements []string `json:"statements"` // AutoMigrate 将执行的DDL
+	Notes      []string `json:"notes"`      // 不会自动处理的差异（如数据库中多出的列）
+
+	// ExtraIndexes 数据库中存在但模型未定义的索引和唯一约束，AutoMigrate 不会删除
+	ExtraIndexes []ExtraIndex `json:"extra_indexes"`
+}
+
+// ExtraIndex 模型未定义的索引或唯一约束
+type ExtraIndex struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique"`
+	Drop    string   `json:"drop"` // 删除语句，SQLite 列级唯一约束无法单独删除时为空
+}
+
+// HasDrift 模型与数据库结构是否存在差异（migrate 会执行DDL）
+func (p ModelPlan) HasDrift() bool {
+	return len(p.Statements) > 0
+}
+
+// HasStaleUnique 是否存在模型未定义的唯一约束：会拒绝模型认为合法的写入，需要版本化迁移删除
+// 多出的普通索引通常由版本化迁移创建，只打印不视为差异
+func (p ModelPlan) HasStaleUnique() bool {
+	for _, index := range p.ExtraIndexes {
+		if index.Unique {
+			return true
+		}
+	}
+	return false
+}
+
+// ddlRecorder 记录而不执行写操作的连接池：查询照常访问数据库以读取当前结构，
+// Exec 只记录SQL。实现 TxCommitter 使读写分离插件不再切换连接，迁移中的事务也只是空操作
+type ddlRecorder struct {
+	gorm.ConnPool
+	dialector  gorm.Dialector
+	statements []string
+}
+
+func (r *ddlRecorder) ExecContext(_ context.Context, query string, args ...interface{}) (sql.Result, error) {
+	// 迁移内部事务产生的保存点语句不属于DDL
+	upper := strings.ToUpper(strings.TrimSpace(query))
+	if strings.HasPrefix(upper, "SAVEPOINT") || strings.HasPrefix(upper, "RELEASE SAVEPOINT") || strings.HasPrefix(upper, "ROLLBACK TO") {
+		return driverResult{}, nil
+	}
+	r.statements = append(r.statements, r.dialector.Explain(query, args...))
+	return driverResult{}, nil
+}
+
+func (r *ddlRecorder) BeginTx(_ context.Context, _ *sql.TxOptions) (gorm.ConnPool, error) {
+	return r, nil
+}
+
+func (r *ddlRecorder) Commit() error {
+	return nil
+}
+
+func (r *ddlRecorder) Rollback() error {
+	return nil
+}
+
+// driverResult 未执行语句的结果
+type driverResult struct{}
+
+func (driverResult) LastInsertId() (int64, error) { return 0, nil }
+func (driverResult) RowsAffected() (int64, error) { return 0, nil }
+
+// Plan 对比注册模型与当前数据库结构（列、类型、可空、默认值、索引、唯一约束），
+// 返回 migrate 将执行的DDL，不修改数据库
+func (mr *ModelRegistry) Plan(ctx context.Context) ([]ModelPlan, error) {
+	db := mr.getDB()
+	if db == nil {
+		return nil, fmt.Errorf("数据库未初始化")
+	}
+
+	plans := make([]ModelPlan, 0, len(mr.models))
+	for _, model := range mr.models {
+		plan, err := planModel(ctx, db, model)
+		if err != nil {
+			return nil, fmt.Errorf("生成模型 %s 迁移计划失败: %v", getModelName(model), err)
+		}
+		plans = append(plans, plan)
+	}
+	return plans, nil
+}
+
+// planModel 在记录连接上执行 AutoMigrate，收集将执行的DDL
+func planModel(ctx context.Context, db *gorm.DB, model interface{}) (ModelPlan, error) {
+	plan := ModelPlan{ModelName: getModelName(model), TableName: getTableName(model)}
+
+	recorder := &ddlRecorder{ConnPool: db.Statement.ConnPool, dialector: db.Dialector}
+	session := db.Session(&gorm.Session{NewDB: true, Context: ctx})
+	session.Statement.ConnPool = recorder
+
+	if err := session.AutoMigrate(model); err != nil {
+		return plan, err
+	}
+	for _, statement := range recorder.statements {
+		plan.Statements = append(plan.Statements, strings.TrimSpace(statement))
+	}
+
+	notes, err := extraColumns(db.WithContext(ctx), model)
+	if err != nil {
+		return plan, err
+	}
+	plan.Notes = notes
+
+	plan.ExtraIndexes, err = extraIndexes(db.WithContext(ctx), model)
+	return plan, err
+}
+
+// extraColumns 数据库中存在但模型未定义的列（AutoMigrate 不会删除，需要版本化迁移处理）
+func extraColumns(db *gorm.DB, model interface{}) ([]string, error) {
+	if !db.Migrator().HasTable(model) {
+		return nil, nil
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+	columnTypes, err := db.Migrator().ColumnTypes(model)
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []string
+	for _, columnType := range columnTypes {
+		if _, ok := stmt.Schema.FieldsByDBName[columnType.Name()]; !ok {
+			notes = append(notes, fmt.Sprintf("列 %s 在模型中未定义", columnType.Name()))
+		}
+	}
+	return notes, nil
+}
+
+// liveIndex 数据库中的索引
+type liveIndex struct {
+	name       string
+	columns    []string
+	unique     bool
+	constraint bool // 唯一约束：PostgreSQL 需 DROP CONSTRAINT，SQLite 列级约束无法单独删除
+}
+
+// extraIndexes 数据库中存在但模型未定义的索引和唯一约束，生成删除语句但不执行
+// 模型的索引按名称匹配；unique 标签生成的约束名称由数据库决定，按单列唯一匹配
+func extraIndexes(db *gorm.DB, model interface{}) ([]ExtraIndex, error) {
+	if !db.Migrator().HasTable(model) {
+		return nil, nil
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+	modelIndexes := stmt.Schema.ParseIndexes()
+	uniqueColumns := make(map[string]bool)
+	for _, field := range stmt.Schema.Fields {
+		if field.Unique {
+			uniqueColumns[field.DBName] = true
+		}
+	}
+
+	indexes, err := liveIndexes(db, stmt.Schema.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	var extras []ExtraIndex
+	for _, index := range indexes {
+		if _, ok := modelIndexes[index.name]; ok {
+			continue
+		}
+		if index.unique && len(index.columns) == 1 && uniqueColumns[index.columns[0]] {
+			continue
+		}
+
+		extra := ExtraIndex{Name: index.name, Columns: index.columns, Unique: index.unique}
+		if extra.Drop, err = dropIndexStatement(db, stmt.Schema.Table, index); err != nil {
+			return nil, err
+		}
+		extras = append(extras, extra)
+	}
+	return extras, nil
+}
+
+// liveIndexes 读取表上的索引（不含主键），按名称排序
+func liveIndexes(db *gorm.DB, table string) ([]liveIndex, error) {
+	var indexes []liveIndex
+	if db.Dialector.Name() == "sqlite" {
+		// glebarez/sqlite 未实现 GetIndexes，origin 为 c 是 CREATE INDEX，u 是列级唯一约束
+		var list []struct {
+			Name   string
+			Unique bool
+			Origin string
+		}
+		if err := db.Raw("SELECT name, \"unique\", origin FROM pragma_index_list(?)", table).Scan(&list).Error; err != nil {
+			return nil, err
+		}
+		for _, item := range list {
+			if item.Origin == "pk" {
+				continue
+			}
+			var columns []string
+			if err := db.Raw("SELECT name FROM pragma_index_info(?) ORDER BY seqno", item.Name).Scan(&columns).Error; err != nil {
+				return nil, err
+			}
+			indexes = append(indexes, liveIndex{name: item.Name, columns: columns, unique: item.Unique, constraint: item.Origin == "u"})
+		}
+	} else {
+		list, err := db.Migrator().GetIndexes(table)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range list {
+			if primary, _ := item.PrimaryKey(); primary {
+				continue
+			}
+			unique, _ := item.Unique()
+			index := liveIndex{name: item.Name(), columns: item.Columns(), unique: unique}
+			index.constraint = unique && db.Dialector.Name() == "postgres" && db.Migrator().HasConstraint(table, item.Name())
+			indexes = append(indexes, index)
+		}
+	}
+
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i].name < indexes[j].name })
+	return indexes, nil
+}
+
+// dropIndexStatement 在记录连接上生成删除索引或约束的DDL
+func dropIndexStatement(db *gorm.DB, table string, index liveIndex) (string, error) {
+	if index.constraint && db.Dialector.Name() == "sqlite" {
+		return "", nil
+	}
+
+	recorder := &ddlRecorder{ConnPool: db.Statement.ConnPool, dialector: db.Dialector}
+	session := db.Session(&gorm.Session{NewDB: true})
+	session.Statement.ConnPool = recorder
+
+	var err error
+	if index.constraint {
+		err = session.Migrator().DropConstraint(table, index.name)
+	} else {
+		err = session.Migrator().DropIndex(table, index.name)
+	}
+	if err != nil || len(recorder.statements) == 0 {
+		return "", err
+	}
+	return strings.TrimSpace(recorder.statements[0]), nil
+}
+
+// printPlan 打印迁移计划，返回是否存在差异
+func printPlan(plans []ModelPlan) bool {
+	drift := false
+	for _, plan := range plans {
+		for _, note := range plan.Notes {
+			fmt.Printf("-- ⚠️ %s: %s\n", plan.TableName, note)
+		}
+		if len(plan.ExtraIndexes) > 0 {
+			// 多出的索引和约束不会由 migrate 删除，需要时通过版本化迁移执行
+			fmt.Printf("-- ⚠️ %s: 数据库中存在模型未定义的索引\n", plan.TableName)
+			for _, index := range plan.ExtraIndexes {
+				if index.Drop == "" {
+					fmt.Printf("-- %s (%s): SQLite 列级唯一约束需重建表删除\n", index.Name, strings.Join(index.Columns, ", "))
+					continue
+				}
+				fmt.Println(index.Drop + ";")
+			}
+			fmt.Println("")
+		}
+		if plan.HasStaleUnique() {
+			drift = true
+		}
+		if !plan.HasDrift() {
+			continue
+		}
+
+		drift = true
+		fmt.Printf("-- %s (%s)\n", plan.TableName, plan.ModelName)
+		for _, statement := range plan.Statements {
+			fmt.Println(statement + ";")
+		}
+		fmt.Println("")
+		logger.Info("发现结构差异",
+			zap.String("table", plan.TableName),
+			zap.Int("statements", len(plan.Statements)))
+	}
+	return drift
+}
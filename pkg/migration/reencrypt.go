@@ -0,0 +1,104 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"gin-demo/pkg/encryption"
+	"gin-demo/pkg/logger"
+	"reflect"
+	"strings"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/schema"
+)
+
+// defaultReencryptBatchSize 重新加密每批处理的记录数
+const defaultReencryptBatchSize = 500
+
+// ReencryptResult 单个模型的重新加密结果
+type ReencryptResult struct {
+	ModelName string   `json:"model_name"`
+	TableName string   `json:"table_name"`
+	Fields    []string `json:"fields"`
+	Rows      int64    `json:"rows"`
+}
+
+// Reencrypt 使用当前密钥重写所有模型的加密字段，并通过 BeforeSave 重新计算盲索引
+// 用于启用加密后加密存量明文、轮换密钥后淘汰旧密钥；旧密钥需保留在配置中直到执行完成。
+// 按主键分批、每批一个事务，包含软删除记录，不修改 updated_at，可重复执行
+func (mr *ModelRegistry) Reencrypt(ctx context.Context, batchSize int) ([]ReencryptResult, error) {
+	db := mr.getDB()
+	if db == nil {
+		return nil, fmt.Errorf("数据库未初始化")
+	}
+	if encryption.GetKeyring() == nil {
+		return nil, fmt.Errorf("字段加密未启用")
+	}
+	if batchSize <= 0 {
+		batchSize = defaultReencryptBatchSize
+	}
+	db = db.WithContext(ctx)
+
+	var results []ReencryptResult
+	for _, model := range mr.models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return results, fmt.Errorf("解析模型 %s 失败: %w", getModelName(model), err)
+		}
+		fields := encryptedFields(stmt.Schema)
+		if len(fields) == 0 {
+			continue
+		}
+
+		result := ReencryptResult{ModelName: getModelName(model), TableName: stmt.Schema.Table, Fields: fields}
+		logger.Info("开始重新加密", zap.String("table", result.TableName), zap.Strings("fields", fields))
+
+		rows := reflect.New(reflect.SliceOf(reflect.TypeOf(model)))
+		err := db.Model(model).Unscoped().FindInBatches(rows.Interface(), batchSize, func(batch *gorm.DB, _ int) error {
+			err := db.Transaction(func(tx *gorm.DB) error {
+				for i := 0; i < rows.Elem().Len(); i++ {
+					if err := reencryptRow(tx, rows.Elem().Index(i).Interface()); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			result.Rows += batch.RowsAffected
+			return nil
+		}).Error
+		if err != nil {
+			return results, fmt.Errorf("重新加密 %s 失败: %w", result.TableName, err)
+		}
+
+		logger.Info("重新加密完成", zap.String("table", result.TableName), zap.Int64("rows", result.Rows))
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// reencryptRow 重写单行：读取时已解密，写回时序列化器使用当前密钥加密
+// UpdateColumns 不触发钩子也不更新时间戳，因此手动执行 BeforeSave 维护盲索引
+func reencryptRow(tx *gorm.DB, row interface{}) error {
+	if hook, ok := row.(callbacks.BeforeSaveInterface); ok {
+		if err := hook.BeforeSave(tx); err != nil {
+			return err
+		}
+	}
+	return tx.Model(row).Unscoped().Select("*").UpdateColumns(row).Error
+}
+
+// encryptedFields 返回使用加密序列化器的字段
+func encryptedFields(s *schema.Schema) []string {
+	var fields []string
+	for _, field := range s.Fields {
+		if strings.EqualFold(field.TagSettings["SERIALIZER"], encryption.SerializerName) {
+			fields = append(fields, field.DBName)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,98 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"gin-demo/database"
+	"gin-demo/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// Seeder 数据填充器
+type Seeder interface {
+	// Name 填充器名称，用于 seed --class 指定
+	Name() string
+	// Run 执行填充，db 已处于事务中
+	Run(ctx context.Context, db *gorm.DB) error
+}
+
+// SeederRegistry 填充器注册表
+type SeederRegistry struct {
+	seeders []Seeder
+}
+
+// NewSeederRegistry 创建填充器注册表
+func NewSeederRegistry() *SeederRegistry {
+	return &SeederRegistry{
+		seeders: make([]Seeder, 0),
+	}
+}
+
+// Register 注册填充器，执行顺序与注册顺序一致
+func (sr *SeederRegistry) Register(seeder Seeder) {
+	sr.seeders = append(sr.seeders, seeder)
+}
+
+// Names 获取所有填充器名称
+func (sr *SeederRegistry) Names() []string {
+	names := make([]string, 0, len(sr.seeders))
+	for _, seeder := range sr.seeders {
+		names = append(names, seeder.Name())
+	}
+	return names
+}
+
+// Run 执行填充器，names 为空时按注册顺序执行全部
+// 每个填充器在独立事务中执行，失败时回滚该填充器并停止
+func (sr *SeederRegistry) Run(ctx context.Context, names ...string) error {
+	if database.DB == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+	// 填充始终写主库
+	db := database.DB.Clauses(dbresolver.Write)
+
+	seeders, err := sr.resolve(names)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("开始填充数据", zap.Int("seeders_count", len(seeders)))
+
+	for _, seeder := range seeders {
+		logger.Info("执行填充器", zap.String("seeder", seeder.Name()))
+		if err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return seeder.Run(ctx, tx)
+		}); err != nil {
+			return fmt.Errorf("填充器 %s 执行失败: %v", seeder.Name(), err)
+		}
+	}
+
+	logger.Info("数据填充完成")
+	return nil
+}
+
+// resolve 按名称查找填充器
+func (sr *SeederRegistry) resolve(names []string) ([]Seeder, error) {
+	if len(names) == 0 {
+		return sr.seeders, nil
+	}
+
+	seeders := make([]Seeder, 0, len(names))
+	for _, name := range names {
+		var found Seeder
+		for _, seeder := range sr.seeders {
+			if seeder.Name() == name {
+				found = seeder
+				break
+			}
+		}
+		if found == nil {
+			return nil, fmt.Errorf("填充器 %s 未注册", name)
+		}
+		seeders = append(seeders, found)
+	}
+	return seeders, nil
+}
@@ -0,0 +1,471 @@
+package migration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gin-demo/database"
+	"gin-demo/pkg/logger"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// 版本化迁移状态
+const (
+	MigrationStatusApplied  = "applied"  // 已执行
+	MigrationStatusPending  = "pending"  // 待执行
+	MigrationStatusModified = "modified" // 已执行但文件内容已修改（校验和不一致）
+	MigrationStatusMissing  = "missing"  // 已执行但迁移文件已不存在
+)
+
+// versionLayout 迁移版本号格式（创建时间）
+const versionLayout = "20060102150405"
+
+// sqlFilePattern SQL迁移文件名：{version}_{name}[.{driver}].{up|down}.sql
+// 指定driver的文件优先于通用文件，用于各数据库语法不一致的语句
+var sqlFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+?)(?:\.(mysql|postgres|sqlite))?\.(up|down)\.sql$`)
+
+// migrationNamePattern 迁移名称
+var migrationNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// SchemaMigration 已执行的版本化迁移记录
+type SchemaMigration struct {
+	Version   string    `gorm:"primaryKey;size:32"`
+	Name      string    `gorm:"size:255;not null"`
+	Checksum  string    `gorm:"size:64;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Migration 版本化迁移：Go函数或SQL文件
+// 每个迁移与其执行记录在同一事务中执行（MySQL的DDL会隐式提交，失败时可能需要手动处理）
+type Migration struct {
+	Version string
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+
+	// upSQL/downSQL 按驱动存放的SQL，空字符串键为通用SQL
+	upSQL   map[string]string
+	downSQL map[string]string
+}
+
+// MigrationStatus 版本化迁移状态
+type MigrationStatus struct {
+	Version   string     `json:"version"`
+	Name      string     `json:"name"`
+	Status    string     `json:"status"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// Migrator 版本化迁移执行器
+type Migrator struct {
+	migrations map[string]*Migration
+}
+
+// NewMigrator 创建版本化迁移执行器
+func NewMigrator() *Migrator {
+	return &Migrator{
+		migrations: make(map[string]*Migration),
+	}
+}
+
+// getDB 获取数据库实例，迁移始终走主库
+func (m *Migrator) getDB() (*gorm.DB, error) {
+	if database.DB == nil {
+		return nil, fmt.Errorf("数据库未初始化")
+	}
+	return database.DB.Clauses(dbresolver.Write), nil
+}
+
+// Register 注册Go迁移，版本号重复时panic（应在init中注册）
+func (m *Migrator) Register(migration *Migration) {
+	if _, exists := m.migrations[migration.Version]; exists {
+		panic(fmt.Sprintf("迁移版本 %s 重复注册", migration.Version))
+	}
+	m.migrations[migration.Version] = migration
+}
+
+// LoadFS 加载目录中的SQL迁移文件
+func (m *Migrator) LoadFS(fsys fs.FS) error {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return fmt.Errorf("读取迁移目录失败: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		matches := sqlFilePattern.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+		version, name, driver, direction := matches[1], matches[2], matches[3], matches[4]
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return fmt.Errorf("读取迁移文件 %s 失败: %w", entry.Name(), err)
+		}
+
+		migration, exists := m.migrations[version]
+		if !exists {
+			migration = &Migration{Version: version, Name: name}
+			m.migrations[version] = migration
+		}
+		if migration.Name != name {
+			return fmt.Errorf("迁移版本 %s 重复: %s 与 %s", version, migration.Name, name)
+		}
+		if migration.Up != nil || migration.Down != nil {
+			return fmt.Errorf("迁移版本 %s 已注册为Go迁移", version)
+		}
+
+		if direction == "up" {
+			if migration.upSQL == nil {
+				migration.upSQL = make(map[string]string)
+			}
+			migration.upSQL[driver] = string(content)
+		} else {
+			if migration.downSQL == nil {
+				migration.downSQL = make(map[string]string)
+			}
+			migration.downSQL[driver] = string(content)
+		}
+	}
+
+	return nil
+}
+
+// sorted 按版本号排序的迁移列表
+func (m *Migrator) sorted() []*Migration {
+	migrations := make([]*Migration, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations
+}
+
+// applied 获取已执行的迁移记录（不存在记录表时自动创建）
+func (m *Migrator) applied(ctx context.Context, db *gorm.DB) (map[string]SchemaMigration, error) {
+	if err := db.WithContext(ctx).AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, fmt.Errorf("创建迁移记录表失败: %w", err)
+	}
+
+	var records []SchemaMigration
+	if err := db.WithContext(ctx).Order("version").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("读取迁移记录失败: %w", err)
+	}
+
+	applied := make(map[string]SchemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// Status 获取所有迁移的状态，包括已执行但文件已不存在的迁移
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	db, err := m.getDB()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	driver := db.Dialector.Name()
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.sorted() {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name, Status: MigrationStatusPending}
+		if record, ok := applied[migration.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.AppliedAt = &appliedAt
+			status.Status = MigrationStatusApplied
+			if record.Checksum != migration.checksum(driver) {
+				status.Status = MigrationStatusModified
+			}
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+
+	for _, record := range applied {
+		appliedAt := record.AppliedAt
+		statuses = append(statuses, MigrationStatus{
+			Version:   record.Version,
+			Name:      record.Name,
+			Status:    MigrationStatusMissing,
+			AppliedAt: &appliedAt,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// Pending 获取待执行的迁移数量（不创建迁移记录表，用于只读检查）
+func (m *Migrator) Pending(ctx context.Context) (int, error) {
+	db, err := m.getDB()
+	if err != nil {
+		return 0, err
+	}
+	if !db.WithContext(ctx).Migrator().HasTable(&SchemaMigration{}) {
+		return len(m.migrations), nil
+	}
+
+	var versions []string
+	if err := db.WithContext(ctx).Model(&SchemaMigration{}).Pluck("version", &versions).Error; err != nil {
+		return 0, fmt.Errorf("读取迁移记录失败: %w", err)
+	}
+	applied := make(map[string]bool, len(versions))
+	for _, version := range versions {
+		applied[version] = true
+	}
+
+	pending := 0
+	for version := range m.migrations {
+		if !applied[version] {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// Up 按版本顺序执行所有待执行的迁移，返回执行数量
+// 已执行迁移的内容被修改时拒绝执行，避免各环境结构不一致
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	db, err := m.getDB()
+	if err != nil {
+		return 0, err
+	}
+	applied, err := m.applied(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	driver := db.Dialector.Name()
+	var pending []*Migration
+	for _, migration := range m.sorted() {
+		record, ok := applied[migration.Version]
+		if !ok {
+			pending = append(pending, migration)
+			continue
+		}
+		if record.Checksum != migration.checksum(driver) {
+			return 0, fmt.Errorf("迁移 %s_%s 已执行但内容已修改，请新建迁移而不是修改已执行的迁移", migration.Version, migration.Name)
+		}
+	}
+
+	for i, migration := range pending {
+		logger.Info("执行迁移", zap.String("version", migration.Version), zap.String("name", migration.Name))
+		if err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := migration.runUp(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				Checksum:  migration.checksum(driver),
+				AppliedAt: time.Now(),
+			}).Error
+		}); err != nil {
+			return i, fmt.Errorf("迁移 %s_%s 执行失败: %v", migration.Version, migration.Name, err)
+		}
+	}
+
+	return len(pending), nil
+}
+
+// Down 按版本倒序回滚最近执行的 steps 个迁移，返回回滚数量
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	if steps <= 0 {
+		return 0, nil
+	}
+
+	db, err := m.getDB()
+	if err != nil {
+		return 0, err
+	}
+	applied, err := m.applied(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	versions := make([]string, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(versions)))
+	if len(versions) > steps {
+		versions = versions[:steps]
+	}
+
+	for i, version := range versions {
+		migration, ok := m.migrations[version]
+		if !ok {
+			return i, fmt.Errorf("迁移 %s_%s 的文件已不存在，无法回滚", version, applied[version].Name)
+		}
+
+		logger.Info("回滚迁移", zap.String("version", migration.Version), zap.String("name", migration.Name))
+		if err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := migration.runDown(tx); err != nil {
+				return err
+			}
+			return tx.Where("version = ?", version).Delete(&SchemaMigration{}).Error
+		}); err != nil {
+			return i, fmt.Errorf("迁移 %s_%s 回滚失败: %v", migration.Version, migration.Name, err)
+		}
+	}
+
+	return len(versions), nil
+}
+
+// Redo 回滚并重新执行最近一次迁移
+func (m *Migrator) Redo(ctx context.Context) error {
+	rolledBack, err := m.Down(ctx, 1)
+	if err != nil {
+		return err
+	}
+	if rolledBack == 0 {
+		return errors.New("没有已执行的迁移")
+	}
+	_, err = m.Up(ctx)
+	return err
+}
+
+// Reset 删除迁移记录表（表结构被整体重建时使用）
+func (m *Migrator) Reset(ctx context.Context) error {
+	db, err := m.getDB()
+	if err != nil {
+		return err
+	}
+	return db.WithContext(ctx).Migrator().DropTable(&SchemaMigration{})
+}
+
+// runUp 执行迁移
+func (mg *Migration) runUp(tx *gorm.DB) error {
+	if mg.Up != nil {
+		return mg.Up(tx)
+	}
+	script, ok := selectSQL(mg.upSQL, tx.Dialector.Name())
+	if !ok {
+		return errors.New("缺少up迁移")
+	}
+	return execSQL(tx, script)
+}
+
+// runDown 回滚迁移
+func (mg *Migration) runDown(tx *gorm.DB) error {
+	if mg.Up != nil {
+		if mg.Down == nil {
+			return errors.New("该迁移不可回滚")
+		}
+		return mg.Down(tx)
+	}
+	script, ok := selectSQL(mg.downSQL, tx.Dialector.Name())
+	if !ok {
+		return errors.New("缺少down迁移，该迁移不可回滚")
+	}
+	return execSQL(tx, script)
+}
+
+// checksum 迁移内容校验和：SQL迁移为当前驱动实际执行的SQL，Go迁移无法计算代码哈希，使用版本号和名称
+func (mg *Migration) checksum(driver string) string {
+	var content string
+	if mg.Up != nil {
+		content = mg.Version + "_" + mg.Name
+	} else {
+		up, _ := selectSQL(mg.upSQL, driver)
+		down, _ := selectSQL(mg.downSQL, driver)
+		content = up + "\x00" + down
+	}
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// selectSQL 选择当前驱动的SQL，优先使用驱动专属文件
+func selectSQL(scripts map[string]string, driver string) (string, bool) {
+	if script, ok := scripts[driver]; ok {
+		return script, true
+	}
+	script, ok := scripts[""]
+	return script, ok
+}
+
+// execSQL 逐条执行SQL脚本（以行尾分号分隔语句，忽略 -- 注释行）
+func execSQL(tx *gorm.DB, script string) error {
+	for _, statement := range splitStatements(script) {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitStatements 拆分SQL脚本为单条语句
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
+
+// CreateMigration 在 dir 中生成空的SQL迁移文件，返回生成的文件路径
+func CreateMigration(dir, name string) ([]string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !migrationNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("迁移名称只能包含小写字母、数字和下划线: %s", name)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建迁移目录失败: %w", err)
+	}
+
+	version := time.Now().Format(versionLayout)
+	templates := map[string]string{
+		"up":   fmt.Sprintf("-- %s: 执行迁移\n", name),
+		"down": fmt.Sprintf("-- %s: 回滚迁移\n", name),
+	}
+
+	var files []string
+	for _, direction := range []string{"up", "down"} {
+		file := filepath.Join(dir, fmt.Sprintf("%s_%s.%s.sql", version, name, direction))
+		if err := os.WriteFile(file, []byte(templates[direction]), 0644); err != nil {
+			return nil, fmt.Errorf("写入迁移文件失败: %w", err)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
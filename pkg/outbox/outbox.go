@@ -0,0 +1,105 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"gin-demo/database"
+	"gin-demo/model"
+	"gin-demo/pkg/queue"
+	"gin-demo/pkg/transaction"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// options 发件箱消息选项
+type options struct {
+	aggregateType  string
+	aggregateID    string
+	idempotencyKey string
+	priority       string
+}
+
+// Option 发件箱消息选项函数
+type Option func(*options)
+
+// WithAggregate 指定消息所属聚合，同一聚合的消息按写入顺序投递
+func WithAggregate(aggregateType string, aggregateID interface{}) Option {
+	return func(o *options) {
+		o.aggregateType = aggregateType
+		o.aggregateID = fmt.Sprint(aggregateID)
+	}
+}
+
+// WithIdempotencyKey 指定业务幂等键，开启去重的队列中相同键的消息只处理一次
+func WithIdempotencyKey(key string) Option {
+	return func(o *options) {
+		o.idempotencyKey = key
+	}
+}
+
+// WithPriority 指定消息优先级（如 queue.PriorityHigh），队列需配置对应的优先级通道
+func WithPriority(priority string) Option {
+	return func(o *options) {
+		o.priority = priority
+	}
+}
+
+// Publish 将队列消息写入发件箱
+// ctx 中存在事务（transaction.Manager）时与业务数据在同一事务中写入，事务提交后唤醒投递；
+// 否则直接写入并立即唤醒投递
+func Publish(ctx context.Context, queueName, msgType string, data interface{}, opts ...Option) error {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	message, err := queue.NewMessage(msgType, data)
+	if err != nil {
+		return fmt.Errorf("failed to create message: %w", err)
+	}
+	// 记录发起请求的链路追踪ID，消费端日志与请求关联
+	message.InjectTraceID(ctx)
+	if o.idempotencyKey != "" {
+		message.SetIdempotencyKey(o.idempotencyKey)
+	}
+	if o.priority != "" {
+		message.SetPriority(o.priority)
+	}
+	payload, err := message.ToJSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	record := &model.OutboxMessage{
+		AggregateType: o.aggregateType,
+		AggregateID:   o.aggregateID,
+		Queue:         queueName,
+		MessageType:   msgType,
+		Payload:       string(payload),
+		Status:        model.OutboxStatusPending,
+		AvailableAt:   time.Now(),
+	}
+	if err := getDB(ctx).Create(record).Error; err != nil {
+		return fmt.Errorf("failed to write outbox message: %w", err)
+	}
+
+	// 事务提交后唤醒投递；不在事务中时立即执行
+	transaction.AfterCommit(ctx, func(ctx context.Context) {
+		Notify()
+	})
+	return nil
+}
+
+// PublishType 将类型化消息写入发件箱，队列和消息类型名由 msgType 决定
+func PublishType[T any](ctx context.Context, msgType queue.MessageType[T], payload T, opts ...Option) error {
+	return Publish(ctx, msgType.Queue, msgType.Name, payload, opts...)
+}
+
+// getDB 获取数据库会话，优先使用context中的事务
+func getDB(ctx context.Context) *gorm.DB {
+	if tx, ok := transaction.FromContext(ctx); ok {
+		return tx.WithContext(ctx)
+	}
+	return database.DB.WithContext(ctx)
+}
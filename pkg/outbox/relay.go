@@ -0,0 +1,368 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gin-demo/config"
+	"gin-demo/database"
+	"gin-demo/model"
+	"gin-demo/pkg/logger"
+	"gin-demo/pkg/queue"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+const (
+	defaultPollInterval = time.Second
+	defaultBatchSize    = 100
+	defaultMaxAttempts  = 10
+	defaultRetryBackoff = 5 * time.Second
+	defaultLockTTL      = 30 * time.Second
+	defaultRetention    = 7 * 24 * time.Hour
+
+	// cleanupInterval 清理已投递消息的间隔
+	cleanupInterval = time.Hour
+	lockKey         = "outbox:relay:lock"
+	// maxBatchesPerTick 每次轮询最多连续投递的批次数，积压未投递完时立即开始下一轮
+	maxBatchesPerTick = 10
+)
+
+// acquireLockScript 获取或续期投递锁：锁不存在时抢占，已持有时续期
+var acquireLockScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if current == false then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 1
+end
+if current == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// releaseLockScript 仅释放自己持有的投递锁
+var releaseLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// Publisher 队列发布接口，queue.Manager 实现了该接口
+type Publisher interface {
+	Publish(queueName string, message *queue.Message, opts ...queue.PublishOption) error
+}
+
+// Relay 发件箱投递器：轮询待投递消息并发布到队列
+// 投递语义为至少一次（发布成功但更新状态失败时会重复投递，消息ID保持不变便于消费端去重）；
+// 同一聚合的消息按写入顺序投递，前一条未投递成功时阻塞后续消息
+type Relay struct {
+	db        *gorm.DB
+	publisher Publisher
+	rdb       redis.UniversalClient // 为空时不加分布式锁（单实例部署或测试）
+	cfg       config.OutboxConfig
+	token     string
+	notify    chan struct{}
+
+	lastCleanup time.Time
+	cancel      context.CancelFunc
+	done        chan struct{}
+	mu          sync.Mutex
+}
+
+var defaultRelay *Relay
+
+// NewRelay 创建发件箱投递器，未配置的参数使用默认值
+func NewRelay(db *gorm.DB, publisher Publisher, rdb redis.UniversalClient, cfg config.OutboxConfig) *Relay {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultMaxAttempts
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = defaultRetryBackoff
+	}
+	if cfg.LockTTL <= 0 {
+		cfg.LockTTL = defaultLockTTL
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = defaultRetention
+	}
+
+	return &Relay{
+		db:        db,
+		publisher: publisher,
+		rdb:       rdb,
+		cfg:       cfg,
+		token:     uuid.New().String(),
+		notify:    make(chan struct{}, 1),
+	}
+}
+
+// Init 创建并启动全局发件箱投递器
+func Init(cfg *config.Config, publisher Publisher) {
+	var outboxConfig config.OutboxConfig
+	if cfg.Queue != nil {
+		outboxConfig = cfg.Queue.Outbox
+	}
+
+	defaultRelay = NewRelay(database.GetDB(), publisher, database.GetRedis(), outboxConfig)
+	defaultRelay.Start()
+
+	logger.Info("Outbox relay started",
+		logger.Duration("poll_interval", defaultRelay.cfg.PollInterval),
+		logger.Int("batch_size", defaultRelay.cfg.BatchSize),
+	)
+}
+
+// Stop 停止全局发件箱投递器
+func Stop() {
+	if defaultRelay != nil {
+		defaultRelay.Stop()
+		logger.Info("Outbox relay stopped")
+	}
+}
+
+// Notify 唤醒全局发件箱投递器立即投递，未启动时忽略
+func Notify() {
+	if defaultRelay != nil {
+		defaultRelay.Notify()
+	}
+}
+
+// Start 启动后台投递协程
+func (r *Relay) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+	go r.run(ctx)
+}
+
+// Stop 停止后台投递协程并释放投递锁
+func (r *Relay) Stop() {
+	r.mu.Lock()
+	cancel, done := r.cancel, r.done
+	r.cancel = nil
+	r.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+
+	cancel()
+	<-done
+
+	if r.rdb != nil {
+		ctx, cancelRelease := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelRelease()
+		if err := releaseLockScript.Run(ctx, r.rdb, []string{lockKey}, r.token).Err(); err != nil {
+			logger.Warn("Failed to release outbox relay lock", logger.Err(err))
+		}
+	}
+}
+
+// Notify 唤醒投递协程立即投递（非阻塞）
+func (r *Relay) Notify() {
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+}
+
+// run 投递循环：按轮询间隔或收到通知时投递
+func (r *Relay) run(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		r.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.notify:
+		}
+	}
+}
+
+// tick 获取投递锁后投递所有到期消息，并定期清理已投递消息
+func (r *Relay) tick(ctx context.Context) {
+	locked, err := r.acquireLock(ctx)
+	if err != nil {
+		logger.Error("Failed to acquire outbox relay lock", logger.Err(err))
+		return
+	}
+	if !locked {
+		return
+	}
+
+	for batch := 0; ctx.Err() == nil; batch++ {
+		if batch == maxBatchesPerTick {
+			r.Notify()
+			break
+		}
+		// 每批投递前续期锁，锁已过期并被其他实例获取时停止，避免两个实例同时投递同一聚合
+		if batch > 0 {
+			if locked, err = r.acquireLock(ctx); err != nil || !locked {
+				logger.Warn("Outbox relay lock lost, stopping delivery", logger.Err(err))
+				return
+			}
+		}
+
+		sent, err := r.ProcessBatch(ctx)
+		if err != nil {
+			logger.Error("Failed to process outbox batch", logger.Err(err))
+			return
+		}
+		// 整批都投递成功时可能还有更多消息，否则等待下次轮询
+		if sent < r.cfg.BatchSize {
+			break
+		}
+	}
+
+	if time.Since(r.lastCleanup) >= cleanupInterval {
+		r.lastCleanup = time.Now()
+		if deleted, err := r.Cleanup(ctx); err != nil {
+			logger.Error("Failed to clean up outbox messages", logger.Err(err))
+		} else if deleted > 0 {
+			logger.Info("Outbox messages cleaned up", logger.Int64("deleted", deleted))
+		}
+	}
+}
+
+// acquireLock 获取或续期投递锁，保证同一时间只有一个实例投递（维持聚合内顺序）
+func (r *Relay) acquireLock(ctx context.Context) (bool, error) {
+	if r.rdb == nil {
+		return true, nil
+	}
+	return acquireLockScript.Run(ctx, r.rdb, []string{lockKey}, r.token, r.cfg.LockTTL.Milliseconds()).Bool()
+}
+
+// primary 发件箱的查询和更新都在主库执行：副本落后时会读到主库已标记为已投递的消息而重复投递，并打乱聚合内顺序
+func (r *Relay) primary(ctx context.Context) *gorm.DB {
+	return r.db.Clauses(dbresolver.Write).WithContext(ctx)
+}
+
+// ProcessBatch 按写入顺序投递一批到期的待投递消息，返回投递成功的数量
+// 退避中的消息及其所在聚合的后续消息在查询时排除，不占用批次，避免阻塞其他聚合
+func (r *Relay) ProcessBatch(ctx context.Context) (int, error) {
+	now := time.Now()
+	var messages []model.OutboxMessage
+	if err := r.primary(ctx).
+		Where("status = ? AND available_at <= ?", model.OutboxStatusPending, now).
+		Where("(aggregate_type = '' AND aggregate_id = '') OR NOT EXISTS (?)",
+			r.db.Table("outbox_messages AS earlier").
+				Select("1").
+				Where("earlier.aggregate_type = outbox_messages.aggregate_type AND earlier.aggregate_id = outbox_messages.aggregate_id").
+				Where("earlier.id < outbox_messages.id AND earlier.status = ? AND earlier.available_at > ?", model.OutboxStatusPending, now)).
+		Order("id ASC").
+		Limit(r.cfg.BatchSize).
+		Find(&messages).Error; err != nil {
+		return 0, fmt.Errorf("failed to load outbox messages: %w", err)
+	}
+
+	// blocked 记录本批中投递失败的聚合，其后续消息需等待
+	blocked := make(map[string]bool)
+	sent := 0
+	for i := range messages {
+		msg := &messages[i]
+		key := aggregateKey(msg)
+		if key != "" && blocked[key] {
+			continue
+		}
+
+		if err := r.deliver(ctx, msg); err != nil {
+			// 超过最大次数标记为failed的消息不再阻塞同一聚合的后续消息
+			if key != "" && msg.Status == model.OutboxStatusPending {
+				blocked[key] = true
+			}
+			logger.Warn("Failed to deliver outbox message",
+				logger.Any("outbox_id", msg.ID),
+				logger.String("queue", msg.Queue),
+				logger.Int("attempts", msg.Attempts),
+				logger.Err(err))
+			continue
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// deliver 发布单条消息并更新投递状态
+func (r *Relay) deliver(ctx context.Context, msg *model.OutboxMessage) error {
+	message, err := queue.FromJSON([]byte(msg.Payload))
+	if err == nil {
+		err = r.publisher.Publish(msg.Queue, message)
+	}
+
+	msg.Attempts++
+	now := time.Now()
+	updates := map[string]interface{}{"attempts": msg.Attempts}
+
+	if err == nil {
+		updates["status"] = model.OutboxStatusSent
+		updates["sent_at"] = now
+		updates["last_error"] = ""
+		if updateErr := r.updateMessage(ctx, msg.ID, updates); updateErr != nil {
+			// 已发布但状态未更新，下次会重复投递（至少一次）
+			return fmt.Errorf("message published but status update failed: %w", updateErr)
+		}
+		return nil
+	}
+
+	updates["last_error"] = err.Error()
+	if msg.Attempts >= r.cfg.MaxAttempts {
+		msg.Status = model.OutboxStatusFailed
+		updates["status"] = msg.Status
+	} else {
+		updates["available_at"] = now.Add(r.cfg.RetryBackoff * time.Duration(msg.Attempts))
+	}
+	if updateErr := r.updateMessage(ctx, msg.ID, updates); updateErr != nil {
+		return errors.Join(err, updateErr)
+	}
+	return err
+}
+
+// updateMessage 更新消息投递状态
+func (r *Relay) updateMessage(ctx context.Context, id uint64, updates map[string]interface{}) error {
+	return r.primary(ctx).Model(&model.OutboxMessage{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// Cleanup 删除超过保留时间的已投递消息
+func (r *Relay) Cleanup(ctx context.Context) (int64, error) {
+	result := r.primary(ctx).
+		Where("status = ? AND sent_at < ?", model.OutboxStatusSent, time.Now().Add(-r.cfg.Retention)).
+		Delete(&model.OutboxMessage{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete sent outbox messages: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// aggregateKey 聚合标识，未指定聚合的消息之间不保证顺序
+func aggregateKey(msg *model.OutboxMessage) string {
+	if msg.AggregateType == "" && msg.AggregateID == "" {
+		return ""
+	}
+	return msg.AggregateType + ":" + msg.AggregateID
+}
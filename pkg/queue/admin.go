@@ -0,0 +1,217 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// pauseSyncInterval 从后端同步暂停状态的间隔（其他实例的暂停/恢复操作）
+const pauseSyncInterval = time.Second
+
+// queueConsumer 已注册队列的消费：暂停时停止，已预取的消息放回就绪队列；恢复时重新启动
+type queueConsumer struct {
+	start   func(ctx context.Context, workers *sync.WaitGroup) error
+	cancel  context.CancelFunc // 消费中时非空
+	workers sync.WaitGroup     // 优先级通道的工作协程
+}
+
+// Queues 所有已配置的队列（逻辑名）
+func (m *Manager) Queues() []string {
+	names := make([]string, 0, len(m.config.Queues))
+	for name := range m.config.Queues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PauseQueue 暂停队列消费：所有实例停止从后端取消息，等待正在处理的消息完成后，
+// 已预取未处理的消息放回就绪队列（可查看和清空），发布不受影响
+func (m *Manager) PauseQueue(ctx context.Context, queueName string) error {
+	return m.setPaused(ctx, queueName, true)
+}
+
+// ResumeQueue 恢复队列消费
+func (m *Manager) ResumeQueue(ctx context.Context, queueName string) error {
+	return m.setPaused(ctx, queueName, false)
+}
+
+// setPaused 写入后端的暂停状态并立即应用到本实例，其他实例在同步间隔内生效
+func (m *Manager) setPaused(ctx context.Context, queueName string, paused bool) error {
+	if !m.HasQueue(queueName) {
+		return fmt.Errorf("queue %s not found", queueName)
+	}
+	if err := m.broker.SetPaused(ctx, m.openName(queueName), paused); err != nil {
+		return fmt.Errorf("failed to update pause state of queue %s: %w", queueName, err)
+	}
+
+	m.logger.Info("Queue pause state changed",
+		zap.String("queue", queueName),
+		zap.Bool("paused", paused))
+	return m.applyPaused(queueName, paused)
+}
+
+// IsPaused 队列是否已暂停消费
+func (m *Manager) IsPaused(ctx context.Context, queueName string) (bool, error) {
+	if !m.HasQueue(queueName) {
+		return false, fmt.Errorf("queue %s not found", queueName)
+	}
+	return m.broker.Paused(ctx, m.openName(queueName))
+}
+
+// refreshPaused 从后端读取队列的暂停状态并应用，读取失败时保留原状态
+func (m *Manager) refreshPaused(ctx context.Context, queueName string) error {
+	paused, err := m.broker.Paused(ctx, m.openName(queueName))
+	if err != nil {
+		if ctx.Err() == nil {
+			m.logger.Warn("Failed to load queue pause state", zap.String("queue", queueName), zap.Error(err))
+		}
+		m.pausedMu.RLock()
+		paused = m.paused[queueName]
+		m.pausedMu.RUnlock()
+	}
+	return m.applyPaused(queueName, paused)
+}
+
+// syncPaused 定期同步已注册队列的暂停状态，直到关闭
+func (m *Manager) syncPaused() {
+	defer m.workers.Done()
+
+	ticker := time.NewTicker(pauseSyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.mu.RLock()
+			names := make([]string, 0, len(m.handlers))
+			for name := range m.handlers {
+				names = append(names, name)
+			}
+			m.mu.RUnlock()
+
+			for _, name := range names {
+				if err := m.refreshPaused(m.ctx, name); err != nil {
+					m.logger.Error("Failed to apply queue pause state", zap.String("queue", name), zap.Error(err))
+				}
+			}
+		}
+	}
+}
+
+// applyPaused 更新本实例的暂停状态：暂停时停止消费，恢复时重新开始消费
+func (m *Manager) applyPaused(queueName string, paused bool) error {
+	m.consumerMu.Lock()
+	defer m.consumerMu.Unlock()
+
+	m.pausedMu.Lock()
+	m.paused[queueName] = paused
+	m.pausedMu.Unlock()
+
+	consumer, exists := m.consumers[queueName]
+	if !exists {
+		return nil
+	}
+	if paused {
+		return m.stopConsuming(queueName, consumer)
+	}
+	return m.startConsuming(queueName, consumer)
+}
+
+// startConsuming 开始消费队列，已在消费或正在关闭时不做处理
+func (m *Manager) startConsuming(queueName string, consumer *queueConsumer) error {
+	if consumer.cancel != nil || m.ctx.Err() != nil {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(m.ctx)
+	consumer.cancel = cancel
+	if err := consumer.start(ctx, &consumer.workers); err != nil {
+		// 部分通道可能已开始消费，停止后等待下次恢复或同步时重试
+		if stopErr := m.stopConsuming(queueName, consumer); stopErr != nil {
+			m.logger.Error("Failed to stop queue consuming", zap.String("queue", queueName), zap.Error(stopErr))
+		}
+		return fmt.Errorf("failed to start consuming queue %s: %w", queueName, err)
+	}
+	return nil
+}
+
+// stopConsuming 停止消费队列：等待正在处理的消息完成，再停止后端消费者并将已预取的消息放回就绪队列
+func (m *Manager) stopConsuming(queueName string, consumer *queueConsumer) error {
+	if consumer.cancel == nil {
+		return nil
+	}
+	consumer.cancel()
+	consumer.cancel = nil
+	consumer.workers.Wait()
+
+	var errs []error
+	for _, name := range m.laneNames(queueName) {
+		if err := m.broker.StopConsuming(name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PurgeQueue 清空队列中指定状态（ready / rejected）的消息，包括所有优先级通道，返回清除数量
+func (m *Manager) PurgeQueue(ctx context.Context, queueName, state string) (int64, error) {
+	if !m.HasQueue(queueName) {
+		return 0, fmt.Errorf("queue %s not found", queueName)
+	}
+
+	var total int64
+	for _, name := range m.laneNames(queueName) {
+		purged, err := m.broker.Purge(ctx, name, state)
+		if err != nil {
+			return total, err
+		}
+		total += purged
+	}
+
+	m.logger.Warn("Queue purged",
+		zap.String("queue", queueName),
+		zap.String("state", state),
+		zap.Int64("purged", total))
+	return total, nil
+}
+
+// PeekMessages 按进入顺序查看队列中指定状态和优先级通道的消息（不出队），返回当前页和总数
+// 无法解析的消息保留原始内容，类型为 invalid_payload
+func (m *Manager) PeekMessages(ctx context.Context, queueName, state, priority string, offset, limit int) ([]*Message, int64, error) {
+	if !m.HasQueue(queueName) {
+		return nil, 0, fmt.Errorf("queue %s not found", queueName)
+	}
+	if priority == "" {
+		priority = PriorityNormal
+	}
+	name, err := m.laneName(queueName, priority)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	payloads, total, err := m.broker.Peek(ctx, name, state, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	messages := make([]*Message, 0, len(payloads))
+	for _, payload := range payloads {
+		message, err := FromJSON([]byte(payload))
+		if err != nil {
+			message = &Message{
+				ID:   uuid.New().String(),
+				Type: DeadLetterInvalidPayload,
+				Data: []byte(payload),
+			}
+		}
+		messages = append(messages, message)
+	}
+	return messages, total, nil
+}
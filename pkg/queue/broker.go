@@ -0,0 +1,91 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gin-demo/config"
+	"gin-demo/database"
+	"time"
+)
+
+// 队列中的消息状态，用于查看和清空
+const (
+	MessageStateReady    = "ready"    // 等待处理
+	MessageStateRejected = "rejected" // 已拒绝（死信写入失败等）
+)
+
+// ErrInvalidMessageState 消息状态不是 ready 或 rejected
+var ErrInvalidMessageState = errors.New("invalid message state")
+
+// Delivery 一次消息投递，处理完成后必须确认或拒绝
+type Delivery interface {
+	Payload() string
+	Ack() error
+	Reject() error // 拒绝后保留在后端的rejected列表中供排查
+}
+
+// DeliveryHandler 消费回调
+type DeliveryHandler func(delivery Delivery)
+
+// ConsumeOptions 消费参数
+type ConsumeOptions struct {
+	NumConsumers  int
+	PrefetchLimit int
+	PollDuration  time.Duration
+}
+
+// QueueStats 队列统计信息
+type QueueStats struct {
+	Ready     int64 `json:"ready"`     // 等待处理
+	Unacked   int64 `json:"unacked"`   // 处理中
+	Rejected  int64 `json:"rejected"`  // 已拒绝
+	Delayed   int64 `json:"delayed"`   // 延迟消息（未到期的重试和定时消息）
+	Consumers int64 `json:"consumers"` // 消费者数量
+
+	Lanes map[string]QueueStats `json:"lanes,omitempty"` // 各优先级通道的统计（仅配置了优先级的队列）
+}
+
+// Broker 队列后端，队列名为实际队列名（如 email_queue）
+type Broker interface {
+	// Publish 发布消息到队列
+	Publish(ctx context.Context, queueName string, payload []byte) error
+	// PublishAt 发布定时消息，到达指定时间后进入队列
+	PublishAt(ctx context.Context, queueName string, payload []byte, at time.Time) error
+	// Consume 启动消费者，每条投递调用一次 handler
+	Consume(queueName string, opts ConsumeOptions, handler DeliveryHandler) error
+	// StopConsuming 停止队列的消费者并等待 handler 返回，已取出未确认的消息放回就绪队列，之后可再次 Consume
+	StopConsuming(queueName string) error
+	// Stats 获取队列统计信息
+	Stats(ctx context.Context, queueName string) (QueueStats, error)
+	// Purge 清空队列中指定状态（ready / rejected）的消息，返回清除数量
+	Purge(ctx context.Context, queueName, state string) (int64, error)
+	// Peek 按进入顺序查看指定状态的消息（不出队），返回当前页和总数
+	Peek(ctx context.Context, queueName, state string, offset, limit int) ([]string, int64, error)
+	// SetPaused 暂停或恢复队列消费，暂停状态在所有实例间共享
+	SetPaused(ctx context.Context, queueName string, paused bool) error
+	// Paused 队列是否已暂停消费
+	Paused(ctx context.Context, queueName string) (bool, error)
+	// DeadLetters 获取死信队列
+	DeadLetters() DeadLetterQueue
+	// Dedup 获取消息去重状态存储
+	Dedup() DedupStore
+	// Close 停止所有消费者并释放资源
+	Close() error
+}
+
+// NewBroker 根据配置创建队列后端
+func NewBroker(cfg *config.QueueConfig) (Broker, error) {
+	switch cfg.GetDriver() {
+	case config.QueueDriverRMQ:
+		rdb := database.GetRedis()
+		if rdb == nil {
+			return nil, fmt.Errorf("redis client not initialized")
+		}
+		return NewRMQBroker(rdb, database.IsRedisCluster(), cfg.RMQ)
+	case config.QueueDriverMemory:
+		return NewMemoryBroker(), nil
+	default:
+		return nil, fmt.Errorf("unknown queue driver %q", cfg.Driver)
+	}
+}
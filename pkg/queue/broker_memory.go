@@ -0,0 +1,565 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrBrokerClosed 队列后端已关闭
+	ErrBrokerClosed = errors.New("queue broker closed")
+	// ErrDeliveryFinished 投递已确认或已拒绝
+	ErrDeliveryFinished = errors.New("delivery already acked or rejected")
+)
+
+// MemoryBroker 进程内内存队列后端，确认、拒绝、延迟投递语义与rmq一致
+// 消息仅保存在内存中，进程退出后丢失，用于单元测试和本地开发
+type MemoryBroker struct {
+	mu          sync.Mutex
+	queues      map[string]*memoryQueue
+	timers      map[*time.Timer]struct{}
+	deadLetters *memoryDeadLetterQueue
+	dedup       *memoryDedupStore
+	paused      map[string]bool
+	closed      bool
+	wg          sync.WaitGroup
+}
+
+// memoryQueue 内存队列
+type memoryQueue struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	ready     []string
+	rejected  []string
+	unacked   int64
+	delayed   int64
+	consumers int64
+	closed    bool
+
+	// generation 消费轮次，停止消费时递增，旧轮次的消费协程随之退出
+	generation  int
+	outstanding []*memoryDelivery // 已取出未确认的投递，按取出顺序
+	consuming   sync.WaitGroup
+}
+
+// NewMemoryBroker 创建内存队列后端
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		queues:      make(map[string]*memoryQueue),
+		timers:      make(map[*time.Timer]struct{}),
+		deadLetters: newMemoryDeadLetterQueue(),
+		dedup:       newMemoryDedupStore(),
+		paused:      make(map[string]bool),
+	}
+}
+
+// queue 获取队列，不存在时创建
+func (b *MemoryBroker) queue(queueName string) (*memoryQueue, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrBrokerClosed
+	}
+	q, exists := b.queues[queueName]
+	if !exists {
+		q = &memoryQueue{}
+		q.cond = sync.NewCond(&q.mu)
+		b.queues[queueName] = q
+	}
+	return q, nil
+}
+
+// Publish 发布消息到队列
+func (b *MemoryBroker) Publish(ctx context.Context, queueName string, payload []byte) error {
+	q, err := b.queue(queueName)
+	if err != nil {
+		return err
+	}
+	q.push(string(payload))
+	return nil
+}
+
+// PublishAt 到达指定时间后将消息放入队列
+func (b *MemoryBroker) PublishAt(ctx context.Context, queueName string, payload []byte, at time.Time) error {
+	q, err := b.queue(queueName)
+	if err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	q.delayed++
+	q.mu.Unlock()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var timer *time.Timer
+	timer = time.AfterFunc(time.Until(at), func() {
+		b.mu.Lock()
+		delete(b.timers, timer)
+		b.mu.Unlock()
+
+		q.mu.Lock()
+		q.delayed--
+		q.mu.Unlock()
+		q.push(string(payload))
+	})
+	b.timers[timer] = struct{}{}
+	return nil
+}
+
+// Consume 启动消费协程
+func (b *MemoryBroker) Consume(queueName string, opts ConsumeOptions, handler DeliveryHandler) error {
+	q, err := b.queue(queueName)
+	if err != nil {
+		return err
+	}
+
+	numConsumers := opts.NumConsumers
+	if numConsumers <= 0 {
+		numConsumers = 1
+	}
+	q.mu.Lock()
+	q.consumers += int64(numConsumers)
+	generation := q.generation
+	q.mu.Unlock()
+
+	for i := 0; i < numConsumers; i++ {
+		b.wg.Add(1)
+		q.consuming.Add(1)
+		go func() {
+			defer b.wg.Done()
+			defer q.consuming.Done()
+			for {
+				delivery, ok := q.pop(generation)
+				if !ok {
+					return
+				}
+				handler(delivery)
+			}
+		}()
+	}
+	return nil
+}
+
+// StopConsuming 停止队列的消费协程并等待 handler 返回，未确认的投递按原顺序放回就绪队列头部
+func (b *MemoryBroker) StopConsuming(queueName string) error {
+	q, err := b.queue(queueName)
+	if err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	q.generation++
+	q.consumers = 0
+	q.cond.Broadcast()
+	q.mu.Unlock()
+	q.consuming.Wait()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	payloads := make([]string, 0, len(q.outstanding)+len(q.ready))
+	for _, delivery := range q.outstanding {
+		// 放回后的确认或拒绝不再生效
+		delivery.finished = true
+		payloads = append(payloads, delivery.payload)
+	}
+	q.ready = append(payloads, q.ready...)
+	q.unacked -= int64(len(q.outstanding))
+	q.outstanding = nil
+	if len(q.ready) > 0 {
+		q.cond.Broadcast()
+	}
+	return nil
+}
+
+// Stats 获取队列统计信息
+func (b *MemoryBroker) Stats(ctx context.Context, queueName string) (QueueStats, error) {
+	q, err := b.queue(queueName)
+	if err != nil {
+		return QueueStats{}, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return QueueStats{
+		Ready:     int64(len(q.ready)),
+		Unacked:   q.unacked,
+		Rejected:  int64(len(q.rejected)),
+		Delayed:   q.delayed,
+		Consumers: q.consumers,
+	}, nil
+}
+
+// Purge 清空队列中指定状态的消息
+func (b *MemoryBroker) Purge(ctx context.Context, queueName, state string) (int64, error) {
+	q, err := b.queue(queueName)
+	if err != nil {
+		return 0, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var purged int
+	switch state {
+	case MessageStateReady:
+		purged, q.ready = len(q.ready), nil
+	case MessageStateRejected:
+		purged, q.rejected = len(q.rejected), nil
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrInvalidMessageState, state)
+	}
+	return int64(purged), nil
+}
+
+// Peek 按进入顺序查看指定状态的消息
+func (b *MemoryBroker) Peek(ctx context.Context, queueName, state string, offset, limit int) ([]string, int64, error) {
+	q, err := b.queue(queueName)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var payloads []string
+	switch state {
+	case MessageStateReady:
+		payloads = q.ready
+	case MessageStateRejected:
+		payloads = q.rejected
+	default:
+		return nil, 0, fmt.Errorf("%w: %s", ErrInvalidMessageState, state)
+	}
+
+	total := int64(len(payloads))
+	if offset < 0 || limit <= 0 || offset >= len(payloads) {
+		return []string{}, total, nil
+	}
+	end := offset + limit
+	if end > len(payloads) {
+		end = len(payloads)
+	}
+	return append([]string(nil), payloads[offset:end]...), total, nil
+}
+
+// SetPaused 暂停或恢复队列消费
+func (b *MemoryBroker) SetPaused(ctx context.Context, queueName string, paused bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if paused {
+		b.paused[queueName] = true
+	} else {
+		delete(b.paused, queueName)
+	}
+	return nil
+}
+
+// Paused 队列是否已暂停消费
+func (b *MemoryBroker) Paused(ctx context.Context, queueName string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.paused[queueName], nil
+}
+
+// DeadLetters 获取内存死信队列
+func (b *MemoryBroker) DeadLetters() DeadLetterQueue {
+	return b.deadLetters
+}
+
+// Dedup 获取内存去重状态存储
+func (b *MemoryBroker) Dedup() DedupStore {
+	return b.dedup
+}
+
+// Close 停止延迟投递和消费者，等待正在处理的消息完成
+func (b *MemoryBroker) Close() error {
+	b.mu.Lock()
+	b.closed = true
+	for timer := range b.timers {
+		timer.Stop()
+	}
+	b.timers = make(map[*time.Timer]struct{})
+	for _, q := range b.queues {
+		q.mu.Lock()
+		q.closed = true
+		q.cond.Broadcast()
+		q.mu.Unlock()
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
+	return nil
+}
+
+// push 将消息放入就绪队列并唤醒一个消费者
+func (q *memoryQueue) push(payload string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.ready = append(q.ready, payload)
+	q.cond.Signal()
+}
+
+// pop 阻塞获取下一条消息，队列关闭或所属消费轮次已停止时返回false
+func (q *memoryQueue) pop(generation int) (*memoryDelivery, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.ready) == 0 && !q.closed && q.generation == generation {
+		q.cond.Wait()
+	}
+	if q.closed || q.generation != generation {
+		return nil, false
+	}
+	delivery := &memoryDelivery{queue: q, payload: q.ready[0]}
+	q.ready = q.ready[1:]
+	q.unacked++
+	q.outstanding = append(q.outstanding, delivery)
+	return delivery, true
+}
+
+// memoryDelivery 内存队列投递
+type memoryDelivery struct {
+	queue    *memoryQueue
+	payload  string
+	finished bool
+}
+
+func (d *memoryDelivery) Payload() string {
+	return d.payload
+}
+
+// Ack 确认消息处理完成
+func (d *memoryDelivery) Ack() error {
+	return d.finish(false)
+}
+
+// Reject 拒绝消息，保留在rejected列表中
+func (d *memoryDelivery) Reject() error {
+	return d.finish(true)
+}
+
+func (d *memoryDelivery) finish(reject bool) error {
+	q := d.queue
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if d.finished {
+		return ErrDeliveryFinished
+	}
+	d.finished = true
+	q.unacked--
+	for i, delivery := range q.outstanding {
+		if delivery == d {
+			q.outstanding = append(q.outstanding[:i], q.outstanding[i+1:]...)
+			break
+		}
+	}
+	if reject {
+		q.rejected = append(q.rejected, d.payload)
+	}
+	return nil
+}
+
+// memoryDeadLetterQueue 内存死信队列，每个队列按进入顺序保存
+type memoryDeadLetterQueue struct {
+	mu      sync.Mutex
+	entries map[string][]deadLetterEntry
+}
+
+type deadLetterEntry struct {
+	id   string
+	data []byte
+}
+
+func newMemoryDeadLetterQueue() *memoryDeadLetterQueue {
+	return &memoryDeadLetterQueue{entries: make(map[string][]deadLetterEntry)}
+}
+
+// Add 将消息写入死信队列，同一消息再次写入时移到末尾
+func (q *memoryDeadLetterQueue) Add(ctx context.Context, queueName string, message *Message, reason string, cause error, stack string) error {
+	markDeadLetter(queueName, message, reason, cause, stack)
+	data, err := message.ToJSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.remove(queueName, message.ID)
+	q.entries[queueName] = append(q.entries[queueName], deadLetterEntry{id: message.ID, data: data})
+	return nil
+}
+
+// List 按进入时间倒序分页列出死信
+func (q *memoryDeadLetterQueue) List(ctx context.Context, queueName string, offset, limit int) ([]*Message, int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	entries := q.entries[queueName]
+	reversed := make([]deadLetterEntry, len(entries))
+	for i, entry := range entries {
+		reversed[len(entries)-1-i] = entry
+	}
+	messages, err := decodeDeadLetters(page(reversed, offset, limit))
+	return messages, int64(len(entries)), err
+}
+
+// Oldest 按进入时间正序获取死信
+func (q *memoryDeadLetterQueue) Oldest(ctx context.Context, queueName string, offset, limit int) ([]*Message, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return decodeDeadLetters(page(q.entries[queueName], offset, limit))
+}
+
+// Get 获取单条死信
+func (q *memoryDeadLetterQueue) Get(ctx context.Context, queueName, id string) (*Message, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, entry := range q.entries[queueName] {
+		if entry.id == id {
+			return FromJSON(entry.data)
+		}
+	}
+	return nil, ErrDeadLetterNotFound
+}
+
+// Remove 删除指定死信
+func (q *memoryDeadLetterQueue) Remove(ctx context.Context, queueName string, ids ...string) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var removed int64
+	for _, id := range ids {
+		if q.remove(queueName, id) {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// Purge 清空队列的死信
+func (q *memoryDeadLetterQueue) Purge(ctx context.Context, queueName string) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	count := int64(len(q.entries[queueName]))
+	delete(q.entries, queueName)
+	return count, nil
+}
+
+// Count 统计队列的死信数量
+func (q *memoryDeadLetterQueue) Count(ctx context.Context, queueName string) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return int64(len(q.entries[queueName])), nil
+}
+
+// remove 删除单条死信（调用方需持有锁）
+func (q *memoryDeadLetterQueue) remove(queueName, id string) bool {
+	entries := q.entries[queueName]
+	for i, entry := range entries {
+		if entry.id == id {
+			q.entries[queueName] = append(entries[:i:i], entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// page 分页截取
+func page(entries []deadLetterEntry, offset, limit int) []deadLetterEntry {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(entries) || limit <= 0 {
+		return nil
+	}
+	end := offset + limit
+	if end > len(entries) {
+		end = len(entries)
+	}
+	return entries[offset:end]
+}
+
+// decodeDeadLetters 解码死信
+func decodeDeadLetters(entries []deadLetterEntry) ([]*Message, error) {
+	messages := make([]*Message, 0, len(entries))
+	for _, entry := range entries {
+		message, err := FromJSON(entry.data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode dead letter: %w", err)
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// memoryDedupStore 内存去重状态，过期的状态在访问时清理
+type memoryDedupStore struct {
+	mu     sync.Mutex
+	states map[string]dedupState
+	stats  map[string]*DedupStats
+}
+
+type dedupState struct {
+	status    string
+	expiresAt time.Time
+}
+
+func newMemoryDedupStore() *memoryDedupStore {
+	return &memoryDedupStore{
+		states: make(map[string]dedupState),
+		stats:  make(map[string]*DedupStats),
+	}
+}
+
+// Acquire 抢占消息的处理权，未抢占时返回已有状态并计入统计
+func (s *memoryDedupStore) Acquire(ctx context.Context, queueName, key string, lockTimeout time.Duration) (bool, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stateKey := queueName + "::" + key
+	state, exists := s.states[stateKey]
+	if !exists || time.Now().After(state.expiresAt) {
+		s.states[stateKey] = dedupState{status: DedupStatusProcessing, expiresAt: time.Now().Add(lockTimeout)}
+		return true, "", nil
+	}
+
+	stats, exists := s.stats[queueName]
+	if !exists {
+		stats = &DedupStats{}
+		s.stats[queueName] = stats
+	}
+	if state.status == DedupStatusCompleted {
+		stats.Duplicates++
+	} else {
+		stats.Deferred++
+	}
+	return false, state.status, nil
+}
+
+// Complete 标记消息已处理完成
+func (s *memoryDedupStore) Complete(ctx context.Context, queueName, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[queueName+"::"+key] = dedupState{status: DedupStatusCompleted, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+// Release 释放处理中状态
+func (s *memoryDedupStore) Release(ctx context.Context, queueName, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stateKey := queueName + "::" + key
+	if s.states[stateKey].status == DedupStatusProcessing {
+		delete(s.states, stateKey)
+	}
+	return nil
+}
+
+// Stats 获取队列的去重统计
+func (s *memoryDedupStore) Stats(ctx context.Context, queueName string) (DedupStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stats, exists := s.stats[queueName]; exists {
+		return *stats, nil
+	}
+	return DedupStats{}, nil
+}
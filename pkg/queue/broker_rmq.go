@@ -0,0 +1,476 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"gin-demo/config"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adjust/rmq/v5"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultDelayPollInterval = time.Second
+	// delayedMoveBatchSize 每次脚本调用最多转移的到期消息数
+	delayedMoveBatchSize = 100
+)
+
+// moveDueScript 将到期的延迟消息原子地转移到rmq就绪列表（LPUSH，与rmq发布一致）
+// 成员为 {36位UUID}|{消息}，转移时去掉ID前缀（兼容不带前缀的旧成员）；多实例同时执行时每条消息只会被转移一次，无需选主
+var moveDueScript = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, member in ipairs(due) do
+	local payload = member
+	if string.sub(member, 37, 37) == '|' then
+		payload = string.sub(member, 38)
+	end
+	redis.call('LPUSH', KEYS[2], payload)
+	redis.call('ZREM', KEYS[1], member)
+end
+return #due
+`)
+
+// RMQBroker 基于rmq的Redis队列后端
+type RMQBroker struct {
+	connection  rmq.Connection
+	rdb         redis.UniversalClient
+	cluster     bool
+	cfg         config.RMQConfig
+	queues      map[string]rmq.Queue
+	consumers   map[string][]string // 各队列本连接注册的消费者名，停止消费时从rmq中移除
+	deadLetters DeadLetterQueue
+	dedup       DedupStore
+	logger      *zap.Logger
+	mu          sync.RWMutex
+
+	cancel        context.CancelFunc
+	schedulerDone chan struct{}
+}
+
+// NewRMQBroker 创建rmq队列后端（复用现有Redis客户端）并启动延迟消息调度
+func NewRMQBroker(rdb redis.UniversalClient, cluster bool, cfg config.RMQConfig) (*RMQBroker, error) {
+	// 测试Redis连接
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("redis connection test failed: %w", err)
+	}
+
+	// Cluster模式使用带hash tag的键（rmq::queue::{queue}::ready），保证同一队列的键落在同一slot
+	var connection rmq.Connection
+	var err error
+	if cluster {
+		connection, err = rmq.OpenClusterConnection(cfg.Tag, rdb, nil)
+	} else {
+		connection, err = rmq.OpenConnectionWithRedisClient(cfg.Tag, rdb, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open rmq connection: %w", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	b := &RMQBroker{
+		connection:    connection,
+		rdb:           rdb,
+		cluster:       cluster,
+		cfg:           cfg,
+		queues:        make(map[string]rmq.Queue),
+		consumers:     make(map[string][]string),
+		deadLetters:   NewDeadLetterQueue(rdb, cfg.Tag),
+		dedup:         NewDedupStore(rdb, cfg.Tag),
+		logger:        namedLogger(),
+		cancel:        cancel,
+		schedulerDone: make(chan struct{}),
+	}
+
+	// 启动延迟消息调度（重试和 PublishAt / PublishDelayed 的消息）
+	go b.runScheduler(ctx)
+
+	return b, nil
+}
+
+// openQueue 打开队列，已打开时直接返回
+func (b *RMQBroker) openQueue(queueName string) (rmq.Queue, error) {
+	b.mu.RLock()
+	queue, exists := b.queues[queueName]
+	b.mu.RUnlock()
+	if exists {
+		return queue, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if queue, exists := b.queues[queueName]; exists {
+		return queue, nil
+	}
+	queue, err := b.connection.OpenQueue(queueName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open queue %s: %w", queueName, err)
+	}
+	b.queues[queueName] = queue
+	return queue, nil
+}
+
+// Publish 发布消息到队列
+func (b *RMQBroker) Publish(ctx context.Context, queueName string, payload []byte) error {
+	queue, err := b.openQueue(queueName)
+	if err != nil {
+		return err
+	}
+	return queue.PublishBytes(payload)
+}
+
+// PublishAt 将消息按到期时间写入延迟ZSet，由调度协程转移到队列
+// 队列名记录到延迟队列集合，任一实例的调度协程都会扫描，即使该实例未打开此队列；
+// 成员带唯一ID前缀，相同内容的消息不会合并
+func (b *RMQBroker) PublishAt(ctx context.Context, queueName string, payload []byte, at time.Time) error {
+	// 确保队列已注册到rmq（统计依赖已打开的队列）
+	if _, err := b.openQueue(queueName); err != nil {
+		return err
+	}
+	// 先记录队列名再写入消息，调度协程不会漏掉已写入的消息
+	if err := b.rdb.SAdd(ctx, b.delayedQueuesKey(), queueName).Err(); err != nil {
+		return err
+	}
+	return b.rdb.ZAdd(ctx, b.delayedKey(queueName), redis.Z{
+		Score:  float64(at.UnixMilli()),
+		Member: uuid.New().String() + "|" + string(payload),
+	}).Err()
+}
+
+// Consume 启动消费者
+func (b *RMQBroker) Consume(queueName string, opts ConsumeOptions, handler DeliveryHandler) error {
+	queue, err := b.openQueue(queueName)
+	if err != nil {
+		return err
+	}
+
+	// 启动消费者（这里设置prefetch limit和poll duration）
+	if err := queue.StartConsuming(int64(opts.PrefetchLimit), opts.PollDuration); err != nil {
+		return fmt.Errorf("failed to start consuming queue %s: %w", queueName, err)
+	}
+
+	for i := 0; i < opts.NumConsumers; i++ {
+		consumerName := fmt.Sprintf("%s-consumer-%d", queueName, i)
+		name, err := queue.AddConsumerFunc(consumerName, func(delivery rmq.Delivery) {
+			handler(delivery)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add consumer %s: %w", consumerName, err)
+		}
+		b.mu.Lock()
+		b.consumers[queueName] = append(b.consumers[queueName], name)
+		b.mu.Unlock()
+	}
+	return nil
+}
+
+// StopConsuming 停止队列的消费者，已预取未处理的消息从本连接的unacked列表放回就绪队列
+// rmq队列停止消费后不能重新开始，因此换成新打开的队列对象，恢复时 Consume 使用新对象
+func (b *RMQBroker) StopConsuming(queueName string) error {
+	b.mu.Lock()
+	queue, exists := b.queues[queueName]
+	consumers := b.consumers[queueName]
+	delete(b.consumers, queueName)
+	b.mu.Unlock()
+	if !exists {
+		return nil
+	}
+
+	// 不持有锁等待：消费者退出前可能需要重新发布消息（如重试写入延迟队列）
+	<-queue.StopConsuming()
+
+	fresh, err := b.connection.OpenQueue(queueName)
+	if err != nil {
+		return fmt.Errorf("failed to reopen queue %s: %w", queueName, err)
+	}
+	b.mu.Lock()
+	b.queues[queueName] = fresh
+	b.mu.Unlock()
+
+	// 已停止的消费者不再计入统计
+	if len(consumers) > 0 {
+		members := make([]interface{}, 0, len(consumers))
+		for _, name := range consumers {
+			members = append(members, name)
+		}
+		if err := b.rdb.SRem(context.Background(), b.consumersKey(queue, queueName), members...).Err(); err != nil {
+			return fmt.Errorf("failed to remove consumers of queue %s: %w", queueName, err)
+		}
+	}
+
+	returned, err := queue.ReturnUnacked(math.MaxInt64)
+	if err != nil {
+		return fmt.Errorf("failed to return unacked messages of queue %s: %w", queueName, err)
+	}
+	if returned > 0 {
+		b.logger.Info("Unacked messages returned to ready",
+			zap.String("queue", queueName),
+			zap.Int64("returned", returned))
+	}
+	return nil
+}
+
+// Stats 获取队列统计信息
+func (b *RMQBroker) Stats(ctx context.Context, queueName string) (QueueStats, error) {
+	stats, err := b.connection.CollectStats([]string{queueName})
+	if err != nil {
+		return QueueStats{}, fmt.Errorf("failed to collect stats for queue %s: %w", queueName, err)
+	}
+	delayed, err := b.rdb.ZCard(ctx, b.delayedKey(queueName)).Result()
+	if err != nil {
+		return QueueStats{}, err
+	}
+
+	stat := stats.QueueStats[queueName]
+	return QueueStats{
+		Ready:     stat.ReadyCount,
+		Unacked:   stat.UnackedCount(),
+		Rejected:  stat.RejectedCount,
+		Delayed:   delayed,
+		Consumers: stat.ConsumerCount(),
+	}, nil
+}
+
+// Purge 清空队列中指定状态的消息
+func (b *RMQBroker) Purge(ctx context.Context, queueName, state string) (int64, error) {
+	queue, err := b.openQueue(queueName)
+	if err != nil {
+		return 0, err
+	}
+	switch state {
+	case MessageStateReady:
+		return queue.PurgeReady()
+	case MessageStateRejected:
+		return queue.PurgeRejected()
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrInvalidMessageState, state)
+	}
+}
+
+// Peek 按进入顺序查看指定状态的消息：rmq列表左进右出，最早的消息在右端
+func (b *RMQBroker) Peek(ctx context.Context, queueName, state string, offset, limit int) ([]string, int64, error) {
+	key, err := b.listKey(queueName, state)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := b.rdb.LLen(ctx, key).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	if offset < 0 || limit <= 0 || int64(offset) >= total {
+		return []string{}, total, nil
+	}
+
+	payloads, err := b.rdb.LRange(ctx, key, int64(-offset-limit), int64(-offset-1)).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	for i, j := 0, len(payloads)-1; i < j; i, j = i+1, j-1 {
+		payloads[i], payloads[j] = payloads[j], payloads[i]
+	}
+	return payloads, total, nil
+}
+
+// SetPaused 暂停或恢复队列消费
+func (b *RMQBroker) SetPaused(ctx context.Context, queueName string, paused bool) error {
+	if paused {
+		return b.rdb.Set(ctx, b.pausedKey(queueName), time.Now().Unix(), 0).Err()
+	}
+	return b.rdb.Del(ctx, b.pausedKey(queueName)).Err()
+}
+
+// Paused 队列是否已暂停消费
+func (b *RMQBroker) Paused(ctx context.Context, queueName string) (bool, error) {
+	exists, err := b.rdb.Exists(ctx, b.pausedKey(queueName)).Result()
+	if err != nil {
+		return false, err
+	}
+	return exists > 0, nil
+}
+
+// DeadLetters 获取Redis死信队列
+func (b *RMQBroker) DeadLetters() DeadLetterQueue {
+	return b.deadLetters
+}
+
+// Dedup 获取Redis去重状态存储
+func (b *RMQBroker) Dedup() DedupStore {
+	return b.dedup
+}
+
+// Close 停止延迟消息调度和所有消费者
+func (b *RMQBroker) Close() error {
+	b.cancel()
+	<-b.schedulerDone
+
+	// 不持有锁等待：消费者退出前可能需要重新发布消息（如重试写入延迟队列）
+	b.mu.RLock()
+	queues := make(map[string]rmq.Queue, len(b.queues))
+	for name, queue := range b.queues {
+		queues[name] = queue
+	}
+	b.mu.RUnlock()
+
+	// 停止所有队列
+	for name, queue := range queues {
+		stoppedChan := queue.StopConsuming()
+		// 等待队列停止消费
+		select {
+		case <-stoppedChan:
+			b.logger.Info("Queue stopped consuming", zap.String("queue", name))
+		case <-time.After(10 * time.Second):
+			b.logger.Warn("Timeout waiting for queue to stop consuming", zap.String("queue", name))
+		}
+	}
+
+	// 停止所有消费者（使用 Connection 的 StopAllConsuming 方法）
+	finishedChan := b.connection.StopAllConsuming()
+
+	// 等待所有消费者停止（可选，根据需要设置超时）
+	select {
+	case <-finishedChan:
+		b.logger.Info("All consumers stopped successfully")
+	case <-time.After(30 * time.Second):
+		b.logger.Warn("Timeout waiting for consumers to stop")
+	}
+
+	// 关闭时中断或已预取未处理的消息仍在本连接的unacked列表中，放回就绪队列由其他实例或重启后处理
+	for name, queue := range queues {
+		returned, err := queue.ReturnUnacked(math.MaxInt64)
+		if err != nil {
+			b.logger.Error("Failed to return unacked messages", zap.String("queue", name), zap.Error(err))
+			continue
+		}
+		if returned > 0 {
+			b.logger.Info("Unacked messages returned to ready",
+				zap.String("queue", name),
+				zap.Int64("returned", returned))
+		}
+	}
+	return nil
+}
+
+// delayedQueuesKey 存在延迟消息的队列名集合（队列名数量有限，不做清理）
+func (b *RMQBroker) delayedQueuesKey() string {
+	return fmt.Sprintf("%s::delayed::queues", b.cfg.Tag)
+}
+
+// delayedKey 延迟消息ZSet（成员为 {唯一ID}|{消息JSON}，分数为到期毫秒时间戳）
+// 使用与rmq就绪列表相同的hash tag，Cluster模式下落在同一slot，脚本可以原子转移
+func (b *RMQBroker) delayedKey(queueName string) string {
+	return fmt.Sprintf("%s::delayed::{%s}", b.cfg.Tag, queueName)
+}
+
+// readyKey rmq就绪列表键，与rmq内部键格式保持一致
+func (b *RMQBroker) readyKey(queueName string) string {
+	key, _ := b.listKey(queueName, MessageStateReady)
+	return key
+}
+
+// listKey rmq的就绪或已拒绝列表
+// rmq未公开列表键，按 v5.2.0 的内部格式拼接；go.mod 固定该版本，升级时 TestRMQBrokerKeysMatchRMQ 会发现格式变化
+func (b *RMQBroker) listKey(queueName, state string) (string, error) {
+	if state != MessageStateReady && state != MessageStateRejected {
+		return "", fmt.Errorf("%w: %s", ErrInvalidMessageState, state)
+	}
+	if b.cluster {
+		return fmt.Sprintf("rmq::queue::{%s}::%s", queueName, state), nil
+	}
+	return fmt.Sprintf("rmq::queue::[%s]::%s", queueName, state), nil
+}
+
+// consumersKey rmq记录本连接消费者的集合键，与rmq内部键格式保持一致（rmq未提供移除单个消费者的接口）
+// 连接名取自rmq队列的字符串形式：[队列名 conn:连接名]，同样由 TestRMQBrokerKeysMatchRMQ 校验
+func (b *RMQBroker) consumersKey(queue rmq.Queue, queueName string) string {
+	desc := fmt.Sprint(queue)
+	connection := strings.TrimSuffix(desc[strings.LastIndex(desc, " conn:")+len(" conn:"):], "]")
+	if b.cluster {
+		return fmt.Sprintf("rmq::connection::%s::queue::{%s}::consumers", connection, queueName)
+	}
+	return fmt.Sprintf("rmq::connection::%s::queue::[%s]::consumers", connection, queueName)
+}
+
+// pausedKey 队列暂停标记
+func (b *RMQBroker) pausedKey(queueName string) string {
+	return fmt.Sprintf("%s::paused::%s", b.cfg.Tag, queueName)
+}
+
+// runScheduler 定期将所有队列中到期的延迟消息转移到就绪列表，直到关闭
+func (b *RMQBroker) runScheduler(ctx context.Context) {
+	defer close(b.schedulerDone)
+
+	interval := b.cfg.DelayPollInterval
+	if interval <= 0 {
+		interval = defaultDelayPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			names, err := b.delayedQueues(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					b.logger.Error("Failed to list delayed queues", zap.Error(err))
+				}
+				continue
+			}
+
+			for _, name := range names {
+				if _, err := b.moveDue(ctx, name); err != nil && ctx.Err() == nil {
+					b.logger.Error("Failed to move due delayed messages",
+						zap.String("queue", name),
+						zap.Error(err))
+				}
+			}
+		}
+	}
+}
+
+// delayedQueues 需要扫描的队列：延迟队列集合与本实例已打开的队列
+func (b *RMQBroker) delayedQueues(ctx context.Context) ([]string, error) {
+	names, err := b.rdb.SMembers(ctx, b.delayedQueuesKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		seen[name] = true
+	}
+	b.mu.RLock()
+	for name := range b.queues {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	b.mu.RUnlock()
+	return names, nil
+}
+
+// moveDue 转移队列中所有已到期的延迟消息，返回转移数量
+func (b *RMQBroker) moveDue(ctx context.Context, queueName string) (int64, error) {
+	keys := []string{b.delayedKey(queueName), b.readyKey(queueName)}
+
+	var total int64
+	for {
+		moved, err := moveDueScript.Run(ctx, b.rdb, keys, time.Now().UnixMilli(), delayedMoveBatchSize).Int64()
+		if err != nil {
+			return total, err
+		}
+		total += moved
+		if moved < delayedMoveBatchSize {
+			return total, nil
+		}
+	}
+}
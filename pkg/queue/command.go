@@ -0,0 +1,144 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// RunCommand 运行队列管理命令（cmd/queue）
+func (m *Manager) RunCommand() {
+	if len(os.Args) < 3 || os.Args[1] != "dlq" {
+		m.printUsage()
+		return
+	}
+
+	command := os.Args[2]
+
+	// 命令参数：dlq list --size=50 email、dlq replay --all email
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	all := flags.Bool("all", false, "重放队列的所有死信")
+	page := flags.Int("page", 1, "页码")
+	size := flags.Int("size", 20, "每页数量")
+	_ = flags.Parse(os.Args[3:])
+
+	if flags.NArg() == 0 {
+		m.printUsage()
+		return
+	}
+	queueName := flags.Arg(0)
+	if !m.HasQueue(queueName) {
+		exitf("❌ 队列 %s 未配置\n", queueName)
+	}
+	id := flags.Arg(1)
+	ctx := context.Background()
+
+	switch command {
+	case "list":
+		offset := (*page - 1) * *size
+		if offset < 0 {
+			offset = 0
+		}
+		messages, total, err := m.DeadLetters().List(ctx, queueName, offset, *size)
+		if err != nil {
+			exitf("❌ 获取死信失败: %v\n", err)
+		}
+		fmt.Printf("队列 %s 共 %d 条死信\n", queueName, total)
+		for _, message := range messages {
+			reason, lastError, at := "-", "-", ""
+			if info, ok := message.DeadLetterInfo(); ok {
+				reason, lastError, at = info.Reason, info.Error, info.DeadLetteredAt.Format(time.DateTime)
+			}
+			fmt.Printf("  %s  %-20s %-18s 失败%d次  %s\n", message.ID, at, reason, len(message.Attempts()), lastError)
+		}
+
+	case "show":
+		if id == "" {
+			exitf("❌ 请指定消息ID，例如: dlq show email <id>\n")
+		}
+		message, err := m.DeadLetters().Get(ctx, queueName, id)
+		if err != nil {
+			exitf("❌ 获取死信失败: %v\n", err)
+		}
+		fmt.Println("ID:", message.ID)
+		fmt.Println("类型:", message.Type)
+		fmt.Println("内容:", string(message.Data))
+		metadata, _ := json.MarshalIndent(message.Metadata, "", "  ")
+		fmt.Println("元数据:", string(metadata))
+
+	case "replay":
+		if *all {
+			replayed, err := m.ReplayDeadLetters(ctx, queueName)
+			if err != nil {
+				exitf("❌ 重放死信失败（已重放 %d 条）: %v\n", replayed, err)
+			}
+			fmt.Printf("✅ 已重放 %d 条死信\n", replayed)
+			return
+		}
+		if id == "" {
+			exitf("❌ 请指定消息ID或使用 --all，例如: dlq replay email <id>\n")
+		}
+		if err := m.ReplayDeadLetter(ctx, queueName, id); err != nil {
+			if errors.Is(err, ErrNotReplayable) {
+				exitf("❌ 消息无法解析，不可重放\n")
+			}
+			exitf("❌ 重放死信失败: %v\n", err)
+		}
+		fmt.Println("✅ 死信已重新发布")
+
+	case "delete":
+		if id == "" {
+			exitf("❌ 请指定消息ID，例如: dlq delete email <id>\n")
+		}
+		removed, err := m.DeadLetters().Remove(ctx, queueName, id)
+		if err != nil {
+			exitf("❌ 删除死信失败: %v\n", err)
+		}
+		if removed == 0 {
+			exitf("❌ 死信不存在\n")
+		}
+		fmt.Println("✅ 死信已删除")
+
+	case "purge":
+		purged, err := m.DeadLetters().Purge(ctx, queueName)
+		if err != nil {
+			exitf("❌ 清空死信失败: %v\n", err)
+		}
+		fmt.Printf("✅ 已清空 %d 条死信\n", purged)
+
+	default:
+		m.printUsage()
+	}
+}
+
+// exitf 打印错误并退出
+func exitf(format string, args ...interface{}) {
+	fmt.Printf(format, args...)
+	os.Exit(1)
+}
+
+// printUsage 打印使用说明
+func (m *Manager) printUsage() {
+	fmt.Println("📮 队列管理工具")
+	fmt.Println("========================================")
+	fmt.Println("命令:")
+	fmt.Println("  dlq list <queue>         - 列出死信（--page=1 --size=20，最近的在前）")
+	fmt.Println("  dlq show <queue> <id>    - 查看死信详情（失败原因、调用栈、重试历史）")
+	fmt.Println("  dlq replay <queue> <id>  - 重放一条死信（--all 按进入顺序重放全部）")
+	fmt.Println("  dlq delete <queue> <id>  - 删除一条死信")
+	fmt.Println("  dlq purge <queue>        - 清空队列的死信")
+	fmt.Println("")
+	fmt.Println("示例:")
+	fmt.Println("  go run cmd/queue/main.go dlq list email")
+	fmt.Println("  go run cmd/queue/main.go dlq replay --all email")
+	fmt.Println("")
+	fmt.Print("队列:")
+	for name := range m.config.Queues {
+		fmt.Print(" ", name)
+	}
+	fmt.Println()
+}
@@ -1,33 +1,39 @@
 package queue
 
 import (
+	"context"
+	"fmt"
 	"gin-demo/config"
+	"gin-demo/pkg/logger"
+	"runtime/debug"
 	"time"
 
-	"github.com/adjust/rmq/v5"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
-// Consumer RMQ消费者实现
+// Consumer 消费者：解析消息、调用处理器并按重试策略重试或移入死信队列
 type Consumer struct {
 	handler MessageHandler
 	logger  *zap.Logger
 	config  *config.QueueConfig
+	retry   RetryPolicy
 	manager *Manager // 添加Manager引用
 }
 
 // NewConsumer 创建新的消费者
-func NewConsumer(handler MessageHandler, logger *zap.Logger, config *config.QueueConfig, manager *Manager) *Consumer {
+func NewConsumer(handler MessageHandler, logger *zap.Logger, config *config.QueueConfig, retry RetryPolicy, manager *Manager) *Consumer {
 	return &Consumer{
 		handler: handler,
 		logger:  logger,
 		config:  config,
+		retry:   retry,
 		manager: manager,
 	}
 }
 
-// Consume 实现rmq.Consumer接口
-func (c *Consumer) Consume(delivery rmq.Delivery) {
+// Consume 处理一次投递（Broker 的消费回调）
+func (c *Consumer) Consume(delivery Delivery) {
 	// 解析消息
 	message, err := FromJSON([]byte(delivery.Payload()))
 	if err != nil {
@@ -35,70 +41,117 @@ func (c *Consumer) Consume(delivery rmq.Delivery) {
 			zap.String("queue", c.handler.GetQueueName()),
 			zap.String("payload", delivery.Payload()),
 			zap.Error(err))
-		delivery.Reject()
+		// 保留原始内容便于排查，无法解析的消息不可重放
+		invalid := &Message{
+			ID:        uuid.New().String(),
+			Type:      DeadLetterInvalidPayload,
+			Data:      []byte(delivery.Payload()),
+			Metadata:  make(map[string]interface{}),
+			Timestamp: time.Now(),
+		}
+		c.deadLetter(invalid, delivery, DeadLetterInvalidPayload, err, "")
+		return
+	}
+
+	// 恢复发布方的链路追踪ID，处理器日志和SQL日志与原请求关联；没有时使用消息ID
+	traceID := message.TraceID()
+	if traceID == "" {
+		traceID = message.ID
+	}
+	ctx := logger.SetTraceContext(c.manager.ctx, traceID)
+	log := c.logger.With(
+		zap.String("queue", c.handler.GetQueueName()),
+		zap.String("message_id", message.ID),
+		zap.String("trace_id", traceID))
+
+	// 开启去重时抢占处理权，重复消息不再调用处理器
+	dedupKey, proceed := c.acquireDedup(message, delivery, log)
+	if !proceed {
 		return
 	}
 
 	// 处理消息
-	if err := c.processMessage(message, delivery); err != nil {
-		c.logger.Error("Failed to process message",
-			zap.String("queue", c.handler.GetQueueName()),
-			zap.String("message_id", message.ID),
-			zap.Error(err))
+	if stack, err := c.processMessage(ctx, message); err != nil {
+		// 失败的消息释放处理中状态，重试、重放或重启后可以再次处理
+		c.releaseDedup(dedupKey, log)
+
+		// 队列管理器关闭导致处理中断：不确认也不计入失败次数，由后端关闭时放回就绪队列
+		if c.manager.ctx.Err() != nil {
+			log.Info("Message processing interrupted by shutdown, left unacked", zap.Error(err))
+			return
+		}
 
-		// 检查是否需要重试
-		if c.shouldRetry(message) {
+		log.Error("Failed to process message", zap.Error(err))
+		message.RecordAttempt(err, stack)
+
+		// 检查是否需要重试：永久性错误、不可重试的错误或次数用尽时直接进入死信队列
+		attempt := message.RetryCount + 1
+		switch {
+		case c.retry.ShouldRetry(err, attempt):
 			message.RetryCount++
-			c.retryMessage(message, delivery)
-		} else {
-			delivery.Reject()
+			c.retryMessage(message, delivery, c.retry.NextDelay(attempt))
+		case IsPermanent(err):
+			c.deadLetter(message, delivery, DeadLetterPermanentError, err, stack)
+		case attempt < c.retry.MaxAttempts:
+			c.deadLetter(message, delivery, DeadLetterNonRetryable, err, stack)
+		default:
+			c.deadLetter(message, delivery, DeadLetterRetriesExhausted, err, stack)
 		}
 		return
 	}
 
 	// 确认消息处理成功
+	c.completeDedup(dedupKey, log)
 	delivery.Ack()
-	c.logger.Debug("Message processed successfully",
-		zap.String("queue", c.handler.GetQueueName()),
-		zap.String("message_id", message.ID))
+	log.Debug("Message processed successfully")
 }
 
-// processMessage 处理消息
-func (c *Consumer) processMessage(message *Message, delivery rmq.Delivery) error {
+// processMessage 在队列处理超时内处理消息，处理器panic时转换为错误并返回调用栈
+func (c *Consumer) processMessage(ctx context.Context, message *Message) (stack string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.Queues[c.handler.GetQueueName()].GetTimeout())
+	defer cancel()
+
 	defer func() {
 		if r := recover(); r != nil {
+			stack = string(debug.Stack())
+			err = fmt.Errorf("panic: %v", r)
 			c.logger.Error("Panic occurred while processing message",
 				zap.String("queue", c.handler.GetQueueName()),
 				zap.String("message_id", message.ID),
+				zap.String("trace_id", logger.GetCurrentTraceID(ctx)),
 				zap.Any("panic", r))
 		}
 	}()
 
-	return c.handler.Handle(message)
+	return "", c.handler.Handle(ctx, message)
 }
 
-// shouldRetry 判断是否应该重试
-func (c *Consumer) shouldRetry(message *Message) bool {
-	return message.RetryCount < c.config.RMQ.RetryLimit
+// deadLetter 将消息移入死信队列后确认；写入失败时拒绝消息，保留在后端的rejected列表中
+func (c *Consumer) deadLetter(message *Message, delivery Delivery, reason string, cause error, stack string) {
+	if err := c.manager.deadLetter(c.handler.GetQueueName(), message, reason, cause, stack); err != nil {
+		c.logger.Error("Failed to move message to dead letter queue",
+			zap.String("queue", c.handler.GetQueueName()),
+			zap.String("message_id", message.ID),
+			zap.Error(err))
+		delivery.Reject()
+		return
+	}
+	delivery.Ack()
 }
 
-// retryMessage 重试消息
-func (c *Consumer) retryMessage(message *Message, delivery rmq.Delivery) {
-	// 延迟重试
-	go func() {
-		time.Sleep(c.config.RMQ.RetryDelay)
-
-		// 通过Manager重新发布消息
-		if err := c.manager.Publish(c.handler.GetQueueName(), message); err != nil {
-			c.logger.Error("Failed to retry message",
-				zap.String("message_id", message.ID),
-				zap.Error(err))
-		} else {
-			c.logger.Info("Message retried successfully",
-				zap.String("message_id", message.ID),
-				zap.Int("retry_count", message.RetryCount))
-		}
-	}()
+// retryMessage 重试消息：写入延迟队列后再确认，重启不会丢失等待重试的消息
+func (c *Consumer) retryMessage(message *Message, delivery Delivery, delay time.Duration) {
+	if err := c.manager.PublishDelayed(c.handler.GetQueueName(), message, delay); err != nil {
+		c.logger.Error("Failed to schedule message retry",
+			zap.String("message_id", message.ID),
+			zap.Error(err))
+		delivery.Reject()
+		return
+	}
 
-	delivery.Ack() // 确认当前消息，避免重复处理
+	c.logger.Info("Message scheduled for retry",
+		zap.String("message_id", message.ID),
+		zap.Int("retry_count", message.RetryCount),
+		zap.Duration("delay", delay))
+	delivery.Ack()
 }
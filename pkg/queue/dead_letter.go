@@ -0,0 +1,270 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 死信原因
+const (
+	DeadLetterRetriesExhausted = "retries_exhausted" // 重试次数用尽
+	DeadLetterPermanentError   = "permanent_error"   // 处理器返回永久性错误（不可重试的错误）
+	DeadLetterNonRetryable     = "non_retryable"     // 重试策略的 Retryable 判定错误不可重试
+	DeadLetterInvalidPayload   = "invalid_payload"   // 消息无法解析，不可重放
+)
+
+// 消息元数据键
+const (
+	MetadataAttempts    = "attempts"     // 处理失败历史 []Attempt
+	MetadataDeadLetter  = "dead_letter"  // 进入死信队列的信息 DeadLetterInfo
+	MetadataReplayCount = "replay_count" // 从死信队列重放的次数
+)
+
+var (
+	// ErrDeadLetterNotFound 死信不存在
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+	// ErrNotReplayable 消息无法解析，重放也会再次失败
+	ErrNotReplayable = errors.New("dead letter is not replayable")
+)
+
+// Attempt 一次处理失败的记录
+type Attempt struct {
+	Attempt int       `json:"attempt"`
+	Error   string    `json:"error"`
+	Stack   string    `json:"stack,omitempty"` // 处理器panic时的调用栈
+	At      time.Time `json:"at"`
+}
+
+// DeadLetterInfo 消息进入死信队列的原因
+type DeadLetterInfo struct {
+	Queue          string    `json:"queue"`
+	Reason         string    `json:"reason"`
+	Error          string    `json:"error"`
+	Stack          string    `json:"stack,omitempty"`
+	DeadLetteredAt time.Time `json:"dead_lettered_at"`
+}
+
+// RecordAttempt 在元数据中追加一次失败记录
+func (m *Message) RecordAttempt(err error, stack string) {
+	attempts := m.Attempts()
+	attempts = append(attempts, Attempt{
+		Attempt: len(attempts) + 1,
+		Error:   err.Error(),
+		Stack:   stack,
+		At:      time.Now(),
+	})
+	m.setMetadata(MetadataAttempts, attempts)
+}
+
+// Attempts 获取失败历史
+func (m *Message) Attempts() []Attempt {
+	var attempts []Attempt
+	m.getMetadata(MetadataAttempts, &attempts)
+	return attempts
+}
+
+// DeadLetterInfo 获取死信信息，消息未进入死信队列时返回false
+func (m *Message) DeadLetterInfo() (*DeadLetterInfo, bool) {
+	var info DeadLetterInfo
+	if !m.getMetadata(MetadataDeadLetter, &info) {
+		return nil, false
+	}
+	return &info, true
+}
+
+// setMetadata 写入元数据
+func (m *Message) setMetadata(key string, value interface{}) {
+	if m.Metadata == nil {
+		m.Metadata = make(map[string]interface{})
+	}
+	m.Metadata[key] = value
+}
+
+// getMetadata 读取元数据到结构体（经过JSON往返后元数据为通用map，通过重新编码转换）
+func (m *Message) getMetadata(key string, out interface{}) bool {
+	value, ok := m.Metadata[key]
+	if !ok || value == nil {
+		return false
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, out) == nil
+}
+
+// DeadLetterQueue 死信队列，队列名为逻辑名（如 email）
+type DeadLetterQueue interface {
+	// Add 将消息写入死信队列，元数据中记录原因和错误
+	Add(ctx context.Context, queueName string, message *Message, reason string, cause error, stack string) error
+	// List 按进入时间倒序分页列出死信，返回当前页和总数
+	List(ctx context.Context, queueName string, offset, limit int) ([]*Message, int64, error)
+	// Oldest 按进入时间正序获取最多 limit 条死信
+	Oldest(ctx context.Context, queueName string, offset, limit int) ([]*Message, error)
+	// Get 获取单条死信，不存在时返回 ErrDeadLetterNotFound
+	Get(ctx context.Context, queueName, id string) (*Message, error)
+	// Remove 删除指定死信，返回删除数量
+	Remove(ctx context.Context, queueName string, ids ...string) (int64, error)
+	// Purge 清空队列的所有死信，返回清除数量
+	Purge(ctx context.Context, queueName string) (int64, error)
+	// Count 统计队列的死信数量
+	Count(ctx context.Context, queueName string) (int64, error)
+}
+
+// markDeadLetter 在元数据中记录进入死信队列的原因
+func markDeadLetter(queueName string, message *Message, reason string, cause error, stack string) DeadLetterInfo {
+	info := DeadLetterInfo{
+		Queue:          queueName,
+		Reason:         reason,
+		Stack:          stack,
+		DeadLetteredAt: time.Now(),
+	}
+	if cause != nil {
+		info.Error = cause.Error()
+	}
+	message.setMetadata(MetadataDeadLetter, info)
+	return info
+}
+
+// redisDeadLetterQueue Redis死信队列：每个队列一个Hash（消息ID -> 消息）和一个按进入时间排序的ZSet
+// 键使用相同的hash tag，Cluster模式下落在同一slot
+type redisDeadLetterQueue struct {
+	rdb    redis.UniversalClient
+	prefix string
+}
+
+// NewDeadLetterQueue 创建Redis死信队列
+func NewDeadLetterQueue(rdb redis.UniversalClient, prefix string) DeadLetterQueue {
+	return &redisDeadLetterQueue{rdb: rdb, prefix: prefix}
+}
+
+func (q *redisDeadLetterQueue) messagesKey(queueName string) string {
+	return fmt.Sprintf("%s::dlq::{%s}::messages", q.prefix, queueName)
+}
+
+func (q *redisDeadLetterQueue) indexKey(queueName string) string {
+	return fmt.Sprintf("%s::dlq::{%s}::index", q.prefix, queueName)
+}
+
+// Add 将消息写入死信队列，元数据中记录原因和错误
+func (q *redisDeadLetterQueue) Add(ctx context.Context, queueName string, message *Message, reason string, cause error, stack string) error {
+	info := markDeadLetter(queueName, message, reason, cause, stack)
+
+	data, err := message.ToJSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+
+	_, err = q.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, q.messagesKey(queueName), message.ID, data)
+		pipe.ZAdd(ctx, q.indexKey(queueName), redis.Z{Score: float64(info.DeadLetteredAt.UnixMilli()), Member: message.ID})
+		return nil
+	})
+	return err
+}
+
+// List 按进入时间倒序分页列出死信，返回当前页和总数
+func (q *redisDeadLetterQueue) List(ctx context.Context, queueName string, offset, limit int) ([]*Message, int64, error) {
+	total, err := q.rdb.ZCard(ctx, q.indexKey(queueName)).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	ids, err := q.rdb.ZRevRange(ctx, q.indexKey(queueName), int64(offset), int64(offset+limit-1)).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	messages, err := q.load(ctx, queueName, ids)
+	return messages, total, err
+}
+
+// Oldest 按进入时间正序获取最多 limit 条死信
+func (q *redisDeadLetterQueue) Oldest(ctx context.Context, queueName string, offset, limit int) ([]*Message, error) {
+	ids, err := q.rdb.ZRange(ctx, q.indexKey(queueName), int64(offset), int64(offset+limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+	return q.load(ctx, queueName, ids)
+}
+
+// load 按ID批量读取死信
+func (q *redisDeadLetterQueue) load(ctx context.Context, queueName string, ids []string) ([]*Message, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	values, err := q.rdb.HMGet(ctx, q.messagesKey(queueName), ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]*Message, 0, len(values))
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		message, err := FromJSON([]byte(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode dead letter: %w", err)
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// Get 获取单条死信
+func (q *redisDeadLetterQueue) Get(ctx context.Context, queueName, id string) (*Message, error) {
+	data, err := q.rdb.HGet(ctx, q.messagesKey(queueName), id).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrDeadLetterNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return FromJSON([]byte(data))
+}
+
+// Remove 删除指定死信，返回删除数量
+func (q *redisDeadLetterQueue) Remove(ctx context.Context, queueName string, ids ...string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	members := make([]interface{}, len(ids))
+	for i, id := range ids {
+		members[i] = id
+	}
+
+	var removed *redis.IntCmd
+	_, err := q.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		removed = pipe.HDel(ctx, q.messagesKey(queueName), ids...)
+		pipe.ZRem(ctx, q.indexKey(queueName), members...)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return removed.Val(), nil
+}
+
+// Purge 清空队列的所有死信，返回清除数量
+func (q *redisDeadLetterQueue) Purge(ctx context.Context, queueName string) (int64, error) {
+	var count *redis.IntCmd
+	_, err := q.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		count = pipe.HLen(ctx, q.messagesKey(queueName))
+		pipe.Del(ctx, q.messagesKey(queueName), q.indexKey(queueName))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count.Val(), nil
+}
+
+// Count 统计队列的死信数量
+func (q *redisDeadLetterQueue) Count(ctx context.Context, queueName string) (int64, error) {
+	return q.rdb.ZCard(ctx, q.indexKey(queueName)).Result()
+}
@@ -0,0 +1,204 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// 去重状态
+const (
+	DedupStatusProcessing = "processing" // 处理中
+	DedupStatusCompleted  = "completed"  // 已处理完成
+)
+
+// MetadataIdempotencyKey 业务幂等键，为空时按消息ID去重
+const MetadataIdempotencyKey = "idempotency_key"
+
+// DedupStats 去重统计信息
+type DedupStats struct {
+	Duplicates int64 `json:"duplicates"` // 已处理完成而被丢弃的重复消息
+	Deferred   int64 `json:"deferred"`   // 其他消费者处理中而延后投递的重复消息
+}
+
+// DedupStore 消息去重状态存储，队列名为逻辑名（如 email）
+type DedupStore interface {
+	// Acquire 抢占消息的处理权，未抢占时返回已有状态并计入统计
+	Acquire(ctx context.Context, queueName, key string, lockTimeout time.Duration) (acquired bool, status string, err error)
+	// Complete 标记消息已处理完成，ttl 内的重复消息会被丢弃
+	Complete(ctx context.Context, queueName, key string, ttl time.Duration) error
+	// Release 释放处理中状态（处理失败时），已完成的状态不受影响
+	Release(ctx context.Context, queueName, key string) error
+	// Stats 获取队列的去重统计
+	Stats(ctx context.Context, queueName string) (DedupStats, error)
+}
+
+// SetIdempotencyKey 设置业务幂等键，相同键的消息只处理一次（如同一订单的确认邮件）
+func (m *Message) SetIdempotencyKey(key string) {
+	m.setMetadata(MetadataIdempotencyKey, key)
+}
+
+// IdempotencyKey 获取去重键：优先业务幂等键，否则为消息ID
+func (m *Message) IdempotencyKey() string {
+	if key, ok := m.Metadata[MetadataIdempotencyKey].(string); ok && key != "" {
+		return key
+	}
+	return m.ID
+}
+
+// acquireDedupScript 抢占处理权：键不存在时写入处理中状态，否则返回已有状态并累加统计
+var acquireDedupScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return ''
+end
+local status = redis.call('GET', KEYS[1]) or ARGV[1]
+if status == ARGV[3] then
+	redis.call('HINCRBY', KEYS[2], 'duplicates', 1)
+else
+	redis.call('HINCRBY', KEYS[2], 'deferred', 1)
+end
+return status
+`)
+
+// releaseDedupScript 仅释放处理中状态
+var releaseDedupScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// redisDedupStore Redis去重状态：每条消息一个带TTL的状态键，每个队列一个统计Hash
+// 键使用队列名作为hash tag，Cluster模式下脚本访问的键落在同一slot
+type redisDedupStore struct {
+	rdb    redis.UniversalClient
+	prefix string
+}
+
+// NewDedupStore 创建Redis去重状态存储
+func NewDedupStore(rdb redis.UniversalClient, prefix string) DedupStore {
+	return &redisDedupStore{rdb: rdb, prefix: prefix}
+}
+
+func (s *redisDedupStore) stateKey(queueName, key string) string {
+	return fmt.Sprintf("%s::dedup::{%s}::%s", s.prefix, queueName, key)
+}
+
+func (s *redisDedupStore) statsKey(queueName string) string {
+	return fmt.Sprintf("%s::dedup::{%s}::stats", s.prefix, queueName)
+}
+
+// Acquire 抢占消息的处理权，未抢占时返回已有状态并计入统计
+func (s *redisDedupStore) Acquire(ctx context.Context, queueName, key string, lockTimeout time.Duration) (bool, string, error) {
+	keys := []string{s.stateKey(queueName, key), s.statsKey(queueName)}
+	status, err := acquireDedupScript.Run(ctx, s.rdb, keys,
+		DedupStatusProcessing, lockTimeout.Milliseconds(), DedupStatusCompleted).Text()
+	if err != nil {
+		return false, "", fmt.Errorf("failed to acquire dedup key: %w", err)
+	}
+	return status == "", status, nil
+}
+
+// Complete 标记消息已处理完成
+func (s *redisDedupStore) Complete(ctx context.Context, queueName, key string, ttl time.Duration) error {
+	if err := s.rdb.Set(ctx, s.stateKey(queueName, key), DedupStatusCompleted, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to complete dedup key: %w", err)
+	}
+	return nil
+}
+
+// Release 释放处理中状态
+func (s *redisDedupStore) Release(ctx context.Context, queueName, key string) error {
+	err := releaseDedupScript.Run(ctx, s.rdb, []string{s.stateKey(queueName, key)}, DedupStatusProcessing).Err()
+	if err != nil {
+		return fmt.Errorf("failed to release dedup key: %w", err)
+	}
+	return nil
+}
+
+// Stats 获取队列的去重统计
+func (s *redisDedupStore) Stats(ctx context.Context, queueName string) (DedupStats, error) {
+	var stats DedupStats
+	values, err := s.rdb.HMGet(ctx, s.statsKey(queueName), "duplicates", "deferred").Result()
+	if err != nil {
+		return stats, fmt.Errorf("failed to load dedup stats: %w", err)
+	}
+	stats.Duplicates = parseCount(values[0])
+	stats.Deferred = parseCount(values[1])
+	return stats, nil
+}
+
+// parseCount 解析HMGET返回的计数，字段不存在时为0
+func parseCount(value interface{}) int64 {
+	s, _ := value.(string)
+	count, _ := strconv.ParseInt(s, 10, 64)
+	return count
+}
+
+// acquireDedup 抢占消息的处理权，返回是否继续处理；未开启去重或状态存储不可用时直接处理（退化为至少一次）
+// 已处理完成的重复消息确认丢弃；其他消费者处理中的消息在处理中状态超时后重新投递
+func (c *Consumer) acquireDedup(message *Message, delivery Delivery, log *zap.Logger) (key string, proceed bool) {
+	queueConfig := c.config.Queues[c.handler.GetQueueName()]
+	if queueConfig.Dedup == nil {
+		return "", true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	key = message.IdempotencyKey()
+	lockTimeout := queueConfig.GetDedupLockTimeout()
+	acquired, status, err := c.manager.DedupStore().Acquire(ctx, c.handler.GetQueueName(), key, lockTimeout)
+	if err != nil {
+		log.Warn("Dedup store unavailable, processing without dedup", zap.Error(err))
+		return "", true
+	}
+	if acquired {
+		return key, true
+	}
+
+	if status == DedupStatusCompleted {
+		log.Info("Duplicate message dropped", zap.String("idempotency_key", key))
+		delivery.Ack()
+		return "", false
+	}
+
+	log.Info("Duplicate message in progress, deferred",
+		zap.String("idempotency_key", key),
+		zap.Duration("delay", lockTimeout))
+	if err := c.manager.PublishDelayed(c.handler.GetQueueName(), message, lockTimeout); err != nil {
+		log.Error("Failed to defer duplicate message", zap.Error(err))
+		delivery.Reject()
+		return "", false
+	}
+	delivery.Ack()
+	return "", false
+}
+
+// completeDedup 标记消息已处理完成，失败时仅记录日志（重复投递时会再次处理）
+func (c *Consumer) completeDedup(key string, log *zap.Logger) {
+	if key == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ttl := c.config.Queues[c.handler.GetQueueName()].GetDedupTTL()
+	if err := c.manager.DedupStore().Complete(ctx, c.handler.GetQueueName(), key, ttl); err != nil {
+		log.Error("Failed to mark message as completed", zap.Error(err))
+	}
+}
+
+// releaseDedup 处理失败时释放处理中状态，重试或重放的消息可以再次处理
+func (c *Consumer) releaseDedup(key string, log *zap.Logger) {
+	if key == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.manager.DedupStore().Release(ctx, c.handler.GetQueueName(), key); err != nil {
+		log.Error("Failed to release dedup key", zap.Error(err))
+	}
+}
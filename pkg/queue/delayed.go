@@ -0,0 +1,57 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// PublishAt 发布定时消息，到达指定时间后投递到队列；时间已过时立即发布
+// rmq后端的消息保存在Redis中，进程重启不会丢失
+func (m *Manager) PublishAt(queueName string, message *Message, at time.Time) error {
+	if !at.After(time.Now()) {
+		return m.Publish(queueName, message)
+	}
+	if !m.HasQueue(queueName) {
+		return fmt.Errorf("queue %s not found", queueName)
+	}
+
+	// 到期后进入消息优先级对应的通道（重试的消息保持原优先级）
+	name, err := m.laneName(queueName, message.Priority())
+	if err != nil {
+		return err
+	}
+
+	data, err := message.ToJSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.broker.PublishAt(ctx, name, data, at); err != nil {
+		return fmt.Errorf("failed to schedule message to queue %s: %w", queueName, err)
+	}
+
+	m.logger.Debug("Message scheduled",
+		zap.String("queue", queueName),
+		zap.String("message_id", message.ID),
+		zap.Time("deliver_at", at))
+	return nil
+}
+
+// PublishDelayed 发布延迟消息，delay 后投递到队列
+func (m *Manager) PublishDelayed(queueName string, message *Message, delay time.Duration) error {
+	return m.PublishAt(queueName, message, time.Now().Add(delay))
+}
+
+// DelayedCount 统计队列中尚未到期的延迟消息数量
+func (m *Manager) DelayedCount(ctx context.Context, queueName string) (int64, error) {
+	stats, err := m.GetQueueStats(ctx, queueName)
+	if err != nil {
+		return 0, err
+	}
+	return stats.Delayed, nil
+}
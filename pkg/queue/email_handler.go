@@ -1,7 +1,7 @@
 package queue
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"gin-demo/pkg/logger"
 	"time"
@@ -9,20 +9,23 @@ import (
 	"go.uber.org/zap"
 )
 
+// EmailSend 发送邮件消息
+var EmailSend = NewMessageType[*EmailData](Email, "email.send")
+
 // EmailData 邮件数据结构
 type EmailData struct {
-	To      []string          `json:"to"`
-	CC      []string          `json:"cc,omitempty"`
-	BCC     []string          `json:"bcc,omitempty"`
-	Subject string            `json:"subject"`
-	Body    string            `json:"body"`
+	To      []string          `json:"to" binding:"required,min=1,dive,email"`
+	CC      []string          `json:"cc,omitempty" binding:"dive,email"`
+	BCC     []string          `json:"bcc,omitempty" binding:"dive,email"`
+	Subject string            `json:"subject" binding:"required"`
+	Body    string            `json:"body" binding:"required"`
 	IsHTML  bool              `json:"is_html"`
 	Headers map[string]string `json:"headers,omitempty"`
 }
 
 // EmailHandler 邮件队列处理器
 type EmailHandler struct {
-	*BaseHandler
+	*Router
 	logger *zap.Logger
 }
 
@@ -35,76 +38,43 @@ func NewEmailHandler(queueName string, numConsumers, prefetchLimit int) *EmailHa
 		emailLogger = zap.NewNop()
 	}
 
-	return &EmailHandler{
-		BaseHandler: NewBaseHandler(queueName, numConsumers, prefetchLimit),
-		logger:      emailLogger,
+	h := &EmailHandler{
+		Router: NewRouter(queueName, numConsumers, prefetchLimit),
+		logger: emailLogger,
 	}
+	// 载荷解码和校验由 Router 完成，校验失败的邮件不再重试
+	_ = Register(h.Router, EmailSend, h.send)
+	return h
 }
 
-// Handle 处理邮件消息
-func (h *EmailHandler) Handle(message *Message) error {
-	h.logger.Info("Processing email message",
-		zap.String("message_id", message.ID),
-		zap.String("type", message.Type))
-
-	// 解析邮件数据
-	var emailData EmailData
-	if err := json.Unmarshal(message.Data, &emailData); err != nil {
-		return fmt.Errorf("failed to unmarshal email data: %w", err)
-	}
-
-	// 验证邮件数据
-	if err := h.validateEmailData(&emailData); err != nil {
-		return fmt.Errorf("email data validation failed: %w", err)
+// send 处理邮件消息
+func (h *EmailHandler) send(ctx context.Context, emailData *EmailData) error {
+	log := h.logger.With(zap.String("trace_id", logger.GetCurrentTraceID(ctx)))
+	if message, ok := MessageFromContext(ctx); ok {
+		log = log.With(zap.String("message_id", message.ID))
 	}
+	log.Info("Processing email message")
 
 	// 发送邮件
-	if err := h.sendEmail(&emailData); err != nil {
+	if err := h.sendEmail(ctx, emailData); err != nil {
 		return fmt.Errorf("failed to send email: %w", err)
 	}
 
-	h.logger.Info("Email sent successfully",
-		zap.String("message_id", message.ID),
+	log.Info("Email sent successfully",
 		zap.Strings("to", emailData.To),
 		zap.String("subject", emailData.Subject))
 
 	return nil
 }
 
-// validateEmailData 验证邮件数据
-func (h *EmailHandler) validateEmailData(data *EmailData) error {
-	if len(data.To) == 0 {
-		return fmt.Errorf("recipient list cannot be empty")
-	}
-
-	if data.Subject == "" {
-		return fmt.Errorf("email subject cannot be empty")
-	}
-
-	if data.Body == "" {
-		return fmt.Errorf("email body cannot be empty")
-	}
-
-	// 验证邮箱格式（简单验证）
-	for _, email := range data.To {
-		if !h.isValidEmail(email) {
-			return fmt.Errorf("invalid email address: %s", email)
-		}
-	}
-
-	return nil
-}
-
-// isValidEmail 简单的邮箱格式验证
-func (h *EmailHandler) isValidEmail(email string) bool {
-	// 这里可以使用更复杂的邮箱验证逻辑
-	return len(email) > 0 && email != ""
-}
-
 // sendEmail 发送邮件（模拟实现）
-func (h *EmailHandler) sendEmail(data *EmailData) error {
-	// 模拟邮件发送延迟
-	time.Sleep(100 * time.Millisecond)
+func (h *EmailHandler) sendEmail(ctx context.Context, data *EmailData) error {
+	// 模拟邮件发送延迟，超时或关闭时中断
+	select {
+	case <-time.After(100 * time.Millisecond):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 
 	// 这里应该集成实际的邮件发送服务，如：
 	// - SMTP 服务器
@@ -1,14 +1,20 @@
 package queue
 
 import (
+	"context"
 	"encoding/json"
+	"gin-demo/pkg/logger"
 	"github.com/google/uuid"
 	"time"
 )
 
+// MetadataTraceID 发布消息时的请求链路追踪ID，消费时恢复到处理器的 ctx 中
+const MetadataTraceID = "trace_id"
+
 // MessageHandler 消息处理器接口
+// ctx 带有队列配置的处理超时，队列管理器关闭时被取消，并携带发布方的链路追踪ID
 type MessageHandler interface {
-	Handle(message *Message) error
+	Handle(ctx context.Context, message *Message) error
 	GetQueueName() string
 	GetNumConsumers() int
 	GetPrefetchLimit() int
@@ -41,6 +47,22 @@ func NewMessage(msgType string, data interface{}) (*Message, error) {
 	}, nil
 }
 
+// InjectTraceID 将 ctx 中的链路追踪ID写入元数据（已存在时保留原值）
+func (m *Message) InjectTraceID(ctx context.Context) {
+	if m.TraceID() != "" {
+		return
+	}
+	if traceID := logger.GetCurrentTraceID(ctx); traceID != "" {
+		m.setMetadata(MetadataTraceID, traceID)
+	}
+}
+
+// TraceID 获取发布方的链路追踪ID
+func (m *Message) TraceID() string {
+	traceID, _ := m.Metadata[MetadataTraceID].(string)
+	return traceID
+}
+
 // ToJSON 将消息转换为JSON
 func (m *Message) ToJSON() ([]byte, error) {
 	return json.Marshal(m)
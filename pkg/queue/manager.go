@@ -2,14 +2,13 @@ package queue
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"gin-demo/config"
-	"gin-demo/database"
 	"gin-demo/pkg/logger"
 	"sync"
 	"time"
 
-	"github.com/adjust/rmq/v5"
 	"go.uber.org/zap"
 )
 
@@ -21,14 +20,25 @@ var (
 
 // Manager 队列管理器
 type Manager struct {
-	config     *config.QueueConfig
-	connection rmq.Connection
-	queues     map[string]rmq.Queue
-	handlers   map[string]MessageHandler
-	logger     *zap.Logger
-	mu         sync.RWMutex
-	ctx        context.Context
-	cancel     context.CancelFunc
+	config   *config.QueueConfig
+	broker   Broker
+	handlers map[string]MessageHandler
+	logger   *zap.Logger
+	mu       sync.RWMutex
+
+	// ctx 处理器的根 context，关闭时取消，正在处理的消息可以及时退出
+	ctx    context.Context
+	cancel context.CancelFunc
+	// workers 优先级通道的工作协程和暂停状态同步协程
+	workers sync.WaitGroup
+
+	// paused 本实例缓存的队列暂停状态，定期从后端同步
+	paused   map[string]bool
+	pausedMu sync.RWMutex
+
+	// consumers 已注册队列的消费，暂停时停止、恢复时重新启动
+	consumers  map[string]*queueConsumer
+	consumerMu sync.Mutex
 }
 
 // InitManager 初始化全局队列管理器
@@ -45,39 +55,39 @@ func GetManager() *Manager {
 	return globalManager
 }
 
-// NewManager 创建队列管理器（复用现有Redis客户端）
+// NewManager 创建队列管理器，后端由 queue.driver 配置选择（默认rmq，复用现有Redis客户端）
 func NewManager(cfg *config.QueueConfig) (*Manager, error) {
-	// 获取现有的Redis客户端
-	rdb := database.GetRedis()
-	if rdb == nil {
-		return nil, fmt.Errorf("redis client not initialized")
-	}
-
-	// 测试Redis连接
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := rdb.Ping(ctx).Err(); err != nil {
-		return nil, fmt.Errorf("redis connection test failed: %w", err)
-	}
-
-	// 创建RMQ连接（复用现有Redis客户端）
-	connection, err := rmq.OpenConnectionWithRedisClient(cfg.RMQ.Tag, rdb, nil)
+	broker, err := NewBroker(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open rmq connection: %w", err)
+		return nil, err
 	}
+	return NewManagerWithBroker(cfg, broker), nil
+}
 
-	ctx, cancel = context.WithCancel(context.Background())
+// NewManagerWithBroker 使用指定后端创建队列管理器
+func NewManagerWithBroker(cfg *config.QueueConfig, broker Broker) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
 	m := &Manager{
-		config:     cfg,
-		connection: connection,
-		queues:     make(map[string]rmq.Queue),
-		handlers:   make(map[string]MessageHandler),
-		logger:     logger.Logger.Named("queue"),
-		ctx:        ctx,
-		cancel:     cancel,
+		config:    cfg,
+		broker:    broker,
+		handlers:  make(map[string]MessageHandler),
+		logger:    namedLogger(),
+		ctx:       ctx,
+		cancel:    cancel,
+		paused:    make(map[string]bool),
+		consumers: make(map[string]*queueConsumer),
 	}
+	m.workers.Add(1)
+	go m.syncPaused()
+	return m
+}
 
-	return m, nil
+// namedLogger 队列日志，日志系统未初始化时（如单元测试）不输出
+func namedLogger() *zap.Logger {
+	if logger.Logger == nil {
+		return zap.NewNop()
+	}
+	return logger.Logger.Named("queue")
 }
 
 // RegisterHandler 注册消息处理器
@@ -96,132 +106,261 @@ func (m *Manager) RegisterHandler(handler MessageHandler) error {
 		return fmt.Errorf("queue config for %s not found", queueName)
 	}
 
-	// 打开队列：优先使用配置的实际队列名（如 email_queue），为空则回退逻辑名（如 email）
-	openName := queueConfig.Name
-	if openName == "" {
-		openName = queueName
+	// 重试策略：处理器声明的优先于队列配置
+	retry, err := NewRetryPolicy(m.config, queueName)
+	if provider, ok := handler.(RetryPolicyProvider); ok {
+		retry = provider.RetryPolicy()
+		err = retry.Validate()
 	}
-	queue, err := m.connection.OpenQueue(openName)
 	if err != nil {
-		return fmt.Errorf("failed to open queue %s: %w", openName, err)
+		return fmt.Errorf("invalid retry policy for queue %s: %w", queueName, err)
 	}
 
-	// 启动消费者（这里设置prefetch limit和poll duration）
-	if err := queue.StartConsuming(int64(queueConfig.PrefetchLimit), m.config.RMQ.PollDuration); err != nil {
-		return fmt.Errorf("failed to start consuming queue %s: %w", queueName, err)
+	// 创建消费者（这里设置prefetch limit和poll duration）
+	consumer := NewConsumer(handler, m.logger, m.config, retry, m)
+	opts := ConsumeOptions{
+		NumConsumers:  queueConfig.NumConsumers,
+		PrefetchLimit: queueConfig.PrefetchLimit,
+		PollDuration:  m.config.RMQ.PollDuration,
 	}
-
-	// 创建消费者
-	consumer := NewConsumer(handler, m.logger, m.config, m)
-
-	// 添加消费者
-	for i := 0; i < queueConfig.NumConsumers; i++ {
-		consumerName := fmt.Sprintf("%s-consumer-%d", queueName, i)
-		if _, err := queue.AddConsumer(consumerName, consumer); err != nil {
-			return fmt.Errorf("failed to add consumer %s: %w", consumerName, err)
-		}
+	priorities := queueConfig.GetPriorities()
+	m.consumerMu.Lock()
+	m.consumers[queueName] = &queueConsumer{
+		start: func(ctx context.Context, workers *sync.WaitGroup) error {
+			handle := func(delivery Delivery) {
+				// 停止消费后取到的消息不处理也不确认，由后端放回就绪队列
+				if ctx.Err() != nil {
+					return
+				}
+				consumer.Consume(delivery)
+			}
+			// 配置了优先级通道时按权重在通道间分配消费者
+			if len(priorities) > 0 {
+				return m.consumeLanes(ctx, workers, queueName, opts, priorities, handle)
+			}
+			return m.broker.Consume(m.openName(queueName), opts, handle)
+		},
+	}
+	m.consumerMu.Unlock()
+
+	// 加载暂停状态后启动消费，已暂停的队列恢复时才开始消费
+	if err := m.refreshPaused(m.ctx, queueName); err != nil {
+		m.consumerMu.Lock()
+		delete(m.consumers, queueName)
+		m.consumerMu.Unlock()
+		return err
 	}
 
-	// 保存队列和处理器
-	m.queues[queueName] = queue
+	// 保存处理器
 	m.handlers[queueName] = handler
 
 	m.logger.Info("Handler registered successfully",
 		zap.String("queue", queueName),
+		zap.String("driver", m.config.GetDriver()),
 		zap.Int("consumers", queueConfig.NumConsumers),
-		zap.Int("prefetch_limit", queueConfig.PrefetchLimit))
+		zap.Int("prefetch_limit", queueConfig.PrefetchLimit),
+		zap.Any("priorities", queueConfig.GetPriorities()))
 
 	return nil
 }
 
-// Publish 发布消息
-func (m *Manager) Publish(queueName string, message *Message) error {
-	m.mu.RLock()
-	queue, exists := m.queues[queueName]
-	m.mu.RUnlock()
+// openName 队列的实际名称：优先使用配置的队列名（如 email_queue），为空则回退逻辑名（如 email）
+func (m *Manager) openName(queueName string) string {
+	if name := m.config.Queues[queueName].Name; name != "" {
+		return name
+	}
+	return queueName
+}
 
-	if !exists {
+// Publish 发布消息，可通过 WithPriority 指定优先级通道
+func (m *Manager) Publish(queueName string, message *Message, opts ...PublishOption) error {
+	if !m.HasQueue(queueName) {
 		return fmt.Errorf("queue %s not found", queueName)
 	}
+	for _, opt := range opts {
+		opt(message)
+	}
+	name, err := m.laneName(queueName, message.Priority())
+	if err != nil {
+		return err
+	}
 
 	data, err := message.ToJSON()
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	if err := queue.Publish(string(data)); err != nil {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.broker.Publish(ctx, name, data); err != nil {
 		return fmt.Errorf("failed to publish message to queue %s: %w", queueName, err)
 	}
 
 	m.logger.Debug("Message published",
 		zap.String("queue", queueName),
 		zap.String("message_id", message.ID),
-		zap.String("message_type", message.Type))
+		zap.String("message_type", message.Type),
+		zap.String("priority", message.Priority()))
 
 	return nil
 }
 
+// PublishContext 发布消息并写入 ctx 中的链路追踪ID，消费端日志与发起请求关联
+func (m *Manager) PublishContext(ctx context.Context, queueName string, message *Message, opts ...PublishOption) error {
+	message.InjectTraceID(ctx)
+	return m.Publish(queueName, message, opts...)
+}
+
 // PublishData 发布数据（便捷方法）
-func (m *Manager) PublishData(queueName string, msgType string, data interface{}) error {
+func (m *Manager) PublishData(queueName string, msgType string, data interface{}, opts ...PublishOption) error {
 	message, err := NewMessage(msgType, data)
 	if err != nil {
 		return fmt.Errorf("failed to create message: %w", err)
 	}
-	return m.Publish(queueName, message)
+	return m.Publish(queueName, message, opts...)
 }
 
-// GetQueueStats 获取队列统计信息
-func (m *Manager) GetQueueStats(queueName string) (rmq.Stats, error) {
-	m.mu.RLock()
-	_, exists := m.queues[queueName]
-	m.mu.RUnlock()
+// GetQueueStats 获取队列统计信息，配置了优先级通道时汇总所有通道并附带各通道统计
+func (m *Manager) GetQueueStats(ctx context.Context, queueName string) (QueueStats, error) {
+	if !m.HasQueue(queueName) {
+		return QueueStats{}, fmt.Errorf("queue %s not found", queueName)
+	}
+	if len(m.config.Queues[queueName].GetPriorities()) == 0 {
+		return m.broker.Stats(ctx, m.openName(queueName))
+	}
 
-	if !exists {
-		return rmq.Stats{}, fmt.Errorf("queue %s not found", queueName)
+	total := QueueStats{Lanes: make(map[string]QueueStats)}
+	for priority, name := range m.laneNames(queueName) {
+		stats, err := m.broker.Stats(ctx, name)
+		if err != nil {
+			return QueueStats{}, err
+		}
+		total.Ready += stats.Ready
+		total.Unacked += stats.Unacked
+		total.Rejected += stats.Rejected
+		total.Delayed += stats.Delayed
+		total.Consumers += stats.Consumers
+		total.Lanes[priority] = stats
 	}
+	return total, nil
+}
 
-	// 使用 Connection.CollectStats 方法获取统计信息
-	stats, err := m.connection.CollectStats([]string{queueName})
-	if err != nil {
-		return rmq.Stats{}, fmt.Errorf("failed to collect stats for queue %s: %w", queueName, err)
+// DeadLetters 获取死信队列
+func (m *Manager) DeadLetters() DeadLetterQueue {
+	return m.broker.DeadLetters()
+}
+
+// DedupStore 获取消息去重状态存储
+func (m *Manager) DedupStore() DedupStore {
+	return m.broker.Dedup()
+}
+
+// DedupStats 获取队列的去重统计（重复丢弃和延后投递的数量）
+func (m *Manager) DedupStats(ctx context.Context, queueName string) (DedupStats, error) {
+	if !m.HasQueue(queueName) {
+		return DedupStats{}, fmt.Errorf("queue %s not found", queueName)
 	}
+	return m.broker.Dedup().Stats(ctx, queueName)
+}
 
-	return stats, nil
+// HasQueue 判断队列是否已配置
+func (m *Manager) HasQueue(queueName string) bool {
+	_, exists := m.config.Queues[queueName]
+	return exists
 }
 
-// Close 关闭队列管理器
-func (m *Manager) Close() error {
-	m.cancel()
+// deadLetter 将消息写入死信队列，写入失败时返回错误由调用方回退到后端的rejected列表
+func (m *Manager) deadLetter(queueName string, message *Message, reason string, cause error, stack string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.broker.DeadLetters().Add(ctx, queueName, message, reason, cause, stack); err != nil {
+		return err
+	}
 
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	m.logger.Warn("Message moved to dead letter queue",
+		zap.String("queue", queueName),
+		zap.String("message_id", message.ID),
+		zap.String("reason", reason),
+		zap.Int("attempts", len(message.Attempts())),
+		zap.Error(cause))
+	return nil
+}
+
+// ReplayDeadLetter 将一条死信重新发布到原队列：重置重试次数，保留失败历史
+func (m *Manager) ReplayDeadLetter(ctx context.Context, queueName, id string) error {
+	message, err := m.broker.DeadLetters().Get(ctx, queueName, id)
+	if err != nil {
+		return err
+	}
+	return m.replay(ctx, queueName, message)
+}
+
+// ReplayDeadLetters 按进入时间顺序重放队列的所有可重放死信，返回重放数量
+func (m *Manager) ReplayDeadLetters(ctx context.Context, queueName string) (int, error) {
+	const batchSize = 100
+
+	replayed, skipped := 0, 0
+	for {
+		messages, err := m.broker.DeadLetters().Oldest(ctx, queueName, skipped, batchSize)
+		if err != nil {
+			return replayed, err
+		}
+		if len(messages) == 0 {
+			return replayed, nil
+		}
 
-	// 停止所有队列
-	for name, queue := range m.queues {
-		stoppedChan := queue.StopConsuming()
-		// 等待队列停止消费
-		select {
-		case <-stoppedChan:
-			m.logger.Info("Queue stopped consuming", zap.String("queue", name))
-		case <-time.After(10 * time.Second):
-			m.logger.Warn("Timeout waiting for queue to stop consuming", zap.String("queue", name))
+		for _, message := range messages {
+			err := m.replay(ctx, queueName, message)
+			if errors.Is(err, ErrNotReplayable) {
+				skipped++
+				continue
+			}
+			if err != nil {
+				return replayed, err
+			}
+			replayed++
 		}
 	}
+}
+
+// replay 重新发布死信并从死信队列删除（先发布后删除，至少一次）
+func (m *Manager) replay(ctx context.Context, queueName string, message *Message) error {
+	if info, ok := message.DeadLetterInfo(); ok && info.Reason == DeadLetterInvalidPayload {
+		return ErrNotReplayable
+	}
 
-	// 停止所有消费者（使用 Connection 的 StopAllConsuming 方法）
-	finishedChan := m.connection.StopAllConsuming()
+	var replayCount int
+	message.getMetadata(MetadataReplayCount, &replayCount)
+	delete(message.Metadata, MetadataDeadLetter)
+	message.setMetadata(MetadataReplayCount, replayCount+1)
+	message.RetryCount = 0
 
-	// 等待所有消费者停止（可选，根据需要设置超时）
-	select {
-	case <-finishedChan:
-		m.logger.Info("All consumers stopped successfully")
-	case <-time.After(30 * time.Second):
-		m.logger.Warn("Timeout waiting for consumers to stop")
+	if err := m.Publish(queueName, message); err != nil {
+		return err
+	}
+	if _, err := m.broker.DeadLetters().Remove(ctx, queueName, message.ID); err != nil {
+		return err
 	}
 
-	// 清理映射
-	m.queues = make(map[string]rmq.Queue)
+	m.logger.Info("Dead letter replayed",
+		zap.String("queue", queueName),
+		zap.String("message_id", message.ID),
+		zap.Int("replay_count", replayCount+1))
+	return nil
+}
+
+// Close 关闭队列管理器
+func (m *Manager) Close() error {
+	// 先取消处理器 context 并等待工作协程退出，再等待后端停止消费
+	m.cancel()
+	m.workers.Wait()
+	if err := m.broker.Close(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
 	m.handlers = make(map[string]MessageHandler)
+	m.mu.Unlock()
 
 	m.logger.Info("Queue manager closed")
 	return nil
@@ -0,0 +1,185 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gin-demo/config"
+	"math/rand"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// 消息优先级（通道名），队列需在 priorities 中配置对应通道
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = config.DefaultPriority // 未指定优先级时的默认通道
+	PriorityLow    = "low"
+)
+
+// MetadataPriority 消息优先级，重试和重放时保持在原通道
+const MetadataPriority = "priority"
+
+// ErrUnknownPriority 队列未配置该优先级通道
+var ErrUnknownPriority = errors.New("unknown message priority")
+
+// PublishOption 发布选项
+type PublishOption func(message *Message)
+
+// WithPriority 指定消息优先级（如 PriorityHigh），积压时高权重通道的消息优先被消费
+func WithPriority(priority string) PublishOption {
+	return func(message *Message) {
+		message.SetPriority(priority)
+	}
+}
+
+// SetPriority 设置消息优先级
+func (m *Message) SetPriority(priority string) {
+	m.setMetadata(MetadataPriority, priority)
+}
+
+// Priority 获取消息优先级，未指定时为 PriorityNormal
+func (m *Message) Priority() string {
+	if priority, ok := m.Metadata[MetadataPriority].(string); ok && priority != "" {
+		return priority
+	}
+	return PriorityNormal
+}
+
+// laneName 优先级通道的实际队列名：默认通道为队列名本身（兼容未配置优先级的队列），其他通道追加优先级后缀
+func (m *Manager) laneName(queueName, priority string) (string, error) {
+	name := m.openName(queueName)
+	if priority == PriorityNormal {
+		return name, nil
+	}
+	if _, exists := m.config.Queues[queueName].GetPriorities()[priority]; !exists {
+		return "", fmt.Errorf("%w %q for queue %s", ErrUnknownPriority, priority, queueName)
+	}
+	return name + "." + priority, nil
+}
+
+// laneNames 队列所有通道的实际队列名（优先级 -> 队列名），未配置优先级时只有默认通道
+func (m *Manager) laneNames(queueName string) map[string]string {
+	names := map[string]string{PriorityNormal: m.openName(queueName)}
+	for priority := range m.config.Queues[queueName].GetPriorities() {
+		names[priority], _ = m.laneName(queueName, priority)
+	}
+	return names
+}
+
+// lane 一个优先级通道，后端消费者将投递逐条交给调度器
+type lane struct {
+	name       string
+	weight     int
+	deliveries chan Delivery
+}
+
+// laneScheduler 按权重在优先级通道间分配消费者：多个通道积压时按权重比例消费，空闲通道不占用消费者
+type laneScheduler struct {
+	lanes []*lane
+}
+
+// consumeLanes 为每个通道启动一个后端消费者，由 NumConsumers 个工作协程按权重从各通道取消息处理
+// ctx 取消后工作协程在处理完当前消息后退出，workers 用于等待
+func (m *Manager) consumeLanes(ctx context.Context, workers *sync.WaitGroup, queueName string, opts ConsumeOptions, priorities map[string]int, handler DeliveryHandler) error {
+	scheduler := &laneScheduler{}
+	for priority, weight := range priorities {
+		if weight <= 0 {
+			return fmt.Errorf("invalid weight %d for priority %s of queue %s", weight, priority, queueName)
+		}
+		name, _ := m.laneName(queueName, priority)
+		scheduler.lanes = append(scheduler.lanes, &lane{
+			name:       name,
+			weight:     weight,
+			deliveries: make(chan Delivery, opts.NumConsumers),
+		})
+	}
+	sort.Slice(scheduler.lanes, func(i, j int) bool { return scheduler.lanes[i].weight > scheduler.lanes[j].weight })
+
+	// 每个通道只有一个后端消费者，最多暂存 NumConsumers 条等待工作协程取走，其余留在后端
+	for _, l := range scheduler.lanes {
+		deliveries := l.deliveries
+		err := m.broker.Consume(l.name, ConsumeOptions{
+			NumConsumers:  1,
+			PrefetchLimit: opts.PrefetchLimit,
+			PollDuration:  opts.PollDuration,
+		}, func(delivery Delivery) {
+			select {
+			case deliveries <- delivery:
+			case <-ctx.Done():
+				// 暂停或关闭时尚未分配的消息保持未确认，由后端放回就绪队列
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := 0; i < opts.NumConsumers; i++ {
+		m.workers.Add(1)
+		workers.Add(1)
+		go func() {
+			defer m.workers.Done()
+			defer workers.Done()
+			for {
+				delivery, ok := scheduler.next(ctx)
+				if !ok {
+					return
+				}
+				handler(delivery)
+			}
+		}()
+	}
+	return nil
+}
+
+// next 获取下一条投递：按权重随机排列通道后依次尝试，全部为空时等待任一通道；关闭时返回false
+// 不放回的加权随机使积压通道之间的消费比例与权重一致，不受空闲通道影响
+func (s *laneScheduler) next(ctx context.Context) (Delivery, bool) {
+	if ctx.Err() != nil {
+		return nil, false
+	}
+	for _, l := range s.order() {
+		select {
+		case delivery := <-l.deliveries:
+			return delivery, true
+		default:
+		}
+	}
+
+	cases := make([]reflect.SelectCase, 0, len(s.lanes)+1)
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())})
+	for _, l := range s.lanes {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(l.deliveries)})
+	}
+	chosen, value, ok := reflect.Select(cases)
+	if chosen == 0 || !ok {
+		return nil, false
+	}
+	return value.Interface().(Delivery), true
+}
+
+// order 按权重不放回地随机排列通道
+func (s *laneScheduler) order() []*lane {
+	remaining := append([]*lane(nil), s.lanes...)
+	total := 0
+	for _, l := range remaining {
+		total += l.weight
+	}
+
+	ordered := make([]*lane, 0, len(remaining))
+	for len(remaining) > 0 {
+		r := rand.Intn(total)
+		for i, l := range remaining {
+			if r < l.weight {
+				ordered = append(ordered, l)
+				total -= l.weight
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				break
+			}
+			r -= l.weight
+		}
+	}
+	return ordered
+}
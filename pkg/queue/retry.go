@@ -0,0 +1,124 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"gin-demo/config"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// PermanentError 永久性错误：重试也不会成功（如数据校验失败），消息直接进入死信队列
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return "permanent: " + e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent 将错误标记为永久性错误，err 为空时返回空
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// IsPermanent 判断错误链中是否包含永久性错误
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// RetryPolicy 消息处理失败后的重试策略
+type RetryPolicy struct {
+	MaxAttempts int           // 最大处理次数（含首次），1表示不重试
+	Backoff     string        // config.BackoffFixed / BackoffExponential / BackoffExponentialJitter
+	Delay       time.Duration // 首次重试间隔
+	MaxDelay    time.Duration // 重试间隔上限，0表示不限制
+
+	// Retryable 判断错误是否可重试，为空时除 PermanentError 外均可重试；PermanentError 始终不重试
+	Retryable func(err error) bool
+}
+
+// RetryPolicyProvider 处理器可实现该接口声明自己的重试策略，优先于队列配置
+type RetryPolicyProvider interface {
+	RetryPolicy() RetryPolicy
+}
+
+// NewRetryPolicy 根据队列配置创建重试策略，未配置时使用全局 retry_limit / retry_delay
+func NewRetryPolicy(cfg *config.QueueConfig, queueName string) (RetryPolicy, error) {
+	item := cfg.Queues[queueName].Retry
+	if item == nil {
+		return RetryPolicy{
+			MaxAttempts: cfg.RMQ.RetryLimit + 1,
+			Backoff:     config.BackoffFixed,
+			Delay:       cfg.RMQ.RetryDelay,
+		}, nil
+	}
+
+	policy := RetryPolicy{
+		MaxAttempts: item.MaxAttempts,
+		Backoff:     item.Backoff,
+		Delay:       item.Delay,
+		MaxDelay:    item.MaxDelay,
+	}
+	if policy.Backoff == "" {
+		policy.Backoff = config.BackoffFixed
+	}
+	return policy, policy.Validate()
+}
+
+// Validate 校验重试策略
+func (p RetryPolicy) Validate() error {
+	if p.MaxAttempts < 1 {
+		return fmt.Errorf("retry max_attempts must be at least 1")
+	}
+	switch p.Backoff {
+	case config.BackoffFixed, config.BackoffExponential, config.BackoffExponentialJitter:
+	default:
+		return fmt.Errorf("unknown retry backoff %q", p.Backoff)
+	}
+	if p.Delay < 0 || p.MaxDelay < 0 {
+		return fmt.Errorf("retry delay must not be negative")
+	}
+	return nil
+}
+
+// ShouldRetry 判断第 attempt 次（从1开始）处理失败后是否重试
+func (p RetryPolicy) ShouldRetry(err error, attempt int) bool {
+	if attempt >= p.MaxAttempts || IsPermanent(err) {
+		return false
+	}
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return true
+}
+
+// NextDelay 第 attempt 次（从1开始）处理失败后的重试间隔
+func (p RetryPolicy) NextDelay(attempt int) time.Duration {
+	delay := p.Delay
+	if p.Backoff != config.BackoffFixed {
+		for i := 1; i < attempt; i++ {
+			// 达到上限或即将溢出时停止增长
+			if (p.MaxDelay > 0 && delay >= p.MaxDelay) || delay > math.MaxInt64/2 {
+				break
+			}
+			delay *= 2
+		}
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if p.Backoff == config.BackoffExponentialJitter && delay > 0 {
+		delay = time.Duration(rand.Int63n(int64(delay)))
+	}
+	return delay
+}
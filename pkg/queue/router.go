@@ -0,0 +1,120 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+// ErrUnknownMessageType 队列中没有注册该消息类型的处理函数
+var ErrUnknownMessageType = errors.New("unknown message type")
+
+// MessageType 消息类型：绑定队列、类型名和载荷类型，发布和消费共用，避免类型名拼写不一致或载荷结构不匹配
+type MessageType[T any] struct {
+	Queue string // 逻辑队列名（如 email）
+	Name  string // 消息类型名（如 email.send）
+}
+
+// NewMessageType 声明消息类型
+func NewMessageType[T any](queueName, name string) MessageType[T] {
+	return MessageType[T]{Queue: queueName, Name: name}
+}
+
+// New 创建该类型的消息
+func (t MessageType[T]) New(payload T) (*Message, error) {
+	return NewMessage(t.Name, payload)
+}
+
+// Publish 发布该类型的消息到所属队列
+func (t MessageType[T]) Publish(m *Manager, payload T, opts ...PublishOption) error {
+	message, err := t.New(payload)
+	if err != nil {
+		return fmt.Errorf("failed to create message: %w", err)
+	}
+	return m.Publish(t.Queue, message, opts...)
+}
+
+// messageKey 处理函数 ctx 中保存当前消息的键
+type messageKey struct{}
+
+// MessageFromContext 获取处理函数正在处理的消息（消息ID、元数据等）
+func MessageFromContext(ctx context.Context) (*Message, bool) {
+	message, ok := ctx.Value(messageKey{}).(*Message)
+	return message, ok
+}
+
+// route 一个消息类型的解码和处理函数
+type route func(ctx context.Context, message *Message) error
+
+// Router 按消息类型分发的处理器，多个消息类型可以共享一个队列
+// 载荷自动解码并按 binding 标签校验，解码、校验失败和未注册的类型作为永久性错误进入死信队列
+type Router struct {
+	*BaseHandler
+	mu     sync.RWMutex
+	routes map[string]route
+}
+
+// NewRouter 创建消息类型路由处理器
+func NewRouter(queueName string, numConsumers, prefetchLimit int) *Router {
+	return &Router{
+		BaseHandler: NewBaseHandler(queueName, numConsumers, prefetchLimit),
+		routes:      make(map[string]route),
+	}
+}
+
+// Register 注册消息类型的处理函数，应在 Manager.RegisterHandler 之前完成注册
+func Register[T any](r *Router, msgType MessageType[T], handle func(ctx context.Context, payload T) error) error {
+	if msgType.Queue != r.GetQueueName() {
+		return fmt.Errorf("message type %s belongs to queue %s, not %s", msgType.Name, msgType.Queue, r.GetQueueName())
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.routes[msgType.Name]; exists {
+		return fmt.Errorf("handler for message type %s already registered", msgType.Name)
+	}
+
+	r.routes[msgType.Name] = func(ctx context.Context, message *Message) error {
+		var payload T
+		if err := json.Unmarshal(message.Data, &payload); err != nil {
+			return Permanent(fmt.Errorf("failed to decode %s payload: %w", msgType.Name, err))
+		}
+		if value := reflect.ValueOf(payload); value.Kind() == reflect.Ptr && value.IsNil() {
+			return Permanent(fmt.Errorf("empty %s payload", msgType.Name))
+		}
+		if err := binding.Validator.ValidateStruct(payload); err != nil {
+			return Permanent(fmt.Errorf("invalid %s payload: %w", msgType.Name, err))
+		}
+		return handle(ctx, payload)
+	}
+	return nil
+}
+
+// Handle 解码消息并调用对应类型的处理函数
+func (r *Router) Handle(ctx context.Context, message *Message) error {
+	r.mu.RLock()
+	handle, exists := r.routes[message.Type]
+	r.mu.RUnlock()
+	if !exists {
+		return Permanent(fmt.Errorf("%w %q in queue %s", ErrUnknownMessageType, message.Type, r.GetQueueName()))
+	}
+	return handle(context.WithValue(ctx, messageKey{}, message), message)
+}
+
+// Types 已注册的消息类型
+func (r *Router) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	types := make([]string, 0, len(r.routes))
+	for msgType := range r.routes {
+		types = append(types, msgType)
+	}
+	sort.Strings(types)
+	return types
+}
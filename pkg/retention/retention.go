@@ -0,0 +1,246 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gin-demo/config"
+	"gin-demo/pkg/logger"
+	"gin-demo/pkg/migration"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/schema"
+	"gorm.io/plugin/dbresolver"
+)
+
+// LockName 保留策略任务的数据库咨询锁，多实例部署时同一时间只有一个实例执行
+const LockName = "gin-demo:retention"
+
+// anonymizedAtColumn 匿名化时间列，anonymize 策略要求模型包含该列以跳过已处理的记录
+const anonymizedAtColumn = "anonymized_at"
+
+// Anonymizer 支持匿名化的模型：清除个人信息，保留记录本身（如用于统计或外键引用）
+type Anonymizer interface {
+	Anonymize()
+}
+
+// Result 单个策略的执行结果
+type Result struct {
+	Table  string `json:"table"`
+	Action string `json:"action"`
+	Rows   int64  `json:"rows"`
+}
+
+// policy 已校验的表保留策略
+type policy struct {
+	config.RetentionPolicy
+	model        interface{}
+	table        string
+	primaryKey   string
+	anonymizedAt *schema.Field
+}
+
+// Enforcer 保留策略执行器：分批永久删除或匿名化超过保留时间的软删除记录
+type Enforcer struct {
+	db        *gorm.DB
+	policies  []policy
+	batchSize int
+}
+
+var defaultEnforcer *Enforcer
+
+// NewEnforcer 创建保留策略执行器，校验每个策略对应已注册且支持软删除的模型
+func NewEnforcer(db *gorm.DB, models []interface{}, cfg *config.RetentionConfig) (*Enforcer, error) {
+	schemas := make(map[string]*schema.Schema, len(models))
+	byTable := make(map[string]interface{}, len(models))
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		schemas[stmt.Schema.Table] = stmt.Schema
+		byTable[stmt.Schema.Table] = model
+	}
+
+	e := &Enforcer{db: db, batchSize: cfg.GetBatchSize()}
+	for table, p := range cfg.Policies {
+		model, ok := byTable[table]
+		if !ok {
+			return nil, fmt.Errorf("retention policy for unknown table %s", table)
+		}
+		s := schemas[table]
+		if s.PrioritizedPrimaryField == nil {
+			return nil, fmt.Errorf("table %s has no primary key", table)
+		}
+		if len(s.DeleteClauses) == 0 {
+			return nil, fmt.Errorf("table %s does not support soft delete", table)
+		}
+		if p.After <= 0 {
+			return nil, fmt.Errorf("retention policy for %s: after must be positive", table)
+		}
+		switch p.Action {
+		case config.RetentionActionPurge:
+		case config.RetentionActionAnonymize:
+			if _, ok := model.(Anonymizer); !ok {
+				return nil, fmt.Errorf("model of table %s does not implement Anonymize", table)
+			}
+			if s.LookUpField(anonymizedAtColumn) == nil {
+				return nil, fmt.Errorf("table %s has no %s column", table, anonymizedAtColumn)
+			}
+		default:
+			return nil, fmt.Errorf("retention policy for %s: unknown action %q", table, p.Action)
+		}
+
+		e.policies = append(e.policies, policy{
+			RetentionPolicy: p,
+			model:           model,
+			table:           table,
+			primaryKey:      s.PrioritizedPrimaryField.DBName,
+			anonymizedAt:    s.LookUpField(anonymizedAtColumn),
+		})
+	}
+	return e, nil
+}
+
+// Init 初始化全局保留策略执行器，未启用时不执行
+func Init(cfg *config.Config, db *gorm.DB, models []interface{}) error {
+	if cfg.Retention == nil || !cfg.Retention.Enabled {
+		defaultEnforcer = nil
+		return nil
+	}
+
+	enforcer, err := NewEnforcer(db, models, cfg.Retention)
+	if err != nil {
+		return err
+	}
+	defaultEnforcer = enforcer
+	logger.Info("Retention policies initialized", logger.Int("policies", len(enforcer.policies)))
+	return nil
+}
+
+// Run 使用全局执行器执行保留策略，供定时任务调用
+func Run(ctx context.Context) {
+	if defaultEnforcer == nil {
+		return
+	}
+
+	results, err := defaultEnforcer.Enforce(ctx)
+	if errors.Is(err, migration.ErrLockTimeout) {
+		logger.Info("Retention job is running on another instance, skipped")
+		return
+	}
+	if err != nil {
+		logger.Error("Retention job failed", logger.Err(err))
+	}
+	for _, result := range results {
+		logger.Info("Retention policy enforced",
+			logger.String("table", result.Table),
+			logger.String("action", result.Action),
+			logger.Int64("rows", result.Rows),
+		)
+	}
+}
+
+// Enforce 执行所有保留策略，在数据库咨询锁内运行，锁被其他实例持有时返回 migration.ErrLockTimeout
+func (e *Enforcer) Enforce(ctx context.Context) ([]Result, error) {
+	db := e.db.Clauses(dbresolver.Write).WithContext(ctx)
+	release, err := migration.AcquireLock(ctx, db, LockName, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	results := make([]Result, 0, len(e.policies))
+	for _, p := range e.policies {
+		cutoff := time.Now().Add(-p.After)
+		var rows int64
+		var err error
+		if p.Action == config.RetentionActionPurge {
+			rows, err = e.purge(db, p, cutoff)
+		} else {
+			rows, err = e.anonymize(db, p, cutoff)
+		}
+		results = append(results, Result{Table: p.table, Action: p.Action, Rows: rows})
+		if err != nil {
+			return results, fmt.Errorf("%s %s: %w", p.Action, p.table, err)
+		}
+	}
+	return results, nil
+}
+
+// purge 分批永久删除软删除时间早于 cutoff 的记录
+func (e *Enforcer) purge(db *gorm.DB, p policy, cutoff time.Time) (int64, error) {
+	var total int64
+	for {
+		var ids []interface{}
+		err := db.Model(p.model).Unscoped().
+			Where("deleted_at < ?", cutoff).
+			Order(p.primaryKey).Limit(e.batchSize).
+			Pluck(p.primaryKey, &ids).Error
+		if err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			return total, nil
+		}
+
+		result := db.Model(p.model).Unscoped().Where(map[string]interface{}{p.primaryKey: ids}).Delete(p.model)
+		if result.Error != nil {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+		if len(ids) < e.batchSize {
+			return total, nil
+		}
+	}
+}
+
+// anonymize 分批匿名化软删除时间早于 cutoff 且尚未匿名化的记录，每批一个事务
+// 使用 UpdateColumns 写回，不修改 updated_at；加密字段写入时仍会加密
+func (e *Enforcer) anonymize(db *gorm.DB, p policy, cutoff time.Time) (int64, error) {
+	var total int64
+	for {
+		rows := reflect.New(reflect.SliceOf(reflect.TypeOf(p.model)))
+		err := db.Model(p.model).Unscoped().
+			Where("deleted_at < ? AND anonymized_at IS NULL", cutoff).
+			Order(p.primaryKey).Limit(e.batchSize).
+			Find(rows.Interface()).Error
+		if err != nil {
+			return total, err
+		}
+		count := rows.Elem().Len()
+		if count == 0 {
+			return total, nil
+		}
+
+		now := time.Now()
+		err = db.Transaction(func(tx *gorm.DB) error {
+			for i := 0; i < count; i++ {
+				row := rows.Elem().Index(i)
+				row.Interface().(Anonymizer).Anonymize()
+				if err := p.anonymizedAt.Set(tx.Statement.Context, row, now); err != nil {
+					return err
+				}
+				if hook, ok := row.Interface().(callbacks.BeforeSaveInterface); ok {
+					if err := hook.BeforeSave(tx); err != nil {
+						return err
+					}
+				}
+				if err := tx.Model(row.Interface()).Unscoped().Select("*").UpdateColumns(row.Interface()).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return total, err
+		}
+		total += int64(count)
+		if count < e.batchSize {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package transaction
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"gin-demo/config"
+	"gin-demo/database"
+	"gin-demo/pkg/logger"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+type contextKey struct{}
+
+// txState 上下文中的事务状态
+type txState struct {
+	tx          *gorm.DB
+	root        *txState
+	parent      *txState
+	savepoints  int                         // 仅根事务使用，用于生成保存点名称
+	afterCommit []func(ctx context.Context) // 当前层级注册的提交后回调
+	mu          sync.Mutex
+}
+
+// Options 事务选项
+type Options struct {
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+}
+
+// Option 事务选项函数
+type Option func(*Options)
+
+// WithIsolation 设置事务隔离级别（仅对最外层事务生效）
+func WithIsolation(level sql.IsolationLevel) Option {
+	return func(o *Options) {
+		o.Isolation = level
+	}
+}
+
+// ReadOnly 设置为只读事务（仅对最外层事务生效）
+func ReadOnly() Option {
+	return func(o *Options) {
+		o.ReadOnly = true
+	}
+}
+
+// Manager 事务管理器：将事务放入context，仓储层通过 FromContext 自动使用
+type Manager struct {
+	db               *gorm.DB
+	defaultIsolation sql.IsolationLevel
+}
+
+// NewManager 创建事务管理器，默认隔离级别读取 database.transaction.isolation_level 配置
+func NewManager(db *gorm.DB) *Manager {
+	m := &Manager{db: db}
+	if cfg := config.GetConfig(); cfg != nil && cfg.Database != nil && cfg.Database.Transaction != nil {
+		level, err := ParseIsolationLevel(cfg.Database.Transaction.IsolationLevel)
+		if err != nil {
+			logger.Warn("Invalid transaction isolation level, using database default",
+				logger.String("isolation_level", cfg.Database.Transaction.IsolationLevel), logger.Err(err))
+		}
+		m.defaultIsolation = level
+	}
+	return m
+}
+
+// getDB 获取数据库实例，为空时使用全局数据库连接
+func (m *Manager) getDB() *gorm.DB {
+	if m.db != nil {
+		return m.db
+	}
+	return database.DB
+}
+
+// Run 在事务中执行fn：fn中通过ctx调用的仓储方法自动加入该事务
+// 已处于事务中时创建保存点（嵌套事务），fn返回错误或panic时只回滚到该保存点
+func (m *Manager) Run(ctx context.Context, fn func(ctx context.Context) error, opts ...Option) error {
+	if parent, ok := ctx.Value(contextKey{}).(*txState); ok {
+		return m.runNested(ctx, parent, fn)
+	}
+
+	options := &Options{Isolation: m.defaultIsolation}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	tx := m.getDB().WithContext(ctx).Begin(&sql.TxOptions{
+		Isolation: options.Isolation,
+		ReadOnly:  options.ReadOnly,
+	})
+	if tx.Error != nil {
+		return fmt.Errorf("failed to begin transaction: %w", tx.Error)
+	}
+
+	state := &txState{tx: tx}
+	state.root = state
+
+	if err := runWithRecover(context.WithValue(ctx, contextKey{}, state), fn, func() {
+		tx.Rollback()
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// 提交成功后执行回调（使用不带事务的原始context）
+	for _, hook := range state.afterCommit {
+		runHook(ctx, hook)
+	}
+	return nil
+}
+
+// runNested 使用保存点执行嵌套事务
+func (m *Manager) runNested(ctx context.Context, parent *txState, fn func(ctx context.Context) error) error {
+	root := parent.root
+	root.mu.Lock()
+	root.savepoints++
+	name := fmt.Sprintf("sp_%d", root.savepoints)
+	root.mu.Unlock()
+
+	if err := root.tx.SavePoint(name).Error; err != nil {
+		return fmt.Errorf("failed to create savepoint %s: %w", name, err)
+	}
+
+	state := &txState{tx: root.tx, root: root, parent: parent}
+	if err := runWithRecover(context.WithValue(ctx, contextKey{}, state), fn, func() {
+		root.tx.RollbackTo(name)
+	}); err != nil {
+		// 回滚到保存点，丢弃该层注册的提交后回调
+		return err
+	}
+
+	// 保存点内的回调提升到上一层，随外层事务一起提交或丢弃
+	root.mu.Lock()
+	parent.afterCommit = append(parent.afterCommit, state.afterCommit...)
+	root.mu.Unlock()
+	return nil
+}
+
+// runWithRecover 执行fn，出错或panic时执行rollback（panic会继续向上抛出）
+func runWithRecover(ctx context.Context, fn func(ctx context.Context) error, rollback func()) (err error) {
+	panicked := true
+	defer func() {
+		if panicked {
+			rollback()
+		}
+	}()
+
+	err = fn(ctx)
+	panicked = false
+	if err != nil {
+		rollback()
+	}
+	return err
+}
+
+// runHook 执行提交后回调，回调panic不影响已提交的事务
+func runHook(ctx context.Context, hook func(ctx context.Context)) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("After-commit hook panicked", logger.Any("panic", r))
+		}
+	}()
+	hook(ctx)
+}
+
+// FromContext 获取context中的事务
+func FromContext(ctx context.Context) (*gorm.DB, bool) {
+	if state, ok := ctx.Value(contextKey{}).(*txState); ok {
+		return state.tx, true
+	}
+	return nil, false
+}
+
+// InTransaction 判断context是否处于事务中
+func InTransaction(ctx context.Context) bool {
+	_, ok := FromContext(ctx)
+	return ok
+}
+
+// AfterCommit 注册事务提交后执行的回调；不在事务中时立即执行
+// 典型用法：事务提交后再发布队列消息，避免回滚后消息已发出
+func AfterCommit(ctx context.Context, hook func(ctx context.Context)) {
+	state, ok := ctx.Value(contextKey{}).(*txState)
+	if !ok {
+		runHook(ctx, hook)
+		return
+	}
+
+	state.root.mu.Lock()
+	state.afterCommit = append(state.afterCommit, hook)
+	state.root.mu.Unlock()
+}
+
+// ParseIsolationLevel 解析隔离级别配置
+func ParseIsolationLevel(level string) (sql.IsolationLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "", "default":
+		return sql.LevelDefault, nil
+	case "read_uncommitted":
+		return sql.LevelReadUncommitted, nil
+	case "read_committed":
+		return sql.LevelReadCommitted, nil
+	case "repeatable_read":
+		return sql.LevelRepeatableRead, nil
+	case "serializable":
+		return sql.LevelSerializable, nil
+	default:
+		return sql.LevelDefault, fmt.Errorf("unknown isolation level: %s", level)
+	}
+}
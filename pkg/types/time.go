@@ -60,6 +60,21 @@ func (jt *JSONTime) Scan(value interface{}) error {
 	return fmt.Errorf("cannot scan %T into JSONTime", value)
 }
 
+// GobEncode 实现gob.GobEncoder接口，保留完整精度和时区（用于缓存序列化）
+func (jt JSONTime) GobEncode() ([]byte, error) {
+	return time.Time(jt).GobEncode()
+}
+
+// GobDecode 实现gob.GobDecoder接口
+func (jt *JSONTime) GobDecode(data []byte) error {
+	var t time.Time
+	if err := t.GobDecode(data); err != nil {
+		return err
+	}
+	*jt = JSONTime(t)
+	return nil
+}
+
 // ToTime 转换为标准time.Time类型
 func (jt JSONTime) ToTime() time.Time {
 	return time.Time(jt)
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"gin-demo/database"
+	"gin-demo/pkg/cache"
+
+	"gorm.io/gorm"
+)
+
+// CachedRepository 带读缓存的仓储包装：GetByID 使用 Redis cache-aside，其余方法直接使用底层仓储
+// 缓存失效由 cache.RegisterCallbacks 注册的GORM回调自动完成
+// 实体实现 cache.Sanitizer 时缓存中不含被清除的字段（如密码），读后写前须用 database.WithPrimary 读取完整记录
+type CachedRepository[T any] struct {
+	*Repository[T]
+}
+
+// NewCachedRepository 创建带读缓存的仓储
+func NewCachedRepository[T any](base *Repository[T]) *CachedRepository[T] {
+	return &CachedRepository[T]{Repository: base}
+}
+
+// GetByID 按主键查询，优先读取缓存
+// 事务内或要求读主库（database.WithPrimary）时跳过缓存，保证读到最新数据
+func (r *CachedRepository[T]) GetByID(ctx context.Context, id interface{}) (*T, error) {
+	c := cache.GetCache()
+	db := r.DB(ctx)
+	if _, inTx := db.Statement.ConnPool.(gorm.TxCommitter); c == nil || inTx || database.UsePrimary(ctx) {
+		return r.Repository.GetByID(ctx, id)
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return r.Repository.GetByID(ctx, id)
+	}
+
+	return cache.Fetch(ctx, c, stmt.Table, id, func(ctx context.Context) (*T, error) {
+		return r.Repository.GetByID(ctx, id)
+	})
+}
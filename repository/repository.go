@@ -0,0 +1,299 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gin-demo/database"
+	"gin-demo/model/tool"
+	"gin-demo/pkg/transaction"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/plugin/dbresolver"
+)
+
+// Scope 查询作用域
+type Scope func(db *gorm.DB) *gorm.DB
+
+// softDeleteColumn 软删除字段列名（gorm.DeletedAt）
+const softDeleteColumn = "deleted_at"
+
+// Repository 泛型仓储，封装通用的CRUD、分页、软删除、Upsert和批量操作
+type Repository[T any] struct {
+	db *gorm.DB // 注入的数据库连接或事务，为空时使用全局数据库连接
+}
+
+// NewRepository 创建泛型仓储
+func NewRepository[T any](db *gorm.DB) *Repository[T] {
+	return &Repository[T]{db: db}
+}
+
+// DB 获取绑定上下文的数据库会话，context中存在事务（transaction.Manager）时优先使用该事务
+// 配置了只读副本时读请求走副本，database.WithPrimary(ctx) 可强制走主库
+func (r *Repository[T]) DB(ctx context.Context) *gorm.DB {
+	if tx, ok := transaction.FromContext(ctx); ok {
+		return tx.WithContext(ctx)
+	}
+
+	db := r.db
+	if db == nil {
+		db = database.DB
+	}
+	if database.UsePrimary(ctx) {
+		db = db.Clauses(dbresolver.Write)
+	}
+	return db.WithContext(ctx)
+}
+
+// WithTx 返回绑定到指定事务的仓储实例
+func (r *Repository[T]) WithTx(tx *gorm.DB) *Repository[T] {
+	return &Repository[T]{db: tx}
+}
+
+// Transaction 在事务中执行fn，fn返回错误时整体回滚
+func (r *Repository[T]) Transaction(ctx context.Context, fn func(txRepo *Repository[T]) error) error {
+	return r.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(r.WithTx(tx))
+	})
+}
+
+// SavePoint 在当前事务中创建保存点
+func (r *Repository[T]) SavePoint(ctx context.Context, name string) error {
+	return r.DB(ctx).SavePoint(name).Error
+}
+
+// RollbackTo 回滚到指定保存点
+func (r *Repository[T]) RollbackTo(ctx context.Context, name string) error {
+	return r.DB(ctx).RollbackTo(name).Error
+}
+
+// Create 创建记录
+func (r *Repository[T]) Create(ctx context.Context, entity *T) error {
+	return r.DB(ctx).Create(entity).Error
+}
+
+// IsDuplicateKey 错误是否为唯一约束冲突，由数据库驱动转换（MySQL 1062、PostgreSQL 23505、SQLite UNIQUE）
+func (r *Repository[T]) IsDuplicateKey(ctx context.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+	translator, ok := r.DB(ctx).Dialector.(gorm.ErrorTranslator)
+	return ok && errors.Is(translator.Translate(err), gorm.ErrDuplicatedKey)
+}
+
+// CreateInBatches 分批创建记录
+func (r *Repository[T]) CreateInBatches(ctx context.Context, entities []T, batchSize int) error {
+	if len(entities) == 0 {
+		return nil
+	}
+	return r.DB(ctx).CreateInBatches(entities, batchSize).Error
+}
+
+// Save 保存记录（存在则更新所有字段，不存在则创建）
+func (r *Repository[T]) Save(ctx context.Context, entity *T) error {
+	return r.DB(ctx).Save(entity).Error
+}
+
+// Updates 更新记录的指定字段，values 可以是结构体或 map
+func (r *Repository[T]) Updates(ctx context.Context, entity *T, values interface{}) error {
+	return r.DB(ctx).Model(entity).Updates(values).Error
+}
+
+// UpdateWhere 按条件批量更新，返回影响行数
+func (r *Repository[T]) UpdateWhere(ctx context.Context, values interface{}, scopes ...Scope) (int64, error) {
+	result := r.DB(ctx).Model(new(T)).Scopes(toGormScopes(scopes)...).Updates(values)
+	return result.RowsAffected, result.Error
+}
+
+// Upsert 插入记录，冲突时更新指定字段；updateColumns 为空时更新全部字段
+func (r *Repository[T]) Upsert(ctx context.Context, entity *T, conflictColumns []string, updateColumns []string) error {
+	return r.DB(ctx).Clauses(onConflict(conflictColumns, updateColumns)).Create(entity).Error
+}
+
+// UpsertInBatches 分批Upsert
+func (r *Repository[T]) UpsertInBatches(ctx context.Context, entities []T, batchSize int, conflictColumns []string, updateColumns []string) error {
+	if len(entities) == 0 {
+		return nil
+	}
+	return r.DB(ctx).Clauses(onConflict(conflictColumns, updateColumns)).CreateInBatches(entities, batchSize).Error
+}
+
+// Delete 按主键删除记录（模型包含 gorm.DeletedAt 时为软删除）
+func (r *Repository[T]) Delete(ctx context.Context, id interface{}) error {
+	return r.DB(ctx).Delete(new(T), id).Error
+}
+
+// DeleteByIDs 按主键批量删除，返回影响行数
+func (r *Repository[T]) DeleteByIDs(ctx context.Context, ids interface{}) (int64, error) {
+	result := r.DB(ctx).Delete(new(T), ids)
+	return result.RowsAffected, result.Error
+}
+
+// DeleteWhere 按条件删除，返回影响行数
+func (r *Repository[T]) DeleteWhere(ctx context.Context, scopes ...Scope) (int64, error) {
+	result := r.DB(ctx).Scopes(toGormScopes(scopes)...).Delete(new(T))
+	return result.RowsAffected, result.Error
+}
+
+// GetByID 按主键查询
+func (r *Repository[T]) GetByID(ctx context.Context, id interface{}) (*T, error) {
+	var entity T
+	err := r.DB(ctx).First(&entity, id).Error
+	return &entity, err
+}
+
+// First 按条件查询第一条记录
+func (r *Repository[T]) First(ctx context.Context, scopes ...Scope) (*T, error) {
+	var entity T
+	err := r.DB(ctx).Scopes(toGormScopes(scopes)...).First(&entity).Error
+	return &entity, err
+}
+
+// Find 按条件查询记录列表
+func (r *Repository[T]) Find(ctx context.Context, scopes ...Scope) ([]T, error) {
+	var entities []T
+	err := r.DB(ctx).Scopes(toGormScopes(scopes)...).Find(&entities).Error
+	return entities, err
+}
+
+// FindByIDs 按主键批量查询
+func (r *Repository[T]) FindByIDs(ctx context.Context, ids interface{}) ([]T, error) {
+	var entities []T
+	err := r.DB(ctx).Find(&entities, ids).Error
+	return entities, err
+}
+
+// FindInBatches 分批遍历符合条件的记录，fn 返回错误时停止遍历
+func (r *Repository[T]) FindInBatches(ctx context.Context, batchSize int, fn func(batch []T) error, scopes ...Scope) error {
+	var entities []T
+	return r.DB(ctx).Scopes(toGormScopes(scopes)...).FindInBatches(&entities, batchSize, func(tx *gorm.DB, batch int) error {
+		return fn(entities)
+	}).Error
+}
+
+// Count 按条件统计记录数
+func (r *Repository[T]) Count(ctx context.Context, scopes ...Scope) (int64, error) {
+	var count int64
+	err := r.DB(ctx).Model(new(T)).Scopes(toGormScopes(scopes)...).Count(&count).Error
+	return count, err
+}
+
+// Exists 判断是否存在符合条件的记录
+func (r *Repository[T]) Exists(ctx context.Context, scopes ...Scope) (bool, error) {
+	var found []int
+	result := r.DB(ctx).Model(new(T)).Scopes(toGormScopes(scopes)...).Select("1").Limit(1).Find(&found)
+	return len(found) > 0, result.Error
+}
+
+// Paginate 分页查询，返回当前页数据和总数
+func (r *Repository[T]) Paginate(ctx context.Context, pagination *tool.PaginationRequest, scopes ...Scope) ([]T, int64, error) {
+	var entities []T
+	var total int64
+
+	gormScopes := toGormScopes(scopes)
+
+	// 获取总数
+	if err := r.DB(ctx).Model(new(T)).Scopes(gormScopes...).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 使用Scopes进行分页查询
+	err := r.DB(ctx).Scopes(append(gormScopes, pagination.Paginate())...).Find(&entities).Error
+	return entities, total, err
+}
+
+// Restore 恢复软删除的记录
+func (r *Repository[T]) Restore(ctx context.Context, id interface{}) error {
+	return r.DB(ctx).Unscoped().Model(new(T)).
+		Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).
+		Update(softDeleteColumn, nil).Error
+}
+
+// ForceDelete 按主键永久删除记录（忽略软删除）
+func (r *Repository[T]) ForceDelete(ctx context.Context, id interface{}) error {
+	return r.DB(ctx).Unscoped().Delete(new(T), id).Error
+}
+
+// Where 条件作用域
+func Where(query interface{}, args ...interface{}) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(query, args...)
+	}
+}
+
+// OrderBy 排序作用域
+func OrderBy(value interface{}) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Order(value)
+	}
+}
+
+// likeEscape LIKE转义字符，'!' 在MySQL/PostgreSQL/SQLite字符串字面量中含义一致
+const likeEscape = "!"
+
+var likeReplacer = strings.NewReplacer(likeEscape, likeEscape+likeEscape, "%", likeEscape+"%", "_", likeEscape+"_")
+
+// Search 关键词模糊搜索作用域：多个列之间为 OR 关系，关键词中的通配符按字面匹配
+// PostgreSQL 使用 ILIKE，与MySQL/SQLite的大小写不敏感语义保持一致
+func Search(keyword string, columns ...string) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		if keyword == "" || len(columns) == 0 {
+			return db
+		}
+
+		operator := "LIKE"
+		if db.Dialector.Name() == "postgres" {
+			operator = "ILIKE"
+		}
+
+		pattern := "%" + likeReplacer.Replace(keyword) + "%"
+		conditions := make([]string, 0, len(columns))
+		args := make([]interface{}, 0, len(columns))
+		for _, column := range columns {
+			conditions = append(conditions, fmt.Sprintf("%s %s ? ESCAPE '%s'", db.Statement.Quote(column), operator, likeEscape))
+			args = append(args, pattern)
+		}
+		return db.Where(strings.Join(conditions, " OR "), args...)
+	}
+}
+
+// WithTrashed 包含软删除记录的作用域
+func WithTrashed() Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}
+}
+
+// OnlyTrashed 仅查询软删除记录的作用域
+func OnlyTrashed() Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped().Where("? IS NOT NULL", clause.Column{Table: clause.CurrentTable, Name: softDeleteColumn})
+	}
+}
+
+// toGormScopes 转换为GORM作用域
+func toGormScopes(scopes []Scope) []func(*gorm.DB) *gorm.DB {
+	gormScopes := make([]func(*gorm.DB) *gorm.DB, 0, len(scopes)+1)
+	for _, scope := range scopes {
+		gormScopes = append(gormScopes, scope)
+	}
+	return gormScopes
+}
+
+// onConflict 构造Upsert冲突处理子句
+func onConflict(conflictColumns []string, updateColumns []string) clause.OnConflict {
+	columns := make([]clause.Column, 0, len(conflictColumns))
+	for _, name := range conflictColumns {
+		columns = append(columns, clause.Column{Name: name})
+	}
+
+	if len(updateColumns) == 0 {
+		return clause.OnConflict{Columns: columns, UpdateAll: true}
+	}
+	return clause.OnConflict{Columns: columns, DoUpdates: clause.AssignmentColumns(updateColumns)}
+}
@@ -1,87 +1,162 @@
 package repository
 
 import (
-	"gin-demo/database"
+	"context"
 	"gin-demo/model"
 	"gin-demo/model/tool"
+	"gin-demo/pkg/encryption"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-type UserRepository struct{}
+type UserRepository struct {
+	base   *Repository[model.User]
+	cached *CachedRepository[model.User]
+}
 
-func NewUserRepository() *UserRepository {
-	return &UserRepository{}
+func NewUserRepository(db *gorm.DB) *UserRepository {
+	return newUserRepository(NewRepository[model.User](db))
 }
 
-func (r *UserRepository) Create(user *model.User) error {
-	return database.DB.Create(user).Error
+func newUserRepository(base *Repository[model.User]) *UserRepository {
+	return &UserRepository{
+		base:   base,
+		cached: NewCachedRepository(base),
+	}
 }
 
-func (r *UserRepository) GetByID(id uint) (*model.User, error) {
-	var user model.User
-	err := database.DB.First(&user, id).Error
-	return &user, err
+// WithTx 返回绑定到指定事务的仓储实例
+func (r *UserRepository) WithTx(tx *gorm.DB) *UserRepository {
+	return newUserRepository(r.base.WithTx(tx))
 }
 
-func (r *UserRepository) GetAll() ([]model.User, error) {
-	var users []model.User
-	err := database.DB.Find(&users).Error
-	return users, err
+// Transaction 在事务中执行fn，fn返回错误时整体回滚
+func (r *UserRepository) Transaction(ctx context.Context, fn func(txRepo *UserRepository) error) error {
+	return r.base.Transaction(ctx, func(txBase *Repository[model.User]) error {
+		return fn(newUserRepository(txBase))
+	})
 }
 
-func (r *UserRepository) GetByEmail(email string) (*model.User, error) {
-	var user model.User
-	err := database.DB.Where("email = ?", email).First(&user).Error
-	return &user, err
+// SavePoint 在当前事务中创建保存点
+func (r *UserRepository) SavePoint(ctx context.Context, name string) error {
+	return r.base.SavePoint(ctx, name)
 }
 
-func (r *UserRepository) EmailExists(email string) (bool, error) {
-	var count int64
-	err := database.DB.Model(&model.User{}).Where("email = ?", email).Count(&count).Error
-	return count > 0, err
+// RollbackTo 回滚到指定保存点
+func (r *UserRepository) RollbackTo(ctx context.Context, name string) error {
+	return r.base.RollbackTo(ctx, name)
 }
 
-func (r *UserRepository) Update(user *model.User) error {
-	return database.DB.Save(user).Error
+func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
+	return r.base.Create(ctx, user)
 }
 
-func (r *UserRepository) Delete(id uint) error {
-	return database.DB.Delete(&model.User{}, id).Error
+// IsDuplicateKey 错误是否为唯一约束冲突（如邮箱、手机号盲索引重复）
+func (r *UserRepository) IsDuplicateKey(ctx context.Context, err error) bool {
+	return r.base.IsDuplicateKey(ctx, err)
 }
 
-// GetAllWithPagination 分页获取用户列表 - 使用GORM Scopes优化版本
-func (r *UserRepository) GetAllWithPagination(pagination *tool.PaginationRequest) ([]model.User, int64, error) {
-	var users []model.User
-	var total int64
+// GetByID 按主键查询（带读缓存）
+func (r *UserRepository) GetByID(ctx context.Context, id uint) (*model.User, error) {
+	return r.cached.GetByID(ctx, id)
+}
 
-	// 获取总数
-	if err := database.DB.Model(&model.User{}).Count(&total).Error; err != nil {
-		return nil, 0, err
-	}
+func (r *UserRepository) GetAll(ctx context.Context) ([]model.User, error) {
+	return r.base.Find(ctx)
+}
 
-	// 使用Scopes进行分页查询 - 更简洁优雅
-	err := database.DB.Scopes(pagination.Paginate()).Find(&users).Error
-	return users, total, err
+// GetByEmail 按邮箱精确查询（邮箱加密存储，通过盲索引匹配）
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	return r.base.First(ctx, emailEquals(email))
 }
 
-// GetAllWithPaginationAndSearch 带搜索的分页查询
-func (r *UserRepository) GetAllWithPaginationAndSearch(pagination *tool.PaginationRequest, keyword string) ([]model.User, int64, error) {
-	var users []model.User
-	var total int64
+func (r *UserRepository) EmailExists(ctx context.Context, email string) (bool, error) {
+	return r.base.Exists(ctx, emailEquals(email))
+}
+
+func (r *UserRepository) Update(ctx context.Context, user *model.User) error {
+	return r.base.Save(ctx, user)
+}
+
+// Delete 软删除用户，同时清空盲索引使邮箱和手机号可被重新注册
+func (r *UserRepository) Delete(ctx context.Context, id uint) error {
+	return r.base.DB(ctx).Model(&model.User{}).
+		Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).
+		UpdateColumns(map[string]interface{}{
+			"deleted_at": time.Now(),
+			"email_bidx": nil,
+			"phone_bidx": nil,
+		}).Error
+}
+
+// GetTrashedByID 查询回收站中的用户
+func (r *UserRepository) GetTrashedByID(ctx context.Context, id uint) (*model.User, error) {
+	return r.base.First(ctx, OnlyTrashed(), Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}))
+}
 
-	query := database.DB.Model(&model.User{})
+// GetTrashedWithPagination 分页查询回收站中的用户，按删除时间倒序
+func (r *UserRepository) GetTrashedWithPagination(ctx context.Context, pagination *tool.PaginationRequest) ([]model.User, int64, error) {
+	return r.base.Paginate(ctx, pagination, OnlyTrashed(), OrderBy("deleted_at DESC"))
+}
 
-	// 如果有搜索关键词，添加搜索条件
-	if keyword != "" {
-		searchPattern := "%" + keyword + "%"
-		query = query.Where("name LIKE ? OR email LIKE ?", searchPattern, searchPattern)
+// Restore 恢复软删除的用户并重新计算盲索引，邮箱或手机号已被占用时违反唯一约束返回错误
+func (r *UserRepository) Restore(ctx context.Context, user *model.User) error {
+	user.DeletedAt = gorm.DeletedAt{}
+	return r.base.DB(ctx).Unscoped().Save(user).Error
+}
+
+// ForceDelete 永久删除用户
+func (r *UserRepository) ForceDelete(ctx context.Context, id uint) error {
+	return r.base.ForceDelete(ctx, id)
+}
+
+// PhoneExists 判断手机号是否已被未删除的用户使用
+func (r *UserRepository) PhoneExists(ctx context.Context, phone string) (bool, error) {
+	return r.base.Exists(ctx, phoneEquals(phone))
+}
+
+// GetAllWithPagination 分页获取用户列表 - 使用GORM Scopes优化版本
+func (r *UserRepository) GetAllWithPagination(ctx context.Context, pagination *tool.PaginationRequest) ([]model.User, int64, error) {
+	return r.base.Paginate(ctx, pagination)
+}
+
+// GetAllWithPaginationAndSearch 带搜索的分页查询
+func (r *UserRepository) GetAllWithPaginationAndSearch(ctx context.Context, pagination *tool.PaginationRequest, keyword string) ([]model.User, int64, error) {
+	// 关键词为空时不添加搜索条件
+	return r.base.Paginate(ctx, pagination, searchUsers(keyword))
+}
+
+// emailEquals 邮箱精确匹配作用域（大小写不敏感）
+func emailEquals(email string) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		indexes := encryption.BlindIndexes(email)
+		if len(indexes) == 0 {
+			return db.Where("1 = 0")
+		}
+		return db.Where("email_bidx IN ?", indexes)
 	}
+}
 
-	// 获取总数
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
+// phoneEquals 手机号精确匹配作用域
+func phoneEquals(phone string) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		indexes := encryption.BlindIndexes(phone)
+		if len(indexes) == 0 {
+			return db.Where("1 = 0")
+		}
+		return db.Where("phone_bidx IN ?", indexes)
 	}
+}
 
-	// 分页查询
-	err := query.Scopes(pagination.Paginate()).Find(&users).Error
-	return users, total, err
+// searchUsers 用户搜索作用域：姓名模糊匹配，邮箱加密存储只支持完整邮箱精确匹配
+func searchUsers(keyword string) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		if keyword == "" {
+			return db
+		}
+		byName := Search(keyword, "name")(db.Session(&gorm.Session{NewDB: true}))
+		return db.Where(byName.Or(emailEquals(keyword)(db.Session(&gorm.Session{NewDB: true}))))
+	}
 }
@@ -0,0 +1,44 @@
+package router
+
+import (
+	"gin-demo/controller"
+	"gin-demo/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupAdminRoutes 设置管理相关路由，整个分组仅管理员可访问
+func SetupAdminRoutes(api *gin.RouterGroup, userController *controller.UserController, queueController *controller.QueueController) {
+	adminGroup := api.Group("/admin")
+	adminGroup.Use(middleware.JWTAuthMiddleware(), middleware.RequireAdmin(), middleware.IdempotencyMiddleware())
+
+	// 用户回收站：软删除的用户按保留策略（retention）定期清理
+	trash := adminGroup.Group("/users/trash")
+	{
+		trash.GET("", userController.GetTrashedUsers)
+		trash.POST("/:id/restore", userController.RestoreUser)
+		trash.DELETE("/:id", userController.ForceDeleteUser)
+	}
+
+	// 队列管理：状态、暂停/恢复消费、查看和清空消息
+	queues := adminGroup.Group("/queues")
+	{
+		queues.GET("", queueController.ListQueues)
+		queues.GET("/:queue", queueController.GetQueue)
+		queues.POST("/:queue/pause", queueController.PauseQueue)
+		queues.POST("/:queue/resume", queueController.ResumeQueue)
+		queues.GET("/:queue/messages", queueController.PeekMessages)
+		queues.DELETE("/:queue/messages", queueController.PurgeQueue)
+	}
+
+	// 死信队列：重试耗尽或无法解析的消息
+	deadLetters := adminGroup.Group("/queues/:queue/dead_letters")
+	{
+		deadLetters.GET("", queueController.GetDeadLetters)
+		deadLetters.DELETE("", queueController.PurgeDeadLetters)
+		deadLetters.POST("/replay", queueController.ReplayDeadLetters)
+		deadLetters.GET("/:id", queueController.GetDeadLetter)
+		deadLetters.DELETE("/:id", queueController.DeleteDeadLetter)
+		deadLetters.POST("/:id/replay", queueController.ReplayDeadLetter)
+	}
+}
@@ -2,6 +2,7 @@ package router
 
 import (
 	"gin-demo/controller"
+	"gin-demo/pkg/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -9,11 +10,12 @@ import (
 // SetupEmailRoutes 设置邮件相关路由
 func SetupEmailRoutes(api *gin.RouterGroup, emailController *controller.EmailController) {
 	emailGroup := api.Group("/email_queue")
+	emailGroup.Use(middleware.IdempotencyMiddleware())
 	emailGroup.POST("/send", emailController.SendEmail)
 
 	// 发送测试邮件（GET请求，方便浏览器测试）
 	emailGroup.GET("/test", emailController.SendTestEmail)
 
-	// 获取邮件队列状态
-	emailGroup.GET("/status", emailController.GetEmailStatus)
+	// 获取邮件队列状态（包含队列内部统计，仅管理员）
+	emailGroup.GET("/status", middleware.JWTAuthMiddleware(), middleware.RequireAdmin(), emailController.GetEmailStatus)
 }
@@ -5,7 +5,6 @@ import (
 	"gin-demo/pkg/middleware"
 	"time"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-contrib/pprof"
 	"github.com/gin-gonic/gin"
@@ -28,14 +27,7 @@ func SetupRouter() *gin.Engine {
 	r.Use(gzip.Gzip(gzip.DefaultCompression))
 
 	// CORS中间件
-	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	}))
+	r.Use(middleware.CORSMiddleware())
 
 	// 性能监控 (仅在开发环境)
 	if gin.Mode() != gin.ReleaseMode {
@@ -58,6 +50,9 @@ func SetupRouter() *gin.Engine {
 
 		// 用户路由
 		SetupUserRoutes(api, container.UserController)
+
+		// 管理路由
+		SetupAdminRoutes(api, container.UserController, container.QueueController)
 	}
 
 	// 设置404和405处理器
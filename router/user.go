@@ -11,10 +11,12 @@ import (
 func SetupUserRoutes(api *gin.RouterGroup, userController *controller.UserController) {
 	userGroup := api.Group("/users")
 
-	// 应用JWT认证中间件到所有用户路由
-	userGroup.Use(middleware.JWTAuthMiddleware())
+	// 应用JWT认证中间件到所有用户路由，写操作支持幂等键
+	userGroup.Use(middleware.JWTAuthMiddleware(), middleware.IdempotencyMiddleware())
 	{
 		userGroup.POST("/", userController.CreateUser)
+		// 批量创建/更新/删除用户仅管理员可用
+		userGroup.POST("/batch", middleware.RequireAdmin(), userController.BatchUsers)
 		userGroup.GET("/", userController.GetAllUsers)
 		userGroup.GET("/paginated", userController.GetUsersWithPagination)
 		userGroup.GET("/:id", userController.GetUser)
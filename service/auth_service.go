@@ -1,62 +1,77 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"gin-demo/database"
 	"gin-demo/model"
 	"gin-demo/pkg/auth"
 	"gin-demo/pkg/logger"
+	"gin-demo/pkg/transaction"
 	"gin-demo/repository"
 	"gorm.io/gorm"
+	"time"
 )
 
 type AuthService struct {
-	userRepo *repository.UserRepository
+	userRepo  *repository.UserRepository
+	txManager *transaction.Manager
 }
 
-func NewAuthService(userRepo *repository.UserRepository) *AuthService {
+func NewAuthService(userRepo *repository.UserRepository, txManager *transaction.Manager) *AuthService {
 	return &AuthService{
-		userRepo: userRepo,
+		userRepo:  userRepo,
+		txManager: txManager,
 	}
 }
 
 // Register 用户注册
-func (s *AuthService) Register(req *model.RegisterRequest) (*model.LoginResponse, error) {
-	// 检查邮箱是否已存在
-	exists, err := s.userRepo.EmailExists(req.Email)
-	if err != nil {
-		logger.Error("Failed to check email existence",
-			logger.Err(err),
-			logger.String("email", req.Email))
-		return nil, err
-	}
-	if exists {
-		return nil, errors.New("email already exists")
-	}
-
-	// 加密密码
+func (s *AuthService) Register(ctx context.Context, req *model.RegisterRequest) (*model.LoginResponse, error) {
+	// 加密密码（耗时操作放在事务外）
 	hashedPassword, err := auth.HashPassword(req.Password)
 	if err != nil {
 		logger.Error("Failed to hash password", logger.Err(err))
 		return nil, err
 	}
 
-	// 创建用户
 	user := &model.User{
 		Name:     req.Name,
 		Email:    req.Email,
 		Password: hashedPassword,
 		Age:      req.Age,
+		Role:     model.RoleUser,
 	}
 
-	if err := s.userRepo.Create(user); err != nil {
-		logger.Error("Failed to create user",
-			logger.Err(err),
-			logger.String("email", req.Email))
-		return nil, err // 直接返回数据库错误
+	// 先检查邮箱以便直接返回冲突；并发注册同一邮箱时由盲索引唯一约束保证只有一个成功
+	err = s.txManager.Run(ctx, func(ctx context.Context) error {
+		exists, err := s.userRepo.EmailExists(ctx, req.Email)
+		if err != nil {
+			logger.Error("Failed to check email existence",
+				logger.Err(err),
+				logger.String("email", req.Email))
+			return err
+		}
+		if exists {
+			return errors.New("email already exists")
+		}
+
+		if err := s.userRepo.Create(ctx, user); err != nil {
+			if s.userRepo.IsDuplicateKey(ctx, err) {
+				return errors.New("email already exists")
+			}
+			logger.Error("Failed to create user",
+				logger.Err(err),
+				logger.String("email", req.Email))
+			return err // 直接返回数据库错误
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// 生成JWT Token
-	token, expiresAt, err := auth.GenerateToken(user.ID, user.Email)
+	token, expiresAt, err := auth.GenerateToken(user.ID, user.Email, user.Role)
 	if err != nil {
 		logger.Error("Failed to generate token",
 			logger.Err(err),
@@ -81,9 +96,9 @@ func (s *AuthService) Register(req *model.RegisterRequest) (*model.LoginResponse
 }
 
 // Login 用户登录
-func (s *AuthService) Login(req *model.LoginRequest) (*model.LoginResponse, error) {
+func (s *AuthService) Login(ctx context.Context, req *model.LoginRequest) (*model.LoginResponse, error) {
 	// 根据邮箱查找用户
-	user, err := s.userRepo.GetByEmail(req.Email)
+	user, err := s.userRepo.GetByEmail(ctx, req.Email)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			logger.Warn("Login attempt with non-existent email",
@@ -105,7 +120,7 @@ func (s *AuthService) Login(req *model.LoginRequest) (*model.LoginResponse, erro
 	}
 
 	// 生成JWT Token
-	token, expiresAt, err := auth.GenerateToken(user.ID, user.Email)
+	token, expiresAt, err := auth.GenerateToken(user.ID, user.Email, user.Role)
 	if err != nil {
 		logger.Error("Failed to generate token",
 			logger.Err(err),
@@ -129,9 +144,18 @@ func (s *AuthService) Login(req *model.LoginRequest) (*model.LoginResponse, erro
 	}, nil
 }
 
+// RefreshToken 刷新Token，从主库读取用户使角色变更和注销在刷新时生效
+func (s *AuthService) RefreshToken(ctx context.Context, userID uint) (string, time.Time, error) {
+	user, err := s.userRepo.GetByID(database.WithPrimary(ctx), userID)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return auth.GenerateToken(user.ID, user.Email, user.Role)
+}
+
 // GetCurrentUser 获取当前用户信息
-func (s *AuthService) GetCurrentUser(userID uint) (*model.UserResponse, error) {
-	user, err := s.userRepo.GetByID(userID)
+func (s *AuthService) GetCurrentUser(ctx context.Context, userID uint) (*model.UserResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		logger.Error("Failed to get current user",
 			logger.Err(err),
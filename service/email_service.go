@@ -1,7 +1,8 @@
 package service
 
 import (
-	"fmt"
+	"context"
+	"gin-demo/pkg/outbox"
 	"gin-demo/pkg/queue"
 )
 
@@ -16,7 +17,8 @@ func NewEmailService() *EmailService {
 }
 
 // SendEmail 发送邮件（异步）
-func (s *EmailService) SendEmail(to []string, subject, body string, isHTML bool) error {
+// 消息先写入发件箱，处于事务中时随业务数据一起提交或回滚，由 outbox.Relay 投递到队列
+func (s *EmailService) SendEmail(ctx context.Context, to []string, subject, body string, isHTML bool) error {
 	emailData := &queue.EmailData{
 		To:      to,
 		Subject: subject,
@@ -24,11 +26,5 @@ func (s *EmailService) SendEmail(to []string, subject, body string, isHTML bool)
 		IsHTML:  isHTML,
 	}
 
-	// 直接使用全局队列管理器
-	manager := queue.GetManager()
-	if manager == nil {
-		return fmt.Errorf("queue manager not initialized")
-	}
-
-	return manager.PublishData(queue.Email, "email.send", emailData)
+	return outbox.PublishType(ctx, queue.EmailSend, emailData)
 }
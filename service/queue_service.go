@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gin-demo/model/tool"
+	"gin-demo/pkg/queue"
+	"time"
+)
+
+var (
+	// ErrQueueNotFound 队列未配置
+	ErrQueueNotFound = errors.New("queue not found")
+	// ErrQueueUnavailable 队列管理器未初始化
+	ErrQueueUnavailable = errors.New("queue manager unavailable")
+)
+
+// MessageResponse 消息响应（死信和队列中的消息），消息内容为JSON时原样输出，否则输出字符串
+type MessageResponse struct {
+	ID         string                 `json:"id"`
+	Type       string                 `json:"type"`
+	Data       interface{}            `json:"data"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+	Timestamp  time.Time              `json:"timestamp"`
+	RetryCount int                    `json:"retry_count"`
+}
+
+// QueueStatusResponse 队列状态
+type QueueStatusResponse struct {
+	Name        string `json:"name"`
+	Paused      bool   `json:"paused"`
+	DeadLetters int64  `json:"dead_letters"`
+	queue.QueueStats
+}
+
+// QueueService 队列管理服务
+type QueueService struct{}
+
+// NewQueueService 创建队列管理服务（直接使用全局队列管理器）
+func NewQueueService() *QueueService {
+	return &QueueService{}
+}
+
+// manager 获取队列管理器并校验队列已配置
+func (s *QueueService) manager(queueName string) (*queue.Manager, error) {
+	manager := queue.GetManager()
+	if manager == nil {
+		return nil, ErrQueueUnavailable
+	}
+	if !manager.HasQueue(queueName) {
+		return nil, ErrQueueNotFound
+	}
+	return manager, nil
+}
+
+// ListQueues 获取所有已配置队列的状态
+func (s *QueueService) ListQueues(ctx context.Context) ([]*QueueStatusResponse, error) {
+	manager := queue.GetManager()
+	if manager == nil {
+		return nil, ErrQueueUnavailable
+	}
+
+	queues := manager.Queues()
+	responses := make([]*QueueStatusResponse, 0, len(queues))
+	for _, queueName := range queues {
+		status, err := s.queueStatus(ctx, manager, queueName)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, status)
+	}
+	return responses, nil
+}
+
+// GetQueue 获取队列状态（待处理、处理中、已拒绝、延迟和死信数量，消费者数量，是否暂停）
+func (s *QueueService) GetQueue(ctx context.Context, queueName string) (*QueueStatusResponse, error) {
+	manager, err := s.manager(queueName)
+	if err != nil {
+		return nil, err
+	}
+	return s.queueStatus(ctx, manager, queueName)
+}
+
+// queueStatus 汇总队列状态
+func (s *QueueService) queueStatus(ctx context.Context, manager *queue.Manager, queueName string) (*QueueStatusResponse, error) {
+	stats, err := manager.GetQueueStats(ctx, queueName)
+	if err != nil {
+		return nil, err
+	}
+	paused, err := manager.IsPaused(ctx, queueName)
+	if err != nil {
+		return nil, err
+	}
+	deadLetters, err := manager.DeadLetters().Count(ctx, queueName)
+	if err != nil {
+		return nil, err
+	}
+	return &QueueStatusResponse{
+		Name:        queueName,
+		Paused:      paused,
+		DeadLetters: deadLetters,
+		QueueStats:  stats,
+	}, nil
+}
+
+// PauseQueue 暂停队列消费
+func (s *QueueService) PauseQueue(ctx context.Context, queueName string) error {
+	manager, err := s.manager(queueName)
+	if err != nil {
+		return err
+	}
+	return manager.PauseQueue(ctx, queueName)
+}
+
+// ResumeQueue 恢复队列消费
+func (s *QueueService) ResumeQueue(ctx context.Context, queueName string) error {
+	manager, err := s.manager(queueName)
+	if err != nil {
+		return err
+	}
+	return manager.ResumeQueue(ctx, queueName)
+}
+
+// PurgeQueue 清空队列中指定状态（ready / rejected）的消息，返回清除数量
+func (s *QueueService) PurgeQueue(ctx context.Context, queueName, state string) (int64, error) {
+	manager, err := s.manager(queueName)
+	if err != nil {
+		return 0, err
+	}
+	return manager.PurgeQueue(ctx, queueName, state)
+}
+
+// PeekMessages 分页查看队列中指定状态和优先级通道的消息，最早进入的在前
+func (s *QueueService) PeekMessages(ctx context.Context, queueName, state, priority string, pagination *tool.PaginationRequest) (*tool.PaginateResult, error) {
+	manager, err := s.manager(queueName)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, total, err := manager.PeekMessages(ctx, queueName, state, priority, pagination.GetOffset(), pagination.GetPageSize())
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]*MessageResponse, 0, len(messages))
+	for _, message := range messages {
+		responses = append(responses, toMessageResponse(message))
+	}
+	return tool.NewPaginateResult(responses, pagination, total), nil
+}
+
+// GetDeadLetters 分页获取队列的死信，最近进入的在前
+func (s *QueueService) GetDeadLetters(ctx context.Context, queueName string, pagination *tool.PaginationRequest) (*tool.PaginateResult, error) {
+	manager, err := s.manager(queueName)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, total, err := manager.DeadLetters().List(ctx, queueName, pagination.GetOffset(), pagination.GetPageSize())
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]*MessageResponse, 0, len(messages))
+	for _, message := range messages {
+		responses = append(responses, toMessageResponse(message))
+	}
+	return tool.NewPaginateResult(responses, pagination, total), nil
+}
+
+// GetDeadLetter 获取单条死信
+func (s *QueueService) GetDeadLetter(ctx context.Context, queueName, id string) (*MessageResponse, error) {
+	manager, err := s.manager(queueName)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := manager.DeadLetters().Get(ctx, queueName, id)
+	if err != nil {
+		return nil, err
+	}
+	return toMessageResponse(message), nil
+}
+
+// ReplayDeadLetter 重放单条死信
+func (s *QueueService) ReplayDeadLetter(ctx context.Context, queueName, id string) error {
+	manager, err := s.manager(queueName)
+	if err != nil {
+		return err
+	}
+	return manager.ReplayDeadLetter(ctx, queueName, id)
+}
+
+// ReplayDeadLetters 重放队列的所有可重放死信，返回重放数量
+func (s *QueueService) ReplayDeadLetters(ctx context.Context, queueName string) (int, error) {
+	manager, err := s.manager(queueName)
+	if err != nil {
+		return 0, err
+	}
+	return manager.ReplayDeadLetters(ctx, queueName)
+}
+
+// DeleteDeadLetter 删除单条死信
+func (s *QueueService) DeleteDeadLetter(ctx context.Context, queueName, id string) error {
+	manager, err := s.manager(queueName)
+	if err != nil {
+		return err
+	}
+
+	removed, err := manager.DeadLetters().Remove(ctx, queueName, id)
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return queue.ErrDeadLetterNotFound
+	}
+	return nil
+}
+
+// PurgeDeadLetters 清空队列的死信，返回清除数量
+func (s *QueueService) PurgeDeadLetters(ctx context.Context, queueName string) (int64, error) {
+	manager, err := s.manager(queueName)
+	if err != nil {
+		return 0, err
+	}
+	return manager.DeadLetters().Purge(ctx, queueName)
+}
+
+// toMessageResponse 转换为消息响应
+func toMessageResponse(message *queue.Message) *MessageResponse {
+	var data interface{} = string(message.Data)
+	if json.Valid(message.Data) {
+		data = json.RawMessage(message.Data)
+	}
+	return &MessageResponse{
+		ID:         message.ID,
+		Type:       message.Type,
+		Data:       data,
+		Metadata:   message.Metadata,
+		Timestamp:  message.Timestamp,
+		RetryCount: message.RetryCount,
+	}
+}
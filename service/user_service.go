@@ -1,9 +1,24 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin-demo/database"
 	"gin-demo/model"
 	"gin-demo/model/tool"
+	"gin-demo/pkg/types"
 	"gin-demo/repository"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+var (
+	// ErrUserConflict 恢复用户时邮箱或手机号已被其他用户使用
+	ErrUserConflict = errors.New("email or phone already in use")
+	// ErrUserAnonymized 用户已被保留策略匿名化，无法恢复
+	ErrUserAnonymized = errors.New("user has been anonymized")
 )
 
 type UserService struct {
@@ -16,7 +31,7 @@ func NewUserService(userRepo *repository.UserRepository) *UserService {
 	}
 }
 
-func (s *UserService) CreateUser(req *model.CreateUserRequest) (*model.UserResponse, error) {
+func (s *UserService) CreateUser(ctx context.Context, req *model.CreateUserRequest) (*model.UserResponse, error) {
 	user := &model.User{
 		Name:  req.Name,
 		Email: req.Email,
@@ -24,7 +39,7 @@ func (s *UserService) CreateUser(req *model.CreateUserRequest) (*model.UserRespo
 		Phone: req.Phone,
 	}
 
-	if err := s.userRepo.Create(user); err != nil {
+	if err := s.userRepo.Create(ctx, user); err != nil {
 		return nil, err
 	}
 
@@ -39,8 +54,8 @@ func (s *UserService) CreateUser(req *model.CreateUserRequest) (*model.UserRespo
 	}, nil
 }
 
-func (s *UserService) GetUser(id uint) (*model.UserResponse, error) {
-	user, err := s.userRepo.GetByID(id)
+func (s *UserService) GetUser(ctx context.Context, id uint) (*model.UserResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -56,26 +71,21 @@ func (s *UserService) GetUser(id uint) (*model.UserResponse, error) {
 	}, nil
 }
 
-func (s *UserService) UpdateUser(id uint, req *model.UpdateUserRequest) (*model.UserResponse, error) {
+func (s *UserService) UpdateUser(ctx context.Context, id uint, req *model.UpdateUserRequest) (*model.UserResponse, error) {
+	// 读后写，从主库读取避免副本延迟导致覆盖最新数据
+	ctx = database.WithPrimary(ctx)
+
 	// 先获取现有用户
-	user, err := s.userRepo.GetByID(id)
+	user, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
 	// 更新字段
-	if req.Name != "" {
-		user.Name = req.Name
-	}
-	if req.Age > 0 {
-		user.Age = req.Age
-	}
-	if req.Phone != "" {
-		user.Phone = req.Phone
-	}
+	applyUserUpdate(user, req)
 
 	// 保存更新
-	if err := s.userRepo.Update(user); err != nil {
+	if err := s.userRepo.Update(ctx, user); err != nil {
 		return nil, err
 	}
 
@@ -90,8 +100,8 @@ func (s *UserService) UpdateUser(id uint, req *model.UpdateUserRequest) (*model.
 	}, nil
 }
 
-func (s *UserService) GetAllUsers() ([]model.UserResponse, error) {
-	users, err := s.userRepo.GetAll()
+func (s *UserService) GetAllUsers(ctx context.Context) ([]model.UserResponse, error) {
+	users, err := s.userRepo.GetAll(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -111,19 +121,88 @@ func (s *UserService) GetAllUsers() ([]model.UserResponse, error) {
 	return responses, nil
 }
 
-func (s *UserService) DeleteUser(id uint) error {
+func (s *UserService) DeleteUser(ctx context.Context, id uint) error {
+	ctx = database.WithPrimary(ctx)
+
 	// 先检查用户是否存在
-	_, err := s.userRepo.GetByID(id)
+	_, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	return s.userRepo.Delete(id)
+	return s.userRepo.Delete(ctx, id)
+}
+
+// GetTrashedUsers 分页获取回收站中的用户
+func (s *UserService) GetTrashedUsers(ctx context.Context, pagination *tool.PaginationRequest) (*tool.PaginateResult, error) {
+	users, total, err := s.userRepo.GetTrashedWithPagination(ctx, pagination)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]model.TrashedUserResponse, 0, len(users))
+	for i := range users {
+		responses = append(responses, model.TrashedUserResponse{
+			UserResponse: *newUserResponse(&users[i]),
+			DeletedAt:    types.JSONTime(users[i].DeletedAt.Time),
+			Anonymized:   users[i].AnonymizedAt != nil,
+		})
+	}
+
+	return tool.NewPaginateResult(map[string]interface{}{
+		"users": responses,
+	}, pagination, total), nil
+}
+
+// RestoreUser 从回收站恢复用户，邮箱或手机号已被其他用户注册时返回 ErrUserConflict
+func (s *UserService) RestoreUser(ctx context.Context, id uint) (*model.UserResponse, error) {
+	var restored *model.User
+	err := s.userRepo.Transaction(ctx, func(txRepo *repository.UserRepository) error {
+		user, err := txRepo.GetTrashedByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if user.AnonymizedAt != nil {
+			return ErrUserAnonymized
+		}
+
+		// 删除期间邮箱或手机号可能已被重新注册（唯一约束兜底并发情况）
+		exists, err := txRepo.EmailExists(ctx, user.Email)
+		if err == nil && !exists {
+			exists, err = txRepo.PhoneExists(ctx, user.Phone)
+		}
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrUserConflict
+		}
+
+		if err := txRepo.Restore(ctx, user); err != nil {
+			return err
+		}
+		restored = user
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newUserResponse(restored), nil
+}
+
+// ForceDeleteUser 永久删除回收站中的用户
+func (s *UserService) ForceDeleteUser(ctx context.Context, id uint) error {
+	ctx = database.WithPrimary(ctx)
+
+	if _, err := s.userRepo.GetTrashedByID(ctx, id); err != nil {
+		return err
+	}
+	return s.userRepo.ForceDelete(ctx, id)
 }
 
 // GetUsersWithPagination 分页获取用户列表
-func (s *UserService) GetUsersWithPagination(pagination *tool.PaginationRequest) (*tool.PaginateResult, error) {
-	users, total, err := s.userRepo.GetAllWithPagination(pagination)
+func (s *UserService) GetUsersWithPagination(ctx context.Context, pagination *tool.PaginationRequest) (*tool.PaginateResult, error) {
+	users, total, err := s.userRepo.GetAllWithPagination(ctx, pagination)
 	if err != nil {
 		return nil, err
 	}
@@ -137,8 +216,8 @@ func (s *UserService) GetUsersWithPagination(pagination *tool.PaginationRequest)
 }
 
 // SearchUsersWithPagination 带搜索的分页查询用户
-func (s *UserService) SearchUsersWithPagination(pagination *tool.PaginationRequest, keyword string) (*tool.PaginateResult, error) {
-	users, total, err := s.userRepo.GetAllWithPaginationAndSearch(pagination, keyword)
+func (s *UserService) SearchUsersWithPagination(ctx context.Context, pagination *tool.PaginationRequest, keyword string) (*tool.PaginateResult, error) {
+	users, total, err := s.userRepo.GetAllWithPaginationAndSearch(ctx, pagination, keyword)
 	if err != nil {
 		return nil, err
 	}
@@ -149,3 +228,206 @@ func (s *UserService) SearchUsersWithPagination(pagination *tool.PaginationReque
 
 	return result, nil
 }
+
+// BatchUsers 批量创建/更新/删除用户，所有操作在同一个事务中执行
+// all_or_nothing 模式下任一操作失败则整体回滚；best_effort 模式下失败的操作回滚到各自的保存点
+func (s *UserService) BatchUsers(ctx context.Context, req *model.BatchUserRequest) (*model.BatchUserResponse, error) {
+	mode := req.GetMode()
+	response := &model.BatchUserResponse{
+		Mode:    mode,
+		Results: make([]model.BatchUserResult, len(req.Operations)),
+	}
+
+	// 先逐项校验，避免无效数据进入事务
+	valid := make([]bool, len(req.Operations))
+	hasInvalid := false
+	for i, op := range req.Operations {
+		response.Results[i] = model.BatchUserResult{Index: i, Op: op.Op, ID: op.ID}
+		if err := validateBatchOperation(&op); err != nil {
+			response.Results[i].Status = model.BatchStatusFailed
+			response.Results[i].Error = err.Error()
+			hasInvalid = true
+			continue
+		}
+		valid[i] = true
+	}
+
+	if hasInvalid && mode == model.BatchModeAllOrNothing {
+		for i := range response.Results {
+			if valid[i] {
+				response.Results[i].Status = model.BatchStatusSkipped
+			}
+		}
+		response.CountResults()
+		return response, nil
+	}
+
+	failedIndex := -1
+	err := s.userRepo.Transaction(ctx, func(txRepo *repository.UserRepository) error {
+		for i, op := range req.Operations {
+			if !valid[i] {
+				continue
+			}
+			result := &response.Results[i]
+
+			if mode == model.BatchModeAllOrNothing {
+				user, err := executeBatchOperation(ctx, txRepo, &op)
+				if err != nil {
+					failedIndex = i
+					result.Status = model.BatchStatusFailed
+					result.Error = err.Error()
+					return err
+				}
+				result.Status = model.BatchStatusSuccess
+				result.User = user
+				continue
+			}
+
+			// best_effort：每个操作使用独立保存点，失败时只回滚该操作
+			savePoint := fmt.Sprintf("batch_item_%d", i)
+			if err := txRepo.SavePoint(ctx, savePoint); err != nil {
+				return err
+			}
+			user, err := executeBatchOperation(ctx, txRepo, &op)
+			if err != nil {
+				if rbErr := txRepo.RollbackTo(ctx, savePoint); rbErr != nil {
+					return rbErr
+				}
+				result.Status = model.BatchStatusFailed
+				result.Error = err.Error()
+				continue
+			}
+			result.Status = model.BatchStatusSuccess
+			result.User = user
+		}
+		return nil
+	})
+
+	if err != nil {
+		if failedIndex < 0 {
+			// 事务本身失败（如提交失败），不属于某个具体操作
+			return nil, err
+		}
+		// all_or_nothing：失败操作之前的已回滚，之后的未执行
+		for i := range response.Results {
+			if !valid[i] || i == failedIndex {
+				continue
+			}
+			if i < failedIndex {
+				response.Results[i].Status = model.BatchStatusRolledBack
+				response.Results[i].User = nil
+			} else {
+				response.Results[i].Status = model.BatchStatusSkipped
+			}
+		}
+		response.CountResults()
+		return response, nil
+	}
+
+	response.Committed = true
+	response.CountResults()
+	return response, nil
+}
+
+// validateBatchOperation 使用现有请求结构体校验单个批量操作
+func validateBatchOperation(op *model.BatchUserOperation) error {
+	switch op.Op {
+	case model.BatchOpCreate:
+		var req model.CreateUserRequest
+		return decodeBatchData(op.Data, &req)
+	case model.BatchOpUpdate:
+		if op.ID == 0 {
+			return errors.New("id is required for update")
+		}
+		var req model.UpdateUserRequest
+		return decodeBatchData(op.Data, &req)
+	case model.BatchOpDelete:
+		if op.ID == 0 {
+			return errors.New("id is required for delete")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported operation: %s", op.Op)
+	}
+}
+
+// decodeBatchData 解析并校验操作数据
+func decodeBatchData(data json.RawMessage, req interface{}) error {
+	if len(data) == 0 {
+		return errors.New("data is required")
+	}
+	if err := json.Unmarshal(data, req); err != nil {
+		return fmt.Errorf("invalid data: %w", err)
+	}
+	return binding.Validator.ValidateStruct(req)
+}
+
+// executeBatchOperation 在事务中执行单个批量操作
+func executeBatchOperation(ctx context.Context, txRepo *repository.UserRepository, op *model.BatchUserOperation) (*model.UserResponse, error) {
+	switch op.Op {
+	case model.BatchOpCreate:
+		var req model.CreateUserRequest
+		if err := decodeBatchData(op.Data, &req); err != nil {
+			return nil, err
+		}
+		user := &model.User{
+			Name:  req.Name,
+			Email: req.Email,
+			Age:   req.Age,
+			Phone: req.Phone,
+		}
+		if err := txRepo.Create(ctx, user); err != nil {
+			return nil, err
+		}
+		return newUserResponse(user), nil
+
+	case model.BatchOpUpdate:
+		var req model.UpdateUserRequest
+		if err := decodeBatchData(op.Data, &req); err != nil {
+			return nil, err
+		}
+		user, err := txRepo.GetByID(ctx, op.ID)
+		if err != nil {
+			return nil, err
+		}
+		applyUserUpdate(user, &req)
+		if err := txRepo.Update(ctx, user); err != nil {
+			return nil, err
+		}
+		return newUserResponse(user), nil
+
+	case model.BatchOpDelete:
+		if _, err := txRepo.GetByID(ctx, op.ID); err != nil {
+			return nil, err
+		}
+		return nil, txRepo.Delete(ctx, op.ID)
+	}
+
+	return nil, fmt.Errorf("unsupported operation: %s", op.Op)
+}
+
+// applyUserUpdate 将更新请求中的非零字段应用到用户
+func applyUserUpdate(user *model.User, req *model.UpdateUserRequest) {
+	if req.Name != "" {
+		user.Name = req.Name
+	}
+	if req.Age > 0 {
+		user.Age = req.Age
+	}
+	if req.Phone != "" {
+		user.Phone = req.Phone
+	}
+}
+
+// newUserResponse 构造用户响应
+func newUserResponse(user *model.User) *model.UserResponse {
+	return &model.UserResponse{
+		ID:        user.ID,
+		Name:      user.Name,
+		Email:     user.Email,
+		Age:       user.Age,
+		Phone:     user.Phone,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+	}
+}
@@ -0,0 +1,178 @@
+package test
+
+import (
+	"gin-demo/config"
+	"gin-demo/controller"
+	"gin-demo/model"
+	"gin-demo/pkg/auth"
+	"gin-demo/router"
+	"gin-demo/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// setupAdminRouter 构建包含用户和管理路由的测试路由，签发令牌使用测试JWT配置
+func setupAdminRouter(t *testing.T) *gin.Engine {
+	previous := config.Cfg
+	config.Cfg = &config.Config{JWT: &config.JWTConfig{Secret: "test-secret", ExpiresHours: 1, Issuer: "test"}}
+	t.Cleanup(func() { config.Cfg = previous })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api := r.Group("/api")
+	userController := controller.NewUserController(service.NewUserService(nil))
+	router.SetupUserRoutes(api, userController)
+	router.SetupAdminRoutes(api, userController, controller.NewQueueController(service.NewQueueService()))
+	router.SetupEmailRoutes(api, controller.NewEmailController(nil, service.NewQueueService()))
+	return r
+}
+
+// createAdmin 创建令牌中用户ID对应的管理员，RequireAdmin 以数据库中的角色为准
+func createAdmin(t *testing.T, db *gorm.DB) *model.User {
+	admin := &model.User{ID: 1, Name: "Admin", Email: "user@example.com", Password: "x", Role: model.RoleAdmin}
+	require.NoError(t, db.Create(admin).Error)
+	return admin
+}
+
+// bearerToken 签发指定角色的访问令牌
+func bearerToken(t *testing.T, role string) string {
+	token, _, err := auth.GenerateToken(1, "user@example.com", role)
+	require.NoError(t, err)
+	return "Bearer " + token
+}
+
+// routePath 将路由参数替换为示例值
+func routePath(path string) string {
+	path = strings.ReplaceAll(path, ":queue", "email")
+	return strings.ReplaceAll(path, ":id", "1")
+}
+
+// assertAdminOnly 普通用户和角色上线前签发的令牌访问路由返回403，未登录返回401
+func assertAdminOnly(t *testing.T, r *gin.Engine, routes []gin.RouteInfo) {
+	userToken := bearerToken(t, model.RoleUser)
+	legacyToken := bearerToken(t, "")
+
+	require.NotEmpty(t, routes)
+	for _, route := range routes {
+		path := routePath(route.Path)
+
+		for _, token := range []string{"", userToken, legacyToken} {
+			req := httptest.NewRequest(route.Method, path, strings.NewReader("{}"))
+			req.Header.Set("Content-Type", "application/json")
+			if token != "" {
+				req.Header.Set("Authorization", token)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			expected := http.StatusForbidden
+			if token == "" {
+				expected = http.StatusUnauthorized
+			}
+			assert.Equal(t, expected, w.Code, "%s %s", route.Method, path)
+		}
+	}
+}
+
+// adminRoutes 筛选路径前缀匹配、且不在排除前缀下的路由
+func adminRoutes(r *gin.Engine, prefix string, excludes ...string) []gin.RouteInfo {
+	var routes []gin.RouteInfo
+	for _, route := range r.Routes() {
+		if !strings.HasPrefix(route.Path, prefix) {
+			continue
+		}
+		excluded := false
+		for _, exclude := range excludes {
+			excluded = excluded || strings.HasPrefix(route.Path, exclude)
+		}
+		if !excluded {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+func TestQueueAdminRoutesRequireAdminRole(t *testing.T) {
+	r := setupAdminRouter(t)
+	routes := adminRoutes(r, "/api/admin/queues", "/api/admin/queues/:queue/dead_letters")
+	assert.Len(t, routes, 6)
+	assertAdminOnly(t, r, routes)
+}
+
+func TestDeadLetterRoutesRequireAdminRole(t *testing.T) {
+	r := setupAdminRouter(t)
+	routes := adminRoutes(r, "/api/admin/queues/:queue/dead_letters")
+	assert.Len(t, routes, 6)
+	assertAdminOnly(t, r, routes)
+}
+
+func TestUserTrashRoutesRequireAdminRole(t *testing.T) {
+	r := setupAdminRouter(t)
+	routes := adminRoutes(r, "/api/admin/users/trash")
+	assert.Len(t, routes, 3)
+	assertAdminOnly(t, r, routes)
+
+	// 分组级别的权限校验覆盖所有管理路由
+	assertAdminOnly(t, r, adminRoutes(r, "/api/admin/"))
+}
+
+func TestUserBatchRouteRequiresAdminRole(t *testing.T) {
+	r := setupAdminRouter(t)
+	routes := adminRoutes(r, "/api/users/batch")
+	assert.Len(t, routes, 1)
+	assertAdminOnly(t, r, routes)
+}
+
+func TestEmailQueueStatusRequiresAdminRole(t *testing.T) {
+	r := setupAdminRouter(t)
+	routes := adminRoutes(r, "/api/email_queue/status")
+	assert.Len(t, routes, 1)
+	assertAdminOnly(t, r, routes)
+}
+
+func TestAdminRoutesAllowAdminRole(t *testing.T) {
+	db := setupSQLiteDB(t)
+	createAdmin(t, db)
+	r := setupAdminRouter(t)
+
+	for _, path := range []string{"/api/admin/queues", "/api/email_queue/status"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", bearerToken(t, model.RoleAdmin))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.NotEqual(t, http.StatusUnauthorized, w.Code, path)
+		assert.NotEqual(t, http.StatusForbidden, w.Code, path)
+	}
+}
+
+func TestRequireAdminRejectsRevokedAdminToken(t *testing.T) {
+	db := setupSQLiteDB(t)
+	admin := createAdmin(t, db)
+	r := setupAdminRouter(t)
+	token := bearerToken(t, model.RoleAdmin)
+
+	request := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/queues", nil)
+		req.Header.Set("Authorization", token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 令牌仍声明管理员角色，但数据库中已降级
+	require.NoError(t, db.Model(admin).Update("role", model.RoleUser).Error)
+	assert.Equal(t, http.StatusForbidden, request())
+
+	// 用户已删除
+	require.NoError(t, db.Model(admin).Update("role", model.RoleAdmin).Error)
+	assert.NotEqual(t, http.StatusForbidden, request())
+	require.NoError(t, db.Delete(admin).Error)
+	assert.Equal(t, http.StatusForbidden, request())
+}
@@ -0,0 +1,39 @@
+package test
+
+import (
+	"context"
+	"gin-demo/config"
+	"gin-demo/model"
+	"gin-demo/pkg/encryption"
+	"gin-demo/pkg/transaction"
+	"gin-demo/repository"
+	"gin-demo/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterMapsDuplicateBlindIndexToConflict(t *testing.T) {
+	previous := config.Cfg
+	config.Cfg = &config.Config{JWT: &config.JWTConfig{Secret: "test-secret", ExpiresHours: 1, Issuer: "test"}}
+	t.Cleanup(func() { config.Cfg = previous })
+
+	db := setupSQLiteDB(t)
+	repo := repository.NewUserRepository(db)
+	authService := service.NewAuthService(repo, transaction.NewManager(db))
+	ctx := context.Background()
+
+	_, err := authService.Register(ctx, &model.RegisterRequest{Name: "Alice", Email: "alice@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	// 模拟并发注册：另一请求已写入相同盲索引，但邮箱检查时尚不可见，由唯一约束兜底
+	require.NoError(t, db.Exec("INSERT INTO users (name, email, password, role, email_bidx, deleted_at) VALUES (?, ?, ?, ?, ?, ?)",
+		"Racer", "bob@example.com", "x", model.RoleUser, *encryption.BlindIndex("bob@example.com"), time.Now()).Error)
+	_, err = authService.Register(ctx, &model.RegisterRequest{Name: "Bob", Email: "bob@example.com", Password: "password123"})
+	assert.EqualError(t, err, "email already exists")
+
+	_, err = authService.Register(ctx, &model.RegisterRequest{Name: "Alice2", Email: "alice@example.com", Password: "password123"})
+	assert.EqualError(t, err, "email already exists")
+}
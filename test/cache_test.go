@@ -0,0 +1,237 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gin-demo/config"
+	"gin-demo/database"
+	"gin-demo/model"
+	"gin-demo/pkg/cache"
+	"gin-demo/pkg/encryption"
+	"gin-demo/repository"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// setupCache 使用内存Redis启用全局缓存
+func setupCache(t *testing.T, db *gorm.DB, version string) redis.Cmdable {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	c := cache.New(&config.CacheConfig{Enabled: true, Version: version}, "", rdb)
+	cache.SetCache(c)
+	t.Cleanup(func() {
+		cache.SetCache(nil)
+		rdb.Close()
+	})
+
+	require.NoError(t, cache.RegisterCallbacks(db))
+	return rdb
+}
+
+func TestCacheGetByIDAndInvalidation(t *testing.T) {
+	db := setupSQLiteDB(t)
+	setupCache(t, db, "v1")
+	repo := repository.NewUserRepository(db)
+	ctx := context.Background()
+
+	user := &model.User{Name: "Alice", Email: "alice@example.com", Password: "hashed", Phone: "13800000001"}
+	require.NoError(t, repo.Create(ctx, user))
+
+	cached, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, cached.Password, "password hash must not be cached")
+	assert.Equal(t, "alice@example.com", cached.Email)
+
+	// 绕过GORM回调直接修改数据库，缓存仍返回旧值
+	require.NoError(t, db.Exec("UPDATE users SET name = ? WHERE id = ?", "Raw", user.ID).Error)
+	cached, err = repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", cached.Name)
+
+	// 通过仓储更新，回调按主键失效缓存
+	cached.Name = "Alice2"
+	require.NoError(t, repo.Update(ctx, cached))
+	cached, err = repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice2", cached.Name)
+	assert.Equal(t, user.CreatedAt.ToTime().Unix(), cached.CreatedAt.ToTime().Unix())
+
+	// 删除后缓存失效
+	require.NoError(t, repo.Delete(ctx, user.ID))
+	_, err = repo.GetByID(ctx, user.ID)
+	assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+}
+
+func TestCacheDoesNotStoreSecrets(t *testing.T) {
+	db := setupSQLiteDB(t)
+	rdb := setupCache(t, db, "v1")
+	repo := repository.NewUserRepository(db)
+	ctx := context.Background()
+
+	// 未启用字段加密时数据库同样是明文，缓存只去除密码哈希
+	user := &model.User{Name: "Alice", Email: "alice@example.com", Password: "$2a$10$secrethash", Phone: "13800000001"}
+	require.NoError(t, repo.Create(ctx, user))
+	_, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	raw, err := rdb.Get(ctx, fmt.Sprintf("cache:v1:users:v0:%d", user.ID)).Result()
+	require.NoError(t, err)
+	assert.NotContains(t, raw, "$2a$10$secrethash")
+
+	// 启用字段加密后整个缓存值加密，明文缓存值视为未命中并被覆盖
+	useKeyring(t, newTestKeyring(t, "k1", map[string]string{"k1": randomKey()}))
+	cached, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", cached.Email)
+	assert.Equal(t, "13800000001", cached.Phone)
+	assert.Empty(t, cached.Password)
+
+	raw, err = rdb.Get(ctx, fmt.Sprintf("cache:v1:users:v0:%d", user.ID)).Result()
+	require.NoError(t, err)
+	assert.True(t, encryption.IsEncrypted(raw))
+	for _, secret := range []string{"alice@example.com", "13800000001", "$2a$10$secrethash"} {
+		assert.NotContains(t, raw, secret)
+	}
+
+	// 缓存命中时解密
+	require.NoError(t, db.Exec("UPDATE users SET name = ? WHERE id = ?", "Raw", user.ID).Error)
+	cached, err = repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", cached.Name)
+	assert.Equal(t, "alice@example.com", cached.Email)
+}
+
+func TestCacheNegativeCaching(t *testing.T) {
+	db := setupSQLiteDB(t)
+	setupCache(t, db, "v1")
+	repo := repository.NewUserRepository(db)
+	ctx := context.Background()
+
+	_, err := repo.GetByID(ctx, 100)
+	require.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+
+	// 绕过回调插入，负缓存仍生效
+	require.NoError(t, db.Exec("INSERT INTO users (id, name, email, password, phone) VALUES (100, 'Raw', 'raw@example.com', 'x', '13800000100')").Error)
+	_, err = repo.GetByID(ctx, 100)
+	assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+
+	// 按条件批量更新无法确定主键时整表失效
+	_, err = repository.NewRepository[model.User](db).UpdateWhere(ctx, map[string]interface{}{"age": 20}, repository.Where("name = ?", "Raw"))
+	require.NoError(t, err)
+	user, err := repo.GetByID(ctx, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 20, user.Age)
+}
+
+func TestCacheVersionNamespace(t *testing.T) {
+	db := setupSQLiteDB(t)
+	rdb := setupCache(t, db, "v1")
+	repo := repository.NewUserRepository(db)
+	ctx := context.Background()
+
+	user := &model.User{Name: "Alice", Email: "alice@example.com", Password: "x", Phone: "13800000001"}
+	require.NoError(t, repo.Create(ctx, user))
+	_, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.NoError(t, db.Exec("UPDATE users SET name = ? WHERE id = ?", "Deployed", user.ID).Error)
+
+	stale, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", stale.Name)
+
+	// 部署新版本（新命名空间）后读取到最新数据
+	cache.SetCache(cache.New(&config.CacheConfig{Enabled: true, Version: "v2"}, "", rdb))
+	fresh, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Deployed", fresh.Name)
+}
+
+func TestCacheSingleflight(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	c := cache.New(&config.CacheConfig{Enabled: true}, "test", rdb)
+
+	var loads int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (*model.User, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return &model.User{ID: 1, Name: "Alice"}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user, err := cache.Fetch(context.Background(), c, "users", 1, loader)
+			assert.NoError(t, err)
+			assert.Equal(t, "Alice", user.Name)
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+}
+
+func TestCacheLoaderUsesPrimaryAndIgnoresCallerCancel(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	c := cache.New(&config.CacheConfig{Enabled: true}, "test", rdb)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (*model.User, error) {
+		// 回填读主库，加载不随首个调用方取消
+		assert.True(t, database.UsePrimary(ctx))
+		close(started)
+		<-release
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return &model.User{ID: 1, Name: "Alice"}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := cache.Fetch(ctx, c, "users", 1, loader)
+		first <- err
+	}()
+	<-started
+
+	second := make(chan *model.User, 1)
+	go func() {
+		user, err := cache.Fetch(context.Background(), c, "users", 1, loader)
+		assert.NoError(t, err)
+		second <- user
+	}()
+
+	// 首个调用方取消后立即返回，等待同一键的调用方仍拿到结果并回填缓存
+	cancel()
+	assert.ErrorIs(t, <-first, context.Canceled)
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	user := <-second
+	require.NotNil(t, user)
+	assert.Equal(t, "Alice", user.Name)
+
+	cached, err := cache.Fetch(context.Background(), c, "users", 1, func(ctx context.Context) (*model.User, error) {
+		return nil, errors.New("should be served from cache")
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", cached.Name)
+}
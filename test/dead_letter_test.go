@@ -0,0 +1,153 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"gin-demo/config"
+	"gin-demo/pkg/queue"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyHandler 可切换失败/panic的测试处理器
+type flakyHandler struct {
+	mode    atomic.Value // "fail" | "panic" | "ok"
+	handled atomic.Int32
+}
+
+func (h *flakyHandler) Handle(ctx context.Context, message *queue.Message) error {
+	switch h.mode.Load() {
+	case "fail":
+		return errors.New("smtp unavailable")
+	case "panic":
+		panic("nil template")
+	}
+	h.handled.Add(1)
+	return nil
+}
+
+func (h *flakyHandler) GetQueueName() string  { return "flaky" }
+func (h *flakyHandler) GetNumConsumers() int  { return 1 }
+func (h *flakyHandler) GetPrefetchLimit() int { return 10 }
+
+// testQueueConfig 测试队列配置：单个 flaky 队列，缩短轮询和重试间隔
+func testQueueConfig(retryLimit int) *config.QueueConfig {
+	return &config.QueueConfig{
+		RMQ: config.RMQConfig{
+			Tag:          "test",
+			PollDuration: 10 * time.Millisecond,
+			RetryLimit:   retryLimit,
+			RetryDelay:   10 * time.Millisecond,
+
+			DelayPollInterval: 10 * time.Millisecond,
+		},
+		Queues: map[string]config.QueueItemConfig{
+			"flaky": {Name: "flaky_queue", NumConsumers: 1, PrefetchLimit: 10},
+		},
+	}
+}
+
+func TestDeadLetterAfterRetriesAndReplay(t *testing.T) {
+	forEachQueueDriver(t, testDeadLetterAfterRetriesAndReplay)
+}
+
+func testDeadLetterAfterRetriesAndReplay(t *testing.T, driver string) {
+	m, _ := setupQueueManager(t, driver, 1)
+	ctx := context.Background()
+	dlq := m.DeadLetters()
+
+	handler := &flakyHandler{}
+	handler.mode.Store("fail")
+	require.NoError(t, m.RegisterHandler(handler))
+
+	message, err := queue.NewMessage("email.send", map[string]string{"to": "a@example.com"})
+	require.NoError(t, err)
+	require.NoError(t, m.Publish("flaky", message))
+
+	// 首次失败重试一次，重试耗尽后进入死信队列
+	require.Eventually(t, func() bool {
+		count, _ := dlq.Count(ctx, "flaky")
+		return count == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	dead, err := dlq.Get(ctx, "flaky", message.ID)
+	require.NoError(t, err)
+	attempts := dead.Attempts()
+	require.Len(t, attempts, 2)
+	assert.Equal(t, "smtp unavailable", attempts[1].Error)
+	info, ok := dead.DeadLetterInfo()
+	require.True(t, ok)
+	assert.Equal(t, queue.DeadLetterRetriesExhausted, info.Reason)
+
+	// 重放后处理成功，死信被移除
+	handler.mode.Store("ok")
+	require.NoError(t, m.ReplayDeadLetter(ctx, "flaky", message.ID))
+	require.Eventually(t, func() bool { return handler.handled.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+	count, err := dlq.Count(ctx, "flaky")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	_, err = dlq.Get(ctx, "flaky", message.ID)
+	assert.ErrorIs(t, err, queue.ErrDeadLetterNotFound)
+}
+
+func TestDeadLetterPanicAndInvalidPayload(t *testing.T) {
+	forEachQueueDriver(t, testDeadLetterPanicAndInvalidPayload)
+}
+
+func testDeadLetterPanicAndInvalidPayload(t *testing.T, driver string) {
+	m, broker := setupQueueManager(t, driver, 0)
+	ctx := context.Background()
+	dlq := m.DeadLetters()
+
+	handler := &flakyHandler{}
+	handler.mode.Store("panic")
+	require.NoError(t, m.RegisterHandler(handler))
+
+	// 处理器panic时消息不再被确认丢弃，记录调用栈后进入死信队列
+	for i := 0; i < 3; i++ {
+		message, err := queue.NewMessage("email.send", i)
+		require.NoError(t, err)
+		require.NoError(t, m.Publish("flaky", message))
+	}
+
+	// 无法解析的消息直接进入死信队列
+	require.NoError(t, broker.Publish(ctx, "flaky_queue", []byte("not json")))
+
+	require.Eventually(t, func() bool {
+		count, _ := dlq.Count(ctx, "flaky")
+		return count == 4
+	}, 5*time.Second, 10*time.Millisecond)
+
+	messages, total, err := dlq.List(ctx, "flaky", 0, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, total)
+	var invalid *queue.Message
+	for _, message := range messages {
+		info, ok := message.DeadLetterInfo()
+		require.True(t, ok)
+		if info.Reason == queue.DeadLetterInvalidPayload {
+			invalid = message
+			continue
+		}
+		assert.Contains(t, info.Error, "nil template")
+		assert.NotEmpty(t, info.Stack)
+	}
+	require.NotNil(t, invalid)
+	assert.Equal(t, "not json", string(invalid.Data))
+
+	// 重放全部时跳过无法解析的消息
+	handler.mode.Store("ok")
+	assert.ErrorIs(t, m.ReplayDeadLetter(ctx, "flaky", invalid.ID), queue.ErrNotReplayable)
+	replayed, err := m.ReplayDeadLetters(ctx, "flaky")
+	require.NoError(t, err)
+	assert.Equal(t, 3, replayed)
+	require.Eventually(t, func() bool { return handler.handled.Load() == 3 }, 5*time.Second, 10*time.Millisecond)
+
+	purged, err := dlq.Purge(ctx, "flaky")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, purged)
+}
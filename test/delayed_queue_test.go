@@ -0,0 +1,103 @@
+package test
+
+import (
+	"context"
+	"gin-demo/pkg/queue"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishDelayedSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	cfg := testQueueConfig(0)
+	broker, err := queue.NewRMQBroker(rdb, false, cfg.RMQ)
+	require.NoError(t, err)
+	m := queue.NewManagerWithBroker(cfg, broker)
+
+	message, err := queue.NewMessage("reminder", "later")
+	require.NoError(t, err)
+	require.NoError(t, m.PublishDelayed("flaky", message, 300*time.Millisecond))
+
+	// 管理器在到期前关闭，延迟消息仍保存在Redis中
+	count, err := m.DelayedCount(ctx, "flaky")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+	require.NoError(t, m.Close())
+
+	broker, err = queue.NewRMQBroker(rdb, false, cfg.RMQ)
+	require.NoError(t, err)
+	restarted := queue.NewManagerWithBroker(cfg, broker)
+	t.Cleanup(func() { restarted.Close() })
+
+	handler := &flakyHandler{}
+	handler.mode.Store("ok")
+	require.NoError(t, restarted.RegisterHandler(handler))
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Zero(t, handler.handled.Load(), "message must not be delivered before it is due")
+
+	require.Eventually(t, func() bool { return handler.handled.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+	count, err = restarted.DelayedCount(ctx, "flaky")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestPublishAtPastTimeDeliversImmediately(t *testing.T) {
+	forEachQueueDriver(t, testPublishAtPastTimeDeliversImmediately)
+}
+
+func testPublishAtPastTimeDeliversImmediately(t *testing.T, driver string) {
+	m, _ := setupQueueManager(t, driver, 0)
+
+	handler := &flakyHandler{}
+	handler.mode.Store("ok")
+	require.NoError(t, m.RegisterHandler(handler))
+
+	message, err := queue.NewMessage("reminder", "now")
+	require.NoError(t, err)
+	require.NoError(t, m.PublishAt("flaky", message, time.Now().Add(-time.Minute)))
+	require.Eventually(t, func() bool { return handler.handled.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	assert.Error(t, m.PublishDelayed("missing", message, time.Minute))
+}
+
+func TestRMQDelayedMessagesScannedAcrossInstances(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	cfg := testQueueConfig(0).RMQ
+
+	// 发布实例写入两条内容相同的延迟消息后退出
+	publisher, err := queue.NewRMQBroker(rdb, false, cfg)
+	require.NoError(t, err)
+	at := time.Now().Add(100 * time.Millisecond)
+	require.NoError(t, publisher.PublishAt(ctx, "reports", []byte("same"), at))
+	require.NoError(t, publisher.PublishAt(ctx, "reports", []byte("same"), at))
+	stats, err := publisher.Stats(ctx, "reports")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, stats.Delayed)
+	require.NoError(t, publisher.Close())
+
+	// 其他实例未打开该队列，调度协程仍会转移到期消息
+	scheduler, err := queue.NewRMQBroker(rdb, false, cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { scheduler.Close() })
+
+	require.Eventually(t, func() bool {
+		ready, err := mr.List("rmq::queue::[reports]::ready")
+		return err == nil && len(ready) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	ready, err := mr.List("rmq::queue::[reports]::ready")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"same", "same"}, ready)
+}
@@ -1,11 +1,15 @@
 package test
 
 import (
+	"context"
+	"gin-demo/config"
+	"gin-demo/pkg/outbox"
 	"gin-demo/pkg/queue"
 	"gin-demo/service"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 // 测试邮件处理器
@@ -30,18 +34,43 @@ func TestEmailHandler(t *testing.T) {
 	require.NoError(t, err)
 
 	// 处理消息（这里会调用模拟的发送逻辑）
-	err = handler.Handle(message)
+	err = handler.Handle(context.Background(), message)
 	assert.NoError(t, err)
 }
 
+// TestEmailQueue 邮件经发件箱投递到队列并被消费（内存队列和SQLite，无需外部服务）
 func TestEmailQueue(t *testing.T) {
-	// 设置测试环境并获取清理函数
-	cleanup := SetupTest(t)
-	defer cleanup() // 确保测试结束后清理资源
+	db := setupSQLiteDB(t)
+	ctx := context.Background()
+
+	cfg := &config.QueueConfig{
+		Driver: config.QueueDriverMemory,
+		Queues: map[string]config.QueueItemConfig{
+			queue.Email: {Name: "email_queue", NumConsumers: 1, PrefetchLimit: 10},
+		},
+	}
+	manager, err := queue.NewManager(cfg)
+	require.NoError(t, err)
+	defer manager.Close()
+	require.NoError(t, queue.RegisterQueueHandlers(manager, cfg))
 
 	emailService := service.NewEmailService()
 	to := []string{"valid@example.com"}
 
-	err := emailService.SendEmail(to, "有效邮件", "这是有效的邮件内容", false)
+	err = emailService.SendEmail(ctx, to, "有效邮件", "这是有效的邮件内容", false)
+	require.NoError(t, err)
+
+	// 发件箱投递到队列后由邮件处理器消费并确认
+	relay := outbox.NewRelay(db, manager, nil, config.OutboxConfig{})
+	sent, err := relay.ProcessBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	require.Eventually(t, func() bool {
+		stats, err := manager.GetQueueStats(ctx, queue.Email)
+		return err == nil && stats.Ready == 0 && stats.Unacked == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	dead, err := manager.DeadLetters().Count(ctx, queue.Email)
 	require.NoError(t, err)
+	assert.Zero(t, dead)
 }
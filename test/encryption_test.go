@@ -0,0 +1,204 @@
+package test
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"gin-demo/config"
+	"gin-demo/model"
+	"gin-demo/model/tool"
+	"gin-demo/pkg/encryption"
+	"gin-demo/repository"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+var testBlindIndexKey = randomKey()
+
+func randomKey() string {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// newTestKeyring 创建密钥环，keys 中的密钥ID对应的密钥在整个测试进程中固定
+func newTestKeyring(t *testing.T, active string, keys map[string]string) *encryption.Keyring {
+	keyring, err := encryption.NewKeyring(&config.EncryptionConfig{
+		Enabled:       true,
+		ActiveKey:     active,
+		Keys:          keys,
+		BlindIndexKey: testBlindIndexKey,
+	})
+	require.NoError(t, err)
+	return keyring
+}
+
+// useKeyring 设置全局密钥环，测试结束后恢复
+func useKeyring(t *testing.T, keyring *encryption.Keyring) {
+	previous := encryption.GetKeyring()
+	encryption.SetKeyring(keyring)
+	t.Cleanup(func() { encryption.SetKeyring(previous) })
+}
+
+// rawUserColumn 绕过序列化器读取数据库中的原始值
+func rawUserColumn(t *testing.T, db *gorm.DB, id uint, column string) string {
+	var value string
+	require.NoError(t, db.Raw("SELECT "+column+" FROM users WHERE id = ?", id).Scan(&value).Error)
+	return value
+}
+
+func TestKeyringRotationDecryptsOldCiphertext(t *testing.T) {
+	keys := map[string]string{"k1": randomKey()}
+	old := newTestKeyring(t, "k1", keys)
+
+	ciphertext, err := old.Encrypt("alice@example.com")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(ciphertext, "enc:v1:k1:"))
+
+	// 同一明文每次加密结果不同
+	again, err := old.Encrypt("alice@example.com")
+	require.NoError(t, err)
+	assert.NotEqual(t, ciphertext, again)
+
+	// 轮换后仍能解密旧密文，新数据使用新密钥
+	rotated := newTestKeyring(t, "k2", map[string]string{"k1": keys["k1"], "k2": randomKey()})
+	plaintext, keyID, err := rotated.Decrypt(ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", plaintext)
+	assert.Equal(t, "k1", keyID)
+
+	fresh, err := rotated.Encrypt("alice@example.com")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(fresh, "enc:v1:k2:"))
+
+	// 旧密钥移除后无法解密
+	_, _, err = newTestKeyring(t, "k2", map[string]string{"k2": randomKey()}).Decrypt(ciphertext)
+	assert.ErrorIs(t, err, encryption.ErrUnknownKey)
+
+	// 篡改的密文解密失败
+	_, _, err = old.Decrypt(ciphertext[:len(ciphertext)-4] + "AAAA")
+	assert.Error(t, err)
+
+	// 明文原样返回，盲索引大小写不敏感
+	plaintext, keyID, err = old.Decrypt("legacy@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "legacy@example.com", plaintext)
+	assert.Empty(t, keyID)
+	assert.Equal(t, old.BlindIndex("Alice@Example.com "), rotated.BlindIndex("alice@example.com"))
+}
+
+func TestEncryptionInitRequiresKeys(t *testing.T) {
+	useKeyring(t, encryption.GetKeyring())
+	cfg := &config.Config{Encryption: &config.EncryptionConfig{Enabled: true, ActiveKey: "k1"}}
+
+	// 启用加密但未配置密钥时启动失败，不会以明文或公开密钥写入数据
+	assert.ErrorContains(t, encryption.Init(cfg), "no encryption keys configured")
+	t.Setenv(encryption.EnvKeys, "k1="+randomKey())
+	assert.ErrorContains(t, encryption.Init(cfg), "no blind index key configured")
+
+	// 密钥从环境变量读取
+	t.Setenv(encryption.EnvActiveKey, "k2")
+	t.Setenv(encryption.EnvKeys, "k1="+randomKey()+", k2="+randomKey())
+	t.Setenv(encryption.EnvBlindIndexKey, testBlindIndexKey)
+	require.NoError(t, encryption.Init(cfg))
+	assert.Equal(t, "k2", encryption.GetKeyring().ActiveKey())
+	assert.Empty(t, cfg.Encryption.Keys)
+
+	t.Setenv(encryption.EnvKeys, "k2")
+	assert.Error(t, encryption.Init(cfg))
+}
+
+func TestEncryptedUserLookupByBlindIndex(t *testing.T) {
+	db := setupSQLiteDB(t)
+	useKeyring(t, newTestKeyring(t, "k1", map[string]string{"k1": randomKey()}))
+	repo := repository.NewUserRepository(db)
+	ctx := context.Background()
+
+	user := &model.User{Name: "Alice", Email: "alice@example.com", Password: "x", Phone: "13800000001"}
+	require.NoError(t, repo.Create(ctx, user))
+
+	// 数据库中为密文，读取后透明解密
+	assert.True(t, encryption.IsEncrypted(rawUserColumn(t, db, user.ID, "email")))
+	assert.True(t, encryption.IsEncrypted(rawUserColumn(t, db, user.ID, "phone")))
+
+	found, err := repo.GetByEmail(ctx, "Alice@Example.com")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, found.ID)
+	assert.Equal(t, "alice@example.com", found.Email)
+	assert.Equal(t, "13800000001", found.Phone)
+
+	exists, err := repo.EmailExists(ctx, "alice@example.com")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = repo.EmailExists(ctx, "bob@example.com")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// 盲索引保证唯一
+	duplicate := &model.User{Name: "Alice2", Email: "ALICE@example.com", Password: "x", Phone: "13800000002"}
+	assert.Error(t, repo.Create(ctx, duplicate))
+
+	// 搜索支持完整邮箱精确匹配
+	_, total, err := repo.GetAllWithPaginationAndSearch(ctx, &tool.PaginationRequest{}, "alice@example.com")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+}
+
+func TestReencryptRotatesKeysAndEncryptsLegacyRows(t *testing.T) {
+	db := setupSQLiteDB(t)
+	ctx := context.Background()
+	repo := repository.NewUserRepository(db)
+
+	// 加密启用前写入的明文记录
+	useKeyring(t, nil)
+	legacy := &model.User{Name: "Legacy", Email: "legacy@example.com", Password: "x", Phone: "13800000001"}
+	require.NoError(t, repo.Create(ctx, legacy))
+
+	// 启用加密后明文记录仍可按邮箱查询
+	k1 := randomKey()
+	encryption.SetKeyring(newTestKeyring(t, "k1", map[string]string{"k1": k1}))
+	found, err := repo.GetByEmail(ctx, "legacy@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, legacy.ID, found.ID)
+
+	current := &model.User{Name: "Current", Email: "current@example.com", Password: "x", Phone: "13800000002"}
+	require.NoError(t, repo.Create(ctx, current))
+	deleted := &model.User{Name: "Deleted", Email: "deleted@example.com", Password: "x", Phone: "13800000003"}
+	require.NoError(t, repo.Create(ctx, deleted))
+	require.NoError(t, repo.Delete(ctx, deleted.ID))
+
+	var before model.User
+	require.NoError(t, db.First(&before, current.ID).Error)
+
+	// 轮换到k2并重新加密
+	encryption.SetKeyring(newTestKeyring(t, "k2", map[string]string{"k1": k1, "k2": randomKey()}))
+	time.Sleep(10 * time.Millisecond)
+	results, err := tool.Registry.Reencrypt(ctx, 2)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "users", results[0].TableName)
+	assert.ElementsMatch(t, []string{"email", "phone"}, results[0].Fields)
+	assert.EqualValues(t, 3, results[0].Rows)
+
+	for _, id := range []uint{legacy.ID, current.ID, deleted.ID} {
+		assert.Contains(t, rawUserColumn(t, db, id, "email"), "enc:v1:k2:")
+		assert.Contains(t, rawUserColumn(t, db, id, "phone"), "enc:v1:k2:")
+	}
+
+	// 盲索引已重建，更新时间不变
+	found, err = repo.GetByEmail(ctx, "legacy@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "13800000001", found.Phone)
+	assert.Equal(t, encryption.BlindIndex("legacy@example.com"), found.EmailBidx)
+
+	var after model.User
+	require.NoError(t, db.First(&after, current.ID).Error)
+	assert.Equal(t, time.Time(before.UpdatedAt), time.Time(after.UpdatedAt))
+}
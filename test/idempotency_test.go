@@ -0,0 +1,128 @@
+package test
+
+import (
+	"gin-demo/database"
+	"gin-demo/pkg/middleware"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupIdempotencyRouter 使用内存Redis构建测试路由
+func setupIdempotencyRouter(t *testing.T, handler gin.HandlerFunc) *gin.Engine {
+	mr := miniredis.RunT(t)
+	previous := database.RDB
+	database.RDB = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		database.RDB.Close()
+		database.RDB = previous
+	})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Next()
+	})
+	r.Use(middleware.IdempotencyMiddleware())
+	r.POST("/users", handler)
+	return r
+}
+
+func doIdempotentRequest(r *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.IdempotencyKeyHeader, key)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	var calls int32
+	r := setupIdempotencyRouter(t, func(c *gin.Context) {
+		n := atomic.AddInt32(&calls, 1)
+		c.Header("X-Call", "first")
+		c.JSON(http.StatusCreated, gin.H{"call": n})
+	})
+
+	first := doIdempotentRequest(r, "key-1", `{"name":"test"}`)
+	require.Equal(t, http.StatusCreated, first.Code)
+
+	second := doIdempotentRequest(r, "key-1", `{"name":"test"}`)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "first", second.Header().Get("X-Call"))
+	assert.Equal(t, "true", second.Header().Get(middleware.IdempotencyReplayedHeader))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestIdempotencyPayloadMismatch(t *testing.T) {
+	r := setupIdempotencyRouter(t, func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{})
+	})
+
+	require.Equal(t, http.StatusCreated, doIdempotentRequest(r, "key-2", `{"name":"a"}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, doIdempotentRequest(r, "key-2", `{"name":"b"}`).Code)
+}
+
+func TestIdempotencyConcurrentDuplicate(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	r := setupIdempotencyRouter(t, func(c *gin.Context) {
+		close(started)
+		<-release
+		c.JSON(http.StatusCreated, gin.H{})
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- doIdempotentRequest(r, "key-3", `{}`)
+	}()
+
+	<-started
+	assert.Equal(t, http.StatusConflict, doIdempotentRequest(r, "key-3", `{}`).Code)
+
+	close(release)
+	assert.Equal(t, http.StatusCreated, (<-done).Code)
+}
+
+func TestIdempotencyServerErrorNotCached(t *testing.T) {
+	var calls int32
+	r := setupIdempotencyRouter(t, func(c *gin.Context) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			c.JSON(http.StatusInternalServerError, gin.H{})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{})
+	})
+
+	assert.Equal(t, http.StatusInternalServerError, doIdempotentRequest(r, "key-4", `{}`).Code)
+	assert.Equal(t, http.StatusCreated, doIdempotentRequest(r, "key-4", `{}`).Code)
+}
+
+func TestCORSPreflightAllowsPatchWithIdempotencyKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.CORSMiddleware())
+	r.PATCH("/users/1", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodOptions, "/users/1", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPatch)
+	req.Header.Set("Access-Control-Request-Headers", "Content-Type, "+middleware.IdempotencyKeyHeader)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Methods"), http.MethodPatch)
+	assert.Contains(t, strings.ToLower(w.Header().Get("Access-Control-Allow-Headers")), strings.ToLower(middleware.IdempotencyKeyHeader))
+}
@@ -0,0 +1,61 @@
+package test
+
+import (
+	"context"
+	"gin-demo/model/tool"
+	"gin-demo/pkg/migration"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMySQLMigrationLockAcquireAndRelease(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	mock.ExpectQuery("SELECT GET_LOCK").
+		WithArgs(migration.MigrationLockName, 30).
+		WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(1))
+	mock.ExpectExec("SELECT RELEASE_LOCK").
+		WithArgs(migration.MigrationLockName).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	release, err := migration.AcquireLock(context.Background(), db, migration.MigrationLockName, 30*time.Second)
+	require.NoError(t, err)
+	release()
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLMigrationLockTimeout(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	// 其他实例持有锁直到超时，GET_LOCK 返回0
+	mock.ExpectQuery("SELECT GET_LOCK").
+		WithArgs(migration.MigrationLockName, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(0))
+
+	_, err := migration.AcquireLock(context.Background(), db, migration.MigrationLockName, time.Second)
+	assert.ErrorIs(t, err, migration.ErrLockTimeout)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrateWithLockFastPath(t *testing.T) {
+	db := setupSQLiteDB(t)
+	ctx := context.Background()
+
+	// AutoMigrate已执行但版本化迁移未执行
+	current, err := migration.UpToDate(ctx, tool.Registry, tool.Migrations)
+	require.NoError(t, err)
+	assert.False(t, current)
+
+	require.NoError(t, migration.MigrateWithLock(ctx, tool.Registry, tool.Migrations, time.Second))
+	assert.True(t, db.Migrator().HasTable(&migration.SchemaMigration{}))
+
+	current, err = migration.UpToDate(ctx, tool.Registry, tool.Migrations)
+	require.NoError(t, err)
+	assert.True(t, current)
+	require.NoError(t, migration.MigrateWithLock(ctx, tool.Registry, tool.Migrations, time.Second))
+}
@@ -0,0 +1,110 @@
+package test
+
+import (
+	"context"
+	"gin-demo/database"
+	"gin-demo/model/tool"
+	"gin-demo/pkg/migration"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// planStatements 按表名查找迁移计划中的DDL和提示
+func planStatements(t *testing.T, table string) ([]string, []string) {
+	plans, err := tool.Registry.Plan(context.Background())
+	require.NoError(t, err)
+	for _, plan := range plans {
+		if plan.TableName == table {
+			return plan.Statements, plan.Notes
+		}
+	}
+	t.Fatalf("plan for table %s not found", table)
+	return nil, nil
+}
+
+func TestMigrationPlanNoDriftAfterMigrate(t *testing.T) {
+	setupSQLiteDB(t)
+
+	plans, err := tool.Registry.Plan(context.Background())
+	require.NoError(t, err)
+	for _, plan := range plans {
+		assert.False(t, plan.HasDrift(), "%s: %v", plan.TableName, plan.Statements)
+		assert.Empty(t, plan.ExtraIndexes, plan.TableName)
+	}
+}
+
+func TestMigrationPlanReportsExtraIndexes(t *testing.T) {
+	db := setupSQLiteDB(t)
+
+	// 从初始版本升级后残留的 email 唯一索引，以及版本化迁移之外手工创建的普通索引
+	require.NoError(t, db.Exec("CREATE UNIQUE INDEX email ON users (email)").Error)
+	require.NoError(t, db.Exec("CREATE INDEX idx_users_age ON users (age)").Error)
+
+	plans, err := tool.Registry.Plan(context.Background())
+	require.NoError(t, err)
+	var plan migration.ModelPlan
+	for _, p := range plans {
+		if p.TableName == "users" {
+			plan = p
+		}
+	}
+
+	require.Len(t, plan.ExtraIndexes, 2)
+	assert.Equal(t, migration.ExtraIndex{Name: "email", Columns: []string{"email"}, Unique: true, Drop: "DROP INDEX `email`"}, plan.ExtraIndexes[0])
+	assert.Equal(t, "idx_users_age", plan.ExtraIndexes[1].Name)
+	assert.False(t, plan.ExtraIndexes[1].Unique)
+	assert.True(t, plan.HasStaleUnique())
+	assert.True(t, db.Migrator().HasIndex("users", "email"))
+}
+
+func TestMigrationPlanReportsDriftWithoutExecuting(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: gormlogger.Discard})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		sqlDB.Close()
+	})
+
+	// 旧版本的users表：缺少phone列和盲索引列，多出legacy列，email带列级唯一约束
+	require.NoError(t, db.Exec("CREATE TABLE `users` (`id` integer PRIMARY KEY AUTOINCREMENT,`name` text NOT NULL,`email` text NOT NULL UNIQUE,`password` text NOT NULL,`age` integer,`legacy` text,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime)").Error)
+
+	statements, notes := planStatements(t, "users")
+	ddl := strings.Join(statements, "\n")
+	assert.Contains(t, ddl, "ADD `phone`")
+	assert.Contains(t, ddl, "ADD `email_bidx` text UNIQUE")
+	assert.NotContains(t, ddl, "SAVEPOINT")
+	assert.Contains(t, notes, "列 legacy 在模型中未定义")
+
+	// SQLite 列级唯一约束无法单独删除，只报告不生成删除语句
+	plans, err := tool.Registry.Plan(context.Background())
+	require.NoError(t, err)
+	for _, plan := range plans {
+		if plan.TableName == "users" {
+			require.Len(t, plan.ExtraIndexes, 1)
+			assert.Equal(t, []string{"email"}, plan.ExtraIndexes[0].Columns)
+			assert.True(t, plan.ExtraIndexes[0].Unique)
+			assert.Empty(t, plan.ExtraIndexes[0].Drop)
+		}
+	}
+
+	// 表不存在时输出建表语句
+	statements, _ = planStatements(t, "outbox_messages")
+	require.NotEmpty(t, statements)
+	assert.True(t, strings.HasPrefix(statements[0], "CREATE TABLE `outbox_messages`"))
+
+	// 计划不修改数据库
+	assert.False(t, db.Migrator().HasColumn("users", "phone"))
+	assert.False(t, db.Migrator().HasTable("outbox_messages"))
+}
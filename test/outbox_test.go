@@ -0,0 +1,250 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"gin-demo/config"
+	"gin-demo/model"
+	"gin-demo/pkg/outbox"
+	"gin-demo/pkg/queue"
+	"gin-demo/pkg/transaction"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/glebarez/sqlite"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
+)
+
+// fakePublisher 记录发布的消息，可模拟指定队列发布失败
+type fakePublisher struct {
+	mu        sync.Mutex
+	published []*queue.Message
+	failQueue string
+	onPublish func()
+}
+
+func (p *fakePublisher) Publish(queueName string, message *queue.Message, opts ...queue.PublishOption) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.onPublish != nil {
+		p.onPublish()
+	}
+	if queueName == p.failQueue {
+		return errors.New("queue unavailable")
+	}
+	p.published = append(p.published, message)
+	return nil
+}
+
+func TestOutboxRollbackDiscardsMessage(t *testing.T) {
+	db := setupSQLiteDB(t)
+	manager := transaction.NewManager(db)
+	ctx := context.Background()
+
+	err := manager.Run(ctx, func(ctx context.Context) error {
+		if err := outbox.Publish(ctx, queue.Email, "email.send", &queue.EmailData{To: []string{"a@example.com"}}); err != nil {
+			return err
+		}
+		return errors.New("boom")
+	})
+	require.EqualError(t, err, "boom")
+
+	var count int64
+	require.NoError(t, db.Model(&model.OutboxMessage{}).Count(&count).Error)
+	assert.Zero(t, count)
+
+	require.NoError(t, manager.Run(ctx, func(ctx context.Context) error {
+		return outbox.Publish(ctx, queue.Email, "email.send", &queue.EmailData{To: []string{"a@example.com"}})
+	}))
+	require.NoError(t, db.Model(&model.OutboxMessage{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestOutboxRelayDeliversInAggregateOrder(t *testing.T) {
+	db := setupSQLiteDB(t)
+	ctx := context.Background()
+
+	require.NoError(t, outbox.Publish(ctx, "broken", "order.created", 1, outbox.WithAggregate("order", 1)))
+	require.NoError(t, outbox.Publish(ctx, queue.Email, "order.paid", 1, outbox.WithAggregate("order", 1)))
+	require.NoError(t, outbox.Publish(ctx, queue.Email, "order.created", 2, outbox.WithAggregate("order", 2)))
+
+	publisher := &fakePublisher{failQueue: "broken"}
+	relay := outbox.NewRelay(db, publisher, nil, config.OutboxConfig{MaxAttempts: 2, RetryBackoff: time.Millisecond})
+
+	// order:1 的首条消息失败，阻塞其后续消息；order:2 不受影响
+	sent, err := relay.ProcessBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	require.Len(t, publisher.published, 1)
+	assert.Equal(t, "order.created", publisher.published[0].Type)
+
+	var first model.OutboxMessage
+	require.NoError(t, db.Order("id").First(&first).Error)
+	assert.Equal(t, model.OutboxStatusPending, first.Status)
+	assert.Equal(t, 1, first.Attempts)
+	assert.Equal(t, "queue unavailable", first.LastError)
+
+	// 达到最大次数后标记为failed，后续消息继续投递
+	time.Sleep(5 * time.Millisecond)
+	sent, err = relay.ProcessBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	require.Len(t, publisher.published, 2)
+	assert.Equal(t, "order.paid", publisher.published[1].Type)
+
+	require.NoError(t, db.First(&first, first.ID).Error)
+	assert.Equal(t, model.OutboxStatusFailed, first.Status)
+}
+
+func TestOutboxRelayStuckAggregateDoesNotStarveOthers(t *testing.T) {
+	db := setupSQLiteDB(t)
+	ctx := context.Background()
+
+	// order:1 的首条消息持续失败，其后积压的消息超过一个批次
+	require.NoError(t, outbox.Publish(ctx, "broken", "order.created", 1, outbox.WithAggregate("order", 1)))
+	for i := 0; i < 3; i++ {
+		require.NoError(t, outbox.Publish(ctx, queue.Email, "order.paid", 1, outbox.WithAggregate("order", 1)))
+	}
+	require.NoError(t, outbox.Publish(ctx, queue.Email, "order.created", 2, outbox.WithAggregate("order", 2)))
+
+	publisher := &fakePublisher{failQueue: "broken"}
+	relay := outbox.NewRelay(db, publisher, nil, config.OutboxConfig{BatchSize: 2, RetryBackoff: time.Hour})
+
+	sent, err := relay.ProcessBatch(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+
+	// 退避中的首条消息和被其阻塞的消息不再占用批次，order:2 得以投递
+	sent, err = relay.ProcessBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	require.Len(t, publisher.published, 1)
+	assert.Equal(t, "order.created", publisher.published[0].Type)
+
+	var pending int64
+	require.NoError(t, db.Model(&model.OutboxMessage{}).Where("status = ?", model.OutboxStatusPending).Count(&pending).Error)
+	assert.EqualValues(t, 4, pending)
+
+	sent, err = relay.ProcessBatch(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+	assert.Len(t, publisher.published, 1)
+}
+
+func TestOutboxCleanupRemovesExpiredSentMessages(t *testing.T) {
+	db := setupSQLiteDB(t)
+	ctx := context.Background()
+
+	sentAt := time.Now().Add(-2 * time.Hour)
+	messages := []model.OutboxMessage{
+		{Queue: queue.Email, MessageType: "a", Payload: "{}", Status: model.OutboxStatusSent, SentAt: &sentAt, AvailableAt: sentAt},
+		{Queue: queue.Email, MessageType: "b", Payload: "{}", Status: model.OutboxStatusPending, AvailableAt: sentAt},
+	}
+	require.NoError(t, db.Create(&messages).Error)
+
+	relay := outbox.NewRelay(db, &fakePublisher{}, nil, config.OutboxConfig{Retention: time.Hour})
+	deleted, err := relay.Cleanup(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	var remaining []model.OutboxMessage
+	require.NoError(t, db.Find(&remaining).Error)
+	require.Len(t, remaining, 1)
+	assert.Equal(t, "b", remaining[0].MessageType)
+}
+
+func TestOutboxRelayReadsFromPrimary(t *testing.T) {
+	db := setupSQLiteDB(t)
+	ctx := context.Background()
+
+	// 副本落后：主库已标记为已投递，副本仍是待投递
+	sentAt := time.Now()
+	require.NoError(t, db.Create(&model.OutboxMessage{
+		ID: 1, Queue: queue.Email, MessageType: "a", Payload: "{}", Status: model.OutboxStatusSent, SentAt: &sentAt, AvailableAt: sentAt,
+	}).Error)
+
+	replicaDSN := "file:outbox_replica?mode=memory&cache=shared"
+	replica, err := gorm.Open(sqlite.Open(replicaDSN), &gorm.Config{Logger: gormlogger.Discard})
+	require.NoError(t, err)
+	replicaDB, err := replica.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { replicaDB.Close() })
+	require.NoError(t, replica.AutoMigrate(&model.OutboxMessage{}))
+	require.NoError(t, replica.Create(&model.OutboxMessage{
+		ID: 1, Queue: queue.Email, MessageType: "a", Payload: "{}", Status: model.OutboxStatusPending, AvailableAt: sentAt,
+	}).Error)
+
+	require.NoError(t, db.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: []gorm.Dialector{sqlite.Open(replicaDSN)},
+	})))
+	var pending int64
+	require.NoError(t, db.Model(&model.OutboxMessage{}).Where("status = ?", model.OutboxStatusPending).Count(&pending).Error)
+	require.EqualValues(t, 1, pending)
+
+	publisher := &fakePublisher{}
+	relay := outbox.NewRelay(db, publisher, nil, config.OutboxConfig{})
+	sent, err := relay.ProcessBatch(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+	assert.Empty(t, publisher.published)
+}
+
+func TestOutboxRelayStopsWhenLockLost(t *testing.T) {
+	db := setupSQLiteDB(t)
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, outbox.Publish(ctx, queue.Email, "email.send", i))
+	}
+
+	// 第一批投递期间锁过期并被其他实例获取
+	publisher := &fakePublisher{}
+	publisher.onPublish = func() { mr.Set("outbox:relay:lock", "other") }
+	relay := outbox.NewRelay(db, publisher, rdb, config.OutboxConfig{BatchSize: 1, PollInterval: time.Hour})
+	relay.Start()
+	t.Cleanup(relay.Stop)
+
+	require.Eventually(t, func() bool {
+		publisher.mu.Lock()
+		defer publisher.mu.Unlock()
+		return len(publisher.published) > 0
+	}, time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	relay.Stop()
+
+	assert.Len(t, publisher.published, 1)
+	var pending int64
+	require.NoError(t, db.Model(&model.OutboxMessage{}).Where("status = ?", model.OutboxStatusPending).Count(&pending).Error)
+	assert.EqualValues(t, 2, pending)
+}
+
+func TestOutboxRelayDrainsBacklogBeyondBatchLimit(t *testing.T) {
+	db := setupSQLiteDB(t)
+	ctx := context.Background()
+
+	for i := 0; i < 25; i++ {
+		require.NoError(t, outbox.Publish(ctx, queue.Email, "email.send", i))
+	}
+
+	// 单次轮询最多投递固定批次，剩余积压立即在下一轮继续投递，无需等待轮询间隔
+	publisher := &fakePublisher{}
+	relay := outbox.NewRelay(db, publisher, nil, config.OutboxConfig{BatchSize: 1, PollInterval: time.Hour})
+	relay.Start()
+	t.Cleanup(relay.Stop)
+
+	assert.Eventually(t, func() bool {
+		publisher.mu.Lock()
+		defer publisher.mu.Unlock()
+		return len(publisher.published) == 25
+	}, 2*time.Second, 5*time.Millisecond)
+}
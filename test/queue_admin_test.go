@@ -0,0 +1,187 @@
+package test
+
+import (
+	"context"
+	"gin-demo/pkg/queue"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueuePauseAndResume(t *testing.T) {
+	forEachQueueDriver(t, testQueuePauseAndResume)
+}
+
+func testQueuePauseAndResume(t *testing.T, driver string) {
+	m, _ := setupQueueManager(t, driver, 0)
+	ctx := context.Background()
+	assert.Equal(t, []string{"flaky"}, m.Queues())
+
+	handler := &flakyHandler{}
+	handler.mode.Store("ok")
+	require.NoError(t, m.RegisterHandler(handler))
+
+	// 暂停期间消息不会被处理，发布不受影响
+	require.NoError(t, m.PauseQueue(ctx, "flaky"))
+	paused, err := m.IsPaused(ctx, "flaky")
+	require.NoError(t, err)
+	assert.True(t, paused)
+
+	require.NoError(t, m.PublishData("flaky", "email.send", 1))
+	time.Sleep(200 * time.Millisecond)
+	assert.Zero(t, handler.handled.Load())
+
+	require.NoError(t, m.ResumeQueue(ctx, "flaky"))
+	require.Eventually(t, func() bool { return handler.handled.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+	paused, err = m.IsPaused(ctx, "flaky")
+	require.NoError(t, err)
+	assert.False(t, paused)
+
+	assert.Error(t, m.PauseQueue(ctx, "missing"))
+}
+
+// blockingHandler 首条消息阻塞到 release 关闭，记录已处理数量
+type blockingHandler struct {
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+	handled atomic.Int32
+}
+
+func (h *blockingHandler) Handle(ctx context.Context, message *queue.Message) error {
+	h.once.Do(func() {
+		close(h.started)
+		<-h.release
+	})
+	h.handled.Add(1)
+	return nil
+}
+
+func (h *blockingHandler) GetQueueName() string  { return "flaky" }
+func (h *blockingHandler) GetNumConsumers() int  { return 1 }
+func (h *blockingHandler) GetPrefetchLimit() int { return 10 }
+
+func TestQueuePauseWithMessagesInFlight(t *testing.T) {
+	forEachQueueDriver(t, testQueuePauseWithMessagesInFlight)
+}
+
+func testQueuePauseWithMessagesInFlight(t *testing.T, driver string) {
+	m, _ := setupQueueManager(t, driver, 0)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, m.PublishData("flaky", "email.send", i))
+	}
+	handler := &blockingHandler{started: make(chan struct{}), release: make(chan struct{})}
+	require.NoError(t, m.RegisterHandler(handler))
+	<-handler.started
+
+	// 暂停等待正在处理的消息完成
+	paused := make(chan error, 1)
+	go func() { paused <- m.PauseQueue(ctx, "flaky") }()
+	select {
+	case <-paused:
+		t.Fatal("pause returned before the in-flight message finished")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(handler.release)
+	require.NoError(t, <-paused)
+
+	// 已预取未处理的消息回到就绪队列，可以查看和清空
+	stats, err := m.GetQueueStats(ctx, "flaky")
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, stats.Ready)
+	assert.Zero(t, stats.Unacked)
+	assert.Zero(t, stats.Consumers)
+	_, total, err := m.PeekMessages(ctx, "flaky", queue.MessageStateReady, "", 0, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, total)
+
+	time.Sleep(200 * time.Millisecond)
+	assert.EqualValues(t, 1, handler.handled.Load())
+
+	// 恢复后重新消费，每条消息只处理一次
+	require.NoError(t, m.ResumeQueue(ctx, "flaky"))
+	require.Eventually(t, func() bool { return handler.handled.Load() == 5 }, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	assert.EqualValues(t, 5, handler.handled.Load())
+	stats, err = m.GetQueueStats(ctx, "flaky")
+	require.NoError(t, err)
+	assert.Zero(t, stats.Ready)
+	assert.Zero(t, stats.Unacked)
+	assert.EqualValues(t, 1, stats.Consumers)
+
+	// 暂停后再次恢复不重复注册消费者
+	require.NoError(t, m.PauseQueue(ctx, "flaky"))
+	require.NoError(t, m.ResumeQueue(ctx, "flaky"))
+	require.NoError(t, m.ResumeQueue(ctx, "flaky"))
+	stats, err = m.GetQueueStats(ctx, "flaky")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, stats.Consumers)
+}
+
+func TestQueuePeekAndPurge(t *testing.T) {
+	forEachQueueDriver(t, testQueuePeekAndPurge)
+}
+
+func testQueuePeekAndPurge(t *testing.T, driver string) {
+	m, broker := setupQueueManager(t, driver, 0)
+	ctx := context.Background()
+
+	// 按进入顺序分页查看，不出队
+	var ids []string
+	for i := 0; i < 3; i++ {
+		message, err := queue.NewMessage("email.send", i)
+		require.NoError(t, err)
+		require.NoError(t, m.Publish("flaky", message))
+		ids = append(ids, message.ID)
+	}
+	messages, total, err := m.PeekMessages(ctx, "flaky", queue.MessageStateReady, "", 1, 1)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, total)
+	require.Len(t, messages, 1)
+	assert.Equal(t, ids[1], messages[0].ID)
+
+	messages, _, err = m.PeekMessages(ctx, "flaky", queue.MessageStateReady, "", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, messages, 3)
+	assert.Equal(t, ids[0], messages[0].ID)
+	assert.Equal(t, ids[2], messages[2].ID)
+
+	_, _, err = m.PeekMessages(ctx, "flaky", "unacked", "", 0, 10)
+	assert.ErrorIs(t, err, queue.ErrInvalidMessageState)
+	_, _, err = m.PeekMessages(ctx, "flaky", queue.MessageStateReady, queue.PriorityHigh, 0, 10)
+	assert.ErrorIs(t, err, queue.ErrUnknownPriority)
+
+	purged, err := m.PurgeQueue(ctx, "flaky", queue.MessageStateReady)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, purged)
+	stats, err := m.GetQueueStats(ctx, "flaky")
+	require.NoError(t, err)
+	assert.Zero(t, stats.Ready)
+
+	// 已拒绝的消息同样可以查看和清空，无法解析的内容原样返回
+	require.NoError(t, broker.Publish(ctx, "flaky_queue", []byte("not json")))
+	require.NoError(t, broker.Consume("flaky_queue", queue.ConsumeOptions{NumConsumers: 1, PrefetchLimit: 1, PollDuration: 10 * time.Millisecond}, func(delivery queue.Delivery) {
+		delivery.Reject()
+	}))
+	require.Eventually(t, func() bool {
+		stats, _ := m.GetQueueStats(ctx, "flaky")
+		return stats.Rejected == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	messages, total, err = m.PeekMessages(ctx, "flaky", queue.MessageStateRejected, "", 0, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+	require.Len(t, messages, 1)
+	assert.Equal(t, queue.DeadLetterInvalidPayload, messages[0].Type)
+	assert.Equal(t, "not json", string(messages[0].Data))
+
+	purged, err = m.PurgeQueue(ctx, "flaky", queue.MessageStateRejected)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, purged)
+}
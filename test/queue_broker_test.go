@@ -0,0 +1,172 @@
+package test
+
+import (
+	"context"
+	"gin-demo/config"
+	"gin-demo/pkg/queue"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// forEachQueueDriver 分别使用rmq（内存Redis）和内存后端运行测试
+func forEachQueueDriver(t *testing.T, test func(t *testing.T, driver string)) {
+	for _, driver := range []string{config.QueueDriverRMQ, config.QueueDriverMemory} {
+		t.Run(driver, func(t *testing.T) {
+			test(t, driver)
+		})
+	}
+}
+
+// setupQueueManager 使用指定后端创建队列管理器，rmq后端连接内存Redis
+func setupQueueManager(t *testing.T, driver string, retryLimit int) (*queue.Manager, queue.Broker) {
+	return setupQueueManagerWithConfig(t, driver, testQueueConfig(retryLimit))
+}
+
+// setupQueueManagerWithConfig 使用指定后端和队列配置创建队列管理器
+func setupQueueManagerWithConfig(t *testing.T, driver string, cfg *config.QueueConfig) (*queue.Manager, queue.Broker) {
+	cfg.Driver = driver
+
+	var broker queue.Broker
+	if driver == config.QueueDriverRMQ {
+		mr := miniredis.RunT(t)
+		rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { rdb.Close() })
+
+		var err error
+		broker, err = queue.NewRMQBroker(rdb, false, cfg.RMQ)
+		require.NoError(t, err)
+	} else {
+		broker = queue.NewMemoryBroker()
+	}
+
+	m := queue.NewManagerWithBroker(cfg, broker)
+	t.Cleanup(func() { m.Close() })
+	return m, broker
+}
+
+func TestMemoryBrokerAckAndReject(t *testing.T) {
+	ctx := context.Background()
+	broker := queue.NewMemoryBroker()
+
+	var handled atomic.Int32
+	require.NoError(t, broker.Consume("jobs", queue.ConsumeOptions{NumConsumers: 2}, func(delivery queue.Delivery) {
+		handled.Add(1)
+		if delivery.Payload() == "bad" {
+			assert.NoError(t, delivery.Reject())
+			return
+		}
+		assert.NoError(t, delivery.Ack())
+		assert.ErrorIs(t, delivery.Ack(), queue.ErrDeliveryFinished)
+	}))
+
+	for _, payload := range []string{"a", "bad", "b"} {
+		require.NoError(t, broker.Publish(ctx, "jobs", []byte(payload)))
+	}
+	require.NoError(t, broker.PublishAt(ctx, "jobs", []byte("later"), time.Now().Add(50*time.Millisecond)))
+
+	stats, err := broker.Stats(ctx, "jobs")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, stats.Delayed)
+	assert.EqualValues(t, 2, stats.Consumers)
+
+	require.Eventually(t, func() bool { return handled.Load() == 4 }, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		stats, _ := broker.Stats(ctx, "jobs")
+		return stats.Unacked == 0
+	}, time.Second, 10*time.Millisecond)
+	stats, err = broker.Stats(ctx, "jobs")
+	require.NoError(t, err)
+	assert.Equal(t, queue.QueueStats{Rejected: 1, Consumers: 2}, stats)
+
+	require.NoError(t, broker.Close())
+	assert.ErrorIs(t, broker.Publish(ctx, "jobs", []byte("c")), queue.ErrBrokerClosed)
+}
+
+func TestQueueDriverSelection(t *testing.T) {
+	cfg := testQueueConfig(0)
+	cfg.Driver = config.QueueDriverMemory
+	m, err := queue.NewManager(cfg)
+	require.NoError(t, err)
+	defer m.Close()
+
+	stats, err := m.GetQueueStats(context.Background(), "flaky")
+	require.NoError(t, err)
+	assert.Zero(t, stats.Ready)
+	_, err = m.GetQueueStats(context.Background(), "missing")
+	assert.Error(t, err)
+
+	cfg.Driver = "kafka"
+	_, err = queue.NewManager(cfg)
+	assert.Error(t, err)
+}
+
+// TestRMQBrokerKeysMatchRMQ rmq未公开查看列表和移除单个消费者的接口，RMQBroker 按 rmq v5.2.0 的内部键格式直接访问Redis；
+// 升级rmq后键格式变化时该测试失败，需要同步修改 broker_rmq.go 中的 listKey / consumersKey
+func TestRMQBrokerKeysMatchRMQ(t *testing.T) {
+	for _, cluster := range []bool{false, true} {
+		t.Run(map[bool]string{false: "standalone", true: "cluster"}[cluster], func(t *testing.T) {
+			ctx := context.Background()
+			mr := miniredis.RunT(t)
+			rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			t.Cleanup(func() { rdb.Close() })
+
+			broker, err := queue.NewRMQBroker(rdb, cluster, testQueueConfig(0).RMQ)
+			require.NoError(t, err)
+			t.Cleanup(func() { broker.Close() })
+
+			queueKey := "[jobs]"
+			if cluster {
+				queueKey = "{jobs}"
+			}
+
+			// 就绪列表：Peek 读取的键与rmq发布写入的键一致
+			require.NoError(t, broker.Publish(ctx, "jobs", []byte("bad")))
+			assert.True(t, mr.Exists("rmq::queue::"+queueKey+"::ready"))
+			messages, total, err := broker.Peek(ctx, "jobs", queue.MessageStateReady, 0, 10)
+			require.NoError(t, err)
+			assert.EqualValues(t, 1, total)
+			assert.Equal(t, []string{"bad"}, messages)
+
+			// 已拒绝列表
+			rejected := make(chan struct{})
+			require.NoError(t, broker.Consume("jobs", queue.ConsumeOptions{NumConsumers: 2, PrefetchLimit: 10, PollDuration: 10 * time.Millisecond}, func(delivery queue.Delivery) {
+				assert.NoError(t, delivery.Reject())
+				close(rejected)
+			}))
+			select {
+			case <-rejected:
+			case <-time.After(5 * time.Second):
+				t.Fatal("message not consumed")
+			}
+			assert.True(t, mr.Exists("rmq::queue::"+queueKey+"::rejected"))
+			messages, _, err = broker.Peek(ctx, "jobs", queue.MessageStateRejected, 0, 10)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"bad"}, messages)
+
+			// 消费者集合：停止消费时从rmq记录的集合中移除本连接的消费者
+			var consumersKeys []string
+			for _, key := range mr.Keys() {
+				if strings.HasPrefix(key, "rmq::connection::") && strings.HasSuffix(key, "::queue::"+queueKey+"::consumers") {
+					consumersKeys = append(consumersKeys, key)
+				}
+			}
+			require.Len(t, consumersKeys, 1)
+			members, err := mr.Members(consumersKeys[0])
+			require.NoError(t, err)
+			assert.Len(t, members, 2)
+
+			require.NoError(t, broker.StopConsuming("jobs"))
+			assert.False(t, mr.Exists(consumersKeys[0]))
+			stats, err := broker.Stats(ctx, "jobs")
+			require.NoError(t, err)
+			assert.Zero(t, stats.Consumers)
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package test
+
+import (
+	"context"
+	"gin-demo/config"
+	"gin-demo/pkg/logger"
+	"gin-demo/pkg/outbox"
+	"gin-demo/pkg/queue"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// funcHandler 使用函数处理消息的测试处理器
+type funcHandler struct {
+	flakyHandler
+	handle func(ctx context.Context, message *queue.Message) error
+}
+
+func (h *funcHandler) Handle(ctx context.Context, message *queue.Message) error {
+	return h.handle(ctx, message)
+}
+
+func TestQueueTracePropagation(t *testing.T) {
+	db := setupSQLiteDB(t)
+	m, _ := setupQueueManager(t, config.QueueDriverMemory, 0)
+
+	traceIDs := make(chan string, 2)
+	require.NoError(t, m.RegisterHandler(&funcHandler{handle: func(ctx context.Context, message *queue.Message) error {
+		_, hasDeadline := ctx.Deadline()
+		assert.True(t, hasDeadline)
+		traceIDs <- logger.GetCurrentTraceID(ctx)
+		return nil
+	}}))
+
+	// 请求中直接发布
+	ctx := logger.SetTraceContext(context.Background(), "req-direct")
+	message, err := queue.NewMessage("ping", 1)
+	require.NoError(t, err)
+	require.NoError(t, m.PublishContext(ctx, "flaky", message))
+	assert.Equal(t, "req-direct", <-traceIDs)
+
+	// 经发件箱投递，链路追踪ID保存在消息元数据中
+	ctx = logger.SetTraceContext(context.Background(), "req-outbox")
+	require.NoError(t, outbox.Publish(ctx, "flaky", "ping", 2))
+	_, err = outbox.NewRelay(db, m, nil, config.OutboxConfig{}).ProcessBatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "req-outbox", <-traceIDs)
+}
+
+func TestQueueHandlerTimeout(t *testing.T) {
+	cfg := testQueueConfig(0)
+	item := cfg.Queues["flaky"]
+	item.Timeout = 20 * time.Millisecond
+	cfg.Queues["flaky"] = item
+	m := queue.NewManagerWithBroker(cfg, queue.NewMemoryBroker())
+	defer m.Close()
+	ctx := context.Background()
+
+	require.NoError(t, m.RegisterHandler(&funcHandler{handle: func(ctx context.Context, message *queue.Message) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}))
+	message, err := queue.NewMessage("slow", 1)
+	require.NoError(t, err)
+	require.NoError(t, m.Publish("flaky", message))
+
+	require.Eventually(t, func() bool {
+		count, _ := m.DeadLetters().Count(ctx, "flaky")
+		return count == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	dead, err := m.DeadLetters().Get(ctx, "flaky", message.ID)
+	require.NoError(t, err)
+	info, _ := dead.DeadLetterInfo()
+	assert.Contains(t, info.Error, context.DeadlineExceeded.Error())
+}
+
+func TestQueueShutdownReturnsInFlightMessage(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	cfg := testQueueConfig(3)
+	broker, err := queue.NewRMQBroker(rdb, false, cfg.RMQ)
+	require.NoError(t, err)
+	m := queue.NewManagerWithBroker(cfg, broker)
+
+	started := make(chan struct{})
+	require.NoError(t, m.RegisterHandler(&funcHandler{handle: func(ctx context.Context, message *queue.Message) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}}))
+	message, err := queue.NewMessage("long", 1)
+	require.NoError(t, err)
+	require.NoError(t, m.Publish("flaky", message))
+
+	<-started
+	require.NoError(t, m.Close())
+
+	// 关闭时中断的消息放回队列，不计入失败次数
+	broker, err = queue.NewRMQBroker(rdb, false, cfg.RMQ)
+	require.NoError(t, err)
+	defer broker.Close()
+	stats, err := broker.Stats(ctx, "flaky_queue")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, stats.Ready)
+	assert.Zero(t, stats.Unacked)
+	assert.Zero(t, stats.Delayed)
+
+	count, err := broker.DeadLetters().Count(ctx, "flaky")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
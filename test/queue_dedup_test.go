@@ -0,0 +1,132 @@
+package test
+
+import (
+	"context"
+	"gin-demo/config"
+	"gin-demo/pkg/queue"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dedupQueueConfig 开启去重的测试队列配置
+func dedupQueueConfig(retryLimit int) *config.QueueConfig {
+	cfg := testQueueConfig(retryLimit)
+	item := cfg.Queues["flaky"]
+	item.Dedup = &config.DedupConfig{TTL: time.Minute, LockTimeout: 50 * time.Millisecond}
+	cfg.Queues["flaky"] = item
+	return cfg
+}
+
+func TestQueueDedupDropsDuplicates(t *testing.T) {
+	forEachQueueDriver(t, testQueueDedupDropsDuplicates)
+}
+
+func testQueueDedupDropsDuplicates(t *testing.T, driver string) {
+	m, _ := setupQueueManagerWithConfig(t, driver, dedupQueueConfig(0))
+	ctx := context.Background()
+
+	handler := &flakyHandler{}
+	handler.mode.Store("ok")
+	require.NoError(t, m.RegisterHandler(handler))
+
+	// 同一消息重复投递（如发件箱重复发布）只处理一次
+	message, err := queue.NewMessage("email.send", "a@example.com")
+	require.NoError(t, err)
+	require.NoError(t, m.Publish("flaky", message))
+	require.Eventually(t, func() bool { return handler.handled.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, m.Publish("flaky", message))
+
+	// 不同消息携带相同业务幂等键同样只处理一次
+	first, err := queue.NewMessage("email.send", "b@example.com")
+	require.NoError(t, err)
+	first.SetIdempotencyKey("order:1:confirmation")
+	second, err := queue.NewMessage("email.send", "b@example.com")
+	require.NoError(t, err)
+	second.SetIdempotencyKey("order:1:confirmation")
+	require.NoError(t, m.Publish("flaky", first))
+	require.Eventually(t, func() bool { return handler.handled.Load() == 2 }, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, m.Publish("flaky", second))
+
+	require.Eventually(t, func() bool {
+		stats, _ := m.DedupStats(ctx, "flaky")
+		return stats.Duplicates == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.EqualValues(t, 2, handler.handled.Load())
+}
+
+func TestQueueDedupAllowsRetryAfterFailure(t *testing.T) {
+	forEachQueueDriver(t, testQueueDedupAllowsRetryAfterFailure)
+}
+
+func testQueueDedupAllowsRetryAfterFailure(t *testing.T, driver string) {
+	m, _ := setupQueueManagerWithConfig(t, driver, dedupQueueConfig(3))
+	ctx := context.Background()
+
+	// 首次处理失败释放处理中状态，重试时可以再次处理
+	var calls atomic.Int32
+	handler := &funcHandler{handle: func(ctx context.Context, message *queue.Message) error {
+		if calls.Add(1) == 1 {
+			return assert.AnError
+		}
+		return nil
+	}}
+	require.NoError(t, m.RegisterHandler(handler))
+
+	message, err := queue.NewMessage("email.send", "c@example.com")
+	require.NoError(t, err)
+	require.NoError(t, m.Publish("flaky", message))
+	require.Eventually(t, func() bool { return calls.Load() == 2 }, 5*time.Second, 10*time.Millisecond)
+
+	// 处理成功后再次投递被丢弃
+	require.NoError(t, m.Publish("flaky", message))
+	require.Eventually(t, func() bool {
+		stats, _ := m.DedupStats(ctx, "flaky")
+		return stats.Duplicates == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.EqualValues(t, 2, calls.Load())
+}
+
+func TestQueueDedupDefersInProgressDuplicate(t *testing.T) {
+	forEachQueueDriver(t, testQueueDedupDefersInProgressDuplicate)
+}
+
+func testQueueDedupDefersInProgressDuplicate(t *testing.T, driver string) {
+	cfg := dedupQueueConfig(0)
+	item := cfg.Queues["flaky"]
+	item.NumConsumers = 2
+	item.Dedup.LockTimeout = 300 * time.Millisecond
+	cfg.Queues["flaky"] = item
+	m, _ := setupQueueManagerWithConfig(t, driver, cfg)
+	ctx := context.Background()
+
+	// 第一个消费者处理期间到达的重复消息延后投递，完成后再到达时被丢弃
+	release := make(chan struct{})
+	var calls atomic.Int32
+	require.NoError(t, m.RegisterHandler(&funcHandler{handle: func(ctx context.Context, message *queue.Message) error {
+		calls.Add(1)
+		<-release
+		return nil
+	}}))
+
+	message, err := queue.NewMessage("email.send", "d@example.com")
+	require.NoError(t, err)
+	require.NoError(t, m.Publish("flaky", message))
+	require.Eventually(t, func() bool { return calls.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, m.Publish("flaky", message))
+
+	require.Eventually(t, func() bool {
+		stats, _ := m.DedupStats(ctx, "flaky")
+		return stats.Deferred >= 1
+	}, 5*time.Second, 10*time.Millisecond)
+	close(release)
+
+	require.Eventually(t, func() bool {
+		stats, _ := m.DedupStats(ctx, "flaky")
+		return stats.Duplicates == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.EqualValues(t, 1, calls.Load())
+}
@@ -0,0 +1,95 @@
+package test
+
+import (
+	"context"
+	"gin-demo/config"
+	"gin-demo/pkg/queue"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// priorityQueueConfig 配置了优先级通道的测试队列配置，单个消费者便于观察消费顺序
+func priorityQueueConfig() *config.QueueConfig {
+	cfg := testQueueConfig(0)
+	item := cfg.Queues["flaky"]
+	item.NumConsumers = 1
+	item.Priorities = map[string]int{queue.PriorityHigh: 100, queue.PriorityLow: 1}
+	cfg.Queues["flaky"] = item
+	return cfg
+}
+
+func TestQueuePriorityLanes(t *testing.T) {
+	forEachQueueDriver(t, testQueuePriorityLanes)
+}
+
+func testQueuePriorityLanes(t *testing.T, driver string) {
+	m, _ := setupQueueManagerWithConfig(t, driver, priorityQueueConfig())
+
+	// 批量低优先级消息积压时，后发布的高优先级消息优先被消费
+	for i := 0; i < 20; i++ {
+		require.NoError(t, m.PublishData("flaky", "newsletter", i, queue.WithPriority(queue.PriorityLow)))
+	}
+	for i := 0; i < 5; i++ {
+		require.NoError(t, m.PublishData("flaky", "password_reset", i, queue.WithPriority(queue.PriorityHigh)))
+	}
+
+	var mu sync.Mutex
+	var order []string
+	require.NoError(t, m.RegisterHandler(&funcHandler{handle: func(ctx context.Context, message *queue.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, message.Type)
+		time.Sleep(time.Millisecond) // 模拟发送耗时
+		return nil
+	}}))
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(order) == 25
+	}, 5*time.Second, 10*time.Millisecond)
+
+	last := 0
+	for i, msgType := range order {
+		if msgType == "password_reset" {
+			last = i
+		}
+	}
+	assert.Less(t, last, 10, "high priority messages should not wait behind the backlog: %v", order)
+}
+
+func TestQueuePriorityStats(t *testing.T) {
+	forEachQueueDriver(t, testQueuePriorityStats)
+}
+
+func testQueuePriorityStats(t *testing.T, driver string) {
+	m, _ := setupQueueManagerWithConfig(t, driver, priorityQueueConfig())
+	ctx := context.Background()
+
+	message, err := queue.NewMessage("password_reset", 1)
+	require.NoError(t, err)
+	require.NoError(t, m.Publish("flaky", message, queue.WithPriority(queue.PriorityHigh)))
+	require.NoError(t, m.PublishData("flaky", "newsletter", 1))
+	// 优先级保存在消息中，延迟重试时回到原通道
+	delayed, err := queue.NewMessage("password_reset", 2)
+	require.NoError(t, err)
+	delayed.SetPriority(queue.PriorityHigh)
+	require.NoError(t, m.PublishDelayed("flaky", delayed, time.Hour))
+
+	err = m.PublishData("flaky", "newsletter", 2, queue.WithPriority("urgent"))
+	assert.ErrorIs(t, err, queue.ErrUnknownPriority)
+
+	stats, err := m.GetQueueStats(ctx, "flaky")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, stats.Ready)
+	assert.EqualValues(t, 1, stats.Delayed)
+	require.Len(t, stats.Lanes, 3)
+	assert.EqualValues(t, 1, stats.Lanes[queue.PriorityHigh].Ready)
+	assert.EqualValues(t, 1, stats.Lanes[queue.PriorityHigh].Delayed)
+	assert.EqualValues(t, 1, stats.Lanes[queue.PriorityNormal].Ready)
+	assert.Zero(t, stats.Lanes[queue.PriorityLow].Ready)
+}
@@ -0,0 +1,104 @@
+package test
+
+import (
+	"context"
+	"gin-demo/pkg/queue"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type orderPaid struct {
+	OrderID int `json:"order_id" binding:"required"`
+}
+
+type userInvited struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+var (
+	orderPaidType   = queue.NewMessageType[orderPaid]("flaky", "order.paid")
+	userInvitedType = queue.NewMessageType[*userInvited]("flaky", "user.invited")
+)
+
+func TestQueueRouterDispatchesTypedPayloads(t *testing.T) {
+	forEachQueueDriver(t, testQueueRouterDispatchesTypedPayloads)
+}
+
+func testQueueRouterDispatchesTypedPayloads(t *testing.T, driver string) {
+	m, _ := setupQueueManager(t, driver, 3)
+	ctx := context.Background()
+
+	// 多个消息类型共享一个队列，载荷按类型自动解码
+	var paid, invited atomic.Int32
+	router := queue.NewRouter("flaky", 1, 10)
+	require.NoError(t, queue.Register(router, orderPaidType, func(ctx context.Context, payload orderPaid) error {
+		message, ok := queue.MessageFromContext(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, "order.paid", message.Type)
+		paid.Add(int32(payload.OrderID))
+		return nil
+	}))
+	require.NoError(t, queue.Register(router, userInvitedType, func(ctx context.Context, payload *userInvited) error {
+		assert.Equal(t, "a@example.com", payload.Email)
+		invited.Add(1)
+		return nil
+	}))
+	assert.Equal(t, []string{"order.paid", "user.invited"}, router.Types())
+	require.NoError(t, m.RegisterHandler(router))
+
+	require.NoError(t, orderPaidType.Publish(m, orderPaid{OrderID: 7}))
+	require.NoError(t, userInvitedType.Publish(m, &userInvited{Email: "a@example.com"}))
+	require.Eventually(t, func() bool { return paid.Load() == 7 && invited.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	// 校验失败和未注册的类型不重试，直接进入死信队列
+	require.NoError(t, userInvitedType.Publish(m, &userInvited{Email: "not-an-email"}))
+	require.NoError(t, m.PublishData("flaky", "order.refunded", orderPaid{OrderID: 7}))
+	require.Eventually(t, func() bool {
+		count, _ := m.DeadLetters().Count(ctx, "flaky")
+		return count == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	messages, _, err := m.DeadLetters().List(ctx, "flaky", 0, 10)
+	require.NoError(t, err)
+	for _, message := range messages {
+		info, ok := message.DeadLetterInfo()
+		require.True(t, ok)
+		assert.Equal(t, queue.DeadLetterPermanentError, info.Reason)
+		assert.Len(t, message.Attempts(), 1)
+		if message.Type == "order.refunded" {
+			assert.Contains(t, info.Error, "unknown message type")
+		} else {
+			assert.Contains(t, info.Error, "invalid user.invited payload")
+		}
+	}
+	assert.EqualValues(t, 1, invited.Load())
+}
+
+func TestQueueRouterRegister(t *testing.T) {
+	router := queue.NewRouter("flaky", 1, 10)
+	handle := func(ctx context.Context, payload orderPaid) error { return nil }
+	require.NoError(t, queue.Register(router, orderPaidType, handle))
+
+	// 同一类型重复注册、类型属于其他队列时报错
+	assert.Error(t, queue.Register(router, orderPaidType, handle))
+	assert.Error(t, queue.Register(router, queue.NewMessageType[orderPaid]("email", "order.paid"), handle))
+
+	// 未注册的类型和空载荷为永久性错误
+	message, err := queue.NewMessage("order.shipped", nil)
+	require.NoError(t, err)
+	err = router.Handle(context.Background(), message)
+	assert.ErrorIs(t, err, queue.ErrUnknownMessageType)
+	assert.True(t, queue.IsPermanent(err))
+
+	email := queue.NewEmailHandler(queue.Email, 1, 10)
+	message, err = queue.NewMessage(queue.EmailSend.Name, nil)
+	require.NoError(t, err)
+	assert.True(t, queue.IsPermanent(email.Handle(context.Background(), message)))
+	message, err = queue.EmailSend.New(&queue.EmailData{To: []string{"bad"}, Subject: "s", Body: "b"})
+	require.NoError(t, err)
+	assert.True(t, queue.IsPermanent(email.Handle(context.Background(), message)))
+}
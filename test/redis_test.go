@@ -0,0 +1,66 @@
+package test
+
+import (
+	"context"
+	"gin-demo/config"
+	"gin-demo/database"
+	"gin-demo/pkg/middleware"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRedisClientModes(t *testing.T) {
+	single, err := database.NewRedisClient(&config.RedisConfig{Host: "127.0.0.1", Port: 6379})
+	require.NoError(t, err)
+	defer single.Close()
+	assert.IsType(t, &redis.Client{}, single)
+
+	sentinel, err := database.NewRedisClient(&config.RedisConfig{
+		Mode:          config.RedisModeSentinel,
+		MasterName:    "mymaster",
+		SentinelAddrs: []string{"127.0.0.1:26379"},
+	})
+	require.NoError(t, err)
+	defer sentinel.Close()
+	assert.IsType(t, &redis.Client{}, sentinel)
+
+	cluster, err := database.NewRedisClient(&config.RedisConfig{
+		Mode:         config.RedisModeCluster,
+		ClusterNodes: []string{"127.0.0.1:7000", "127.0.0.1:7001"},
+	})
+	require.NoError(t, err)
+	defer cluster.Close()
+	assert.IsType(t, &redis.ClusterClient{}, cluster)
+
+	_, err = database.NewRedisClient(&config.RedisConfig{Mode: "unknown"})
+	assert.Error(t, err)
+}
+
+func TestRedisRateLimiter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	previous := database.RDB
+	database.RDB = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		database.RDB.Close()
+		database.RDB = previous
+	})
+
+	ctx := context.Background()
+	limiter := middleware.NewRedisRateLimiter(3, time.Minute)
+
+	// 同一时刻的多个请求分别计数
+	for i := 0; i < 3; i++ {
+		assert.True(t, limiter.Allow(ctx, "1.2.3.4"))
+	}
+	assert.False(t, limiter.Allow(ctx, "1.2.3.4"))
+	assert.Equal(t, 0, limiter.GetRemainingRequests(ctx, "1.2.3.4"))
+
+	// 不同客户端互不影响
+	assert.True(t, limiter.Allow(ctx, "5.6.7.8"))
+	assert.Equal(t, 2, limiter.GetRemainingRequests(ctx, "5.6.7.8"))
+}
@@ -0,0 +1,207 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gin-demo/model/tool"
+	"gin-demo/repository"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// repoItem 泛型仓储测试模型：唯一编码、软删除
+type repoItem struct {
+	ID        uint   `gorm:"primaryKey"`
+	Code      string `gorm:"size:32;uniqueIndex"`
+	Name      string `gorm:"size:64"`
+	Stock     int
+	DeletedAt gorm.DeletedAt
+}
+
+// setupItemRepository 创建带测试表的泛型仓储
+func setupItemRepository(t *testing.T) (*repository.Repository[repoItem], *gorm.DB) {
+	db := setupSQLiteDB(t)
+	require.NoError(t, db.AutoMigrate(&repoItem{}))
+	return repository.NewRepository[repoItem](db), db
+}
+
+func TestRepositoryUpsert(t *testing.T) {
+	repo, _ := setupItemRepository(t)
+	ctx := context.Background()
+
+	require.NoError(t, repo.Create(ctx, &repoItem{Code: "A", Name: "apple", Stock: 1}))
+
+	// 冲突时只更新指定字段
+	require.NoError(t, repo.Upsert(ctx, &repoItem{Code: "A", Name: "avocado", Stock: 5}, []string{"code"}, []string{"stock"}))
+	item, err := repo.First(ctx, repository.Where("code = ?", "A"))
+	require.NoError(t, err)
+	assert.Equal(t, "apple", item.Name)
+	assert.Equal(t, 5, item.Stock)
+
+	// 未指定更新字段时更新全部字段
+	require.NoError(t, repo.Upsert(ctx, &repoItem{Code: "A", Name: "avocado", Stock: 6}, []string{"code"}, nil))
+	item, err = repo.First(ctx, repository.Where("code = ?", "A"))
+	require.NoError(t, err)
+	assert.Equal(t, "avocado", item.Name)
+	assert.Equal(t, 6, item.Stock)
+
+	// 批量Upsert：已存在的更新，不存在的插入
+	require.NoError(t, repo.UpsertInBatches(ctx, []repoItem{
+		{Code: "A", Name: "apricot", Stock: 7},
+		{Code: "B", Name: "banana", Stock: 2},
+	}, 1, []string{"code"}, []string{"name", "stock"}))
+	count, err := repo.Count(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+	item, err = repo.First(ctx, repository.Where("code = ?", "A"))
+	require.NoError(t, err)
+	assert.Equal(t, "apricot", item.Name)
+}
+
+func TestRepositoryExists(t *testing.T) {
+	repo, _ := setupItemRepository(t)
+	ctx := context.Background()
+
+	exists, err := repo.Exists(ctx, repository.Where("code = ?", "A"))
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	item := &repoItem{Code: "A"}
+	require.NoError(t, repo.Create(ctx, item))
+	exists, err = repo.Exists(ctx, repository.Where("code = ?", "A"))
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// 软删除的记录默认不计入，WithTrashed 时计入
+	require.NoError(t, repo.Delete(ctx, item.ID))
+	exists, err = repo.Exists(ctx, repository.Where("code = ?", "A"))
+	require.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = repo.Exists(ctx, repository.WithTrashed(), repository.Where("code = ?", "A"))
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestRepositorySoftDeleteRestoreAndForceDelete(t *testing.T) {
+	repo, db := setupItemRepository(t)
+	ctx := context.Background()
+
+	items := []repoItem{{Code: "A"}, {Code: "B"}, {Code: "C"}}
+	require.NoError(t, repo.CreateInBatches(ctx, items, 10))
+
+	require.NoError(t, repo.Delete(ctx, items[0].ID))
+	affected, err := repo.DeleteByIDs(ctx, []uint{items[1].ID})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, affected)
+
+	_, err = repo.GetByID(ctx, items[0].ID)
+	assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+	trashed, err := repo.Find(ctx, repository.OnlyTrashed(), repository.OrderBy("id"))
+	require.NoError(t, err)
+	require.Len(t, trashed, 2)
+	assert.Equal(t, "A", trashed[0].Code)
+	all, err := repo.Find(ctx, repository.WithTrashed())
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+
+	// 恢复后可正常查询
+	require.NoError(t, repo.Restore(ctx, items[0].ID))
+	restored, err := repo.GetByID(ctx, items[0].ID)
+	require.NoError(t, err)
+	assert.False(t, restored.DeletedAt.Valid)
+
+	// 永久删除同时适用于正常和软删除的记录
+	require.NoError(t, repo.ForceDelete(ctx, items[0].ID))
+	require.NoError(t, repo.ForceDelete(ctx, items[1].ID))
+	var remaining int64
+	require.NoError(t, db.Unscoped().Model(&repoItem{}).Count(&remaining).Error)
+	assert.EqualValues(t, 1, remaining)
+
+	// 按条件删除
+	affected, err = repo.DeleteWhere(ctx, repository.Where("code = ?", "C"))
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, affected)
+	count, err := repo.Count(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestRepositoryBatches(t *testing.T) {
+	repo, _ := setupItemRepository(t)
+	ctx := context.Background()
+
+	require.NoError(t, repo.CreateInBatches(ctx, nil, 10))
+	items := make([]repoItem, 0, 25)
+	for i := 0; i < 25; i++ {
+		items = append(items, repoItem{Code: fmt.Sprintf("C%02d", i), Stock: i})
+	}
+	require.NoError(t, repo.CreateInBatches(ctx, items, 10))
+	for _, item := range items {
+		assert.NotZero(t, item.ID)
+	}
+
+	// 按批次遍历符合条件的记录
+	var sizes []int
+	var total int
+	require.NoError(t, repo.FindInBatches(ctx, 10, func(batch []repoItem) error {
+		sizes = append(sizes, len(batch))
+		for _, item := range batch {
+			total += item.Stock
+		}
+		return nil
+	}, repository.Where("stock >= ?", 5)))
+	assert.Equal(t, []int{10, 10}, sizes)
+	assert.Equal(t, 290, total)
+
+	// fn 返回错误时停止遍历
+	stop := errors.New("stop")
+	batches := 0
+	err := repo.FindInBatches(ctx, 10, func(batch []repoItem) error {
+		batches++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, batches)
+}
+
+func TestRepositoryPaginateWithScopes(t *testing.T) {
+	repo, _ := setupItemRepository(t)
+	ctx := context.Background()
+
+	items := make([]repoItem, 0, 12)
+	for i := 0; i < 12; i++ {
+		name := "odd"
+		if i%2 == 0 {
+			name = "even"
+		}
+		items = append(items, repoItem{Code: fmt.Sprintf("C%02d", i), Name: name, Stock: i})
+	}
+	require.NoError(t, repo.CreateInBatches(ctx, items, 100))
+	require.NoError(t, repo.Delete(ctx, items[0].ID))
+
+	// 总数和分页均应用额外作用域（软删除的记录不计入）
+	page, total, err := repo.Paginate(ctx, &tool.PaginationRequest{Page: 2, PageSize: 2, OrderBy: "stock", Order: "asc"},
+		repository.Where("name = ?", "even"))
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, total)
+	require.Len(t, page, 2)
+	assert.Equal(t, 6, page[0].Stock)
+	assert.Equal(t, 8, page[1].Stock)
+
+	// 超出范围的页为空，总数不变
+	page, total, err = repo.Paginate(ctx, &tool.PaginationRequest{Page: 4, PageSize: 2}, repository.Where("name = ?", "even"))
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, total)
+	assert.Empty(t, page)
+
+	// 回收站分页
+	page, total, err = repo.Paginate(ctx, &tool.PaginationRequest{}, repository.OnlyTrashed())
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+	require.Len(t, page, 1)
+	assert.Equal(t, "C00", page[0].Code)
+}
@@ -0,0 +1,42 @@
+package test
+
+import (
+	"context"
+	"database/sql"
+	"gin-demo/database"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestReplicaPolicyRoundRobinSkipsUnhealthy(t *testing.T) {
+	primary, replicaA, replicaB := &sql.DB{}, &sql.DB{}, &sql.DB{}
+	pools := []gorm.ConnPool{replicaA, replicaB, primary}
+	policy := database.NewReplicaPolicy(database.ReplicaPolicyRoundRobin, primary)
+
+	// 轮询所有健康副本，不会选中主库
+	assert.Same(t, replicaA, policy.Resolve(pools))
+	assert.Same(t, replicaB, policy.Resolve(pools))
+	assert.Same(t, replicaA, policy.Resolve(pools))
+
+	// 剔除不健康副本
+	assert.True(t, policy.SetHealthy(replicaA, false))
+	assert.False(t, policy.SetHealthy(replicaA, false))
+	assert.Same(t, replicaB, policy.Resolve(pools))
+	assert.Same(t, replicaB, policy.Resolve(pools))
+
+	// 所有副本不可用时回退到主库
+	policy.SetHealthy(replicaB, false)
+	assert.Same(t, primary, policy.Resolve(pools))
+
+	// 副本恢复后重新加入
+	assert.True(t, policy.SetHealthy(replicaA, true))
+	assert.Same(t, replicaA, policy.Resolve(pools))
+}
+
+func TestWithPrimary(t *testing.T) {
+	ctx := context.Background()
+	assert.False(t, database.UsePrimary(ctx))
+	assert.True(t, database.UsePrimary(database.WithPrimary(ctx)))
+}
@@ -0,0 +1,176 @@
+package test
+
+import (
+	"context"
+	"gin-demo/config"
+	"gin-demo/model"
+	"gin-demo/model/tool"
+	"gin-demo/pkg/retention"
+	"gin-demo/repository"
+	"gin-demo/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestSoftDeletedUserReleasesUniqueEmail(t *testing.T) {
+	db := setupSQLiteDB(t)
+	ctx := context.Background()
+	repo := repository.NewUserRepository(db)
+	userService := service.NewUserService(repo)
+
+	first := &model.User{Name: "Alice", Email: "alice@example.com", Password: "x", Phone: "13800000001"}
+	require.NoError(t, repo.Create(ctx, first))
+	require.NoError(t, userService.DeleteUser(ctx, first.ID))
+
+	// 软删除后盲索引清空，同一邮箱和手机号可以重新注册
+	var deleted model.User
+	require.NoError(t, db.Unscoped().First(&deleted, first.ID).Error)
+	assert.True(t, deleted.DeletedAt.Valid)
+	assert.Nil(t, deleted.EmailBidx)
+	assert.Nil(t, deleted.PhoneBidx)
+
+	second := &model.User{Name: "Alice2", Email: "alice@example.com", Password: "x", Phone: "13800000001"}
+	require.NoError(t, repo.Create(ctx, second))
+
+	// 邮箱已被占用时无法恢复
+	_, err := userService.RestoreUser(ctx, first.ID)
+	assert.ErrorIs(t, err, service.ErrUserConflict)
+
+	// 占用者删除后可以恢复，盲索引重新计算
+	require.NoError(t, userService.DeleteUser(ctx, second.ID))
+	restored, err := userService.RestoreUser(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", restored.Name)
+	found, err := repo.GetByEmail(ctx, "alice@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, found.ID)
+
+	// 回收站列表与永久删除
+	result, err := userService.GetTrashedUsers(ctx, &tool.PaginationRequest{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, result.Meta.Total)
+
+	_, err = userService.RestoreUser(ctx, first.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, userService.ForceDeleteUser(ctx, first.ID), gorm.ErrRecordNotFound)
+
+	require.NoError(t, userService.ForceDeleteUser(ctx, second.ID))
+	var count int64
+	require.NoError(t, db.Unscoped().Model(&model.User{}).Where("id = ?", second.ID).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestDeletedUserEmailReusableAfterBaselineUpgrade(t *testing.T) {
+	db := setupSQLiteDB(t)
+	ctx := context.Background()
+	repo := repository.NewUserRepository(db)
+	userService := service.NewUserService(repo)
+
+	// 从初始版本自动迁移后的结构：MySQL 保留以列名命名的 email、phone 唯一索引
+	// （SQLite 的列级唯一约束在自动迁移重建表时已删除，这里按 MySQL 的结果创建）
+	require.NoError(t, db.Exec("CREATE UNIQUE INDEX email ON users (email)").Error)
+	require.NoError(t, db.Exec("CREATE UNIQUE INDEX phone ON users (phone)").Error)
+
+	first := &model.User{Name: "Alice", Email: "alice@example.com", Password: "x", Phone: "13800000001"}
+	require.NoError(t, repo.Create(ctx, first))
+	require.NoError(t, userService.DeleteUser(ctx, first.ID))
+
+	// 残留的唯一索引仍被已删除用户占用
+	require.Error(t, repo.Create(ctx, &model.User{Name: "Alice2", Email: "alice@example.com", Password: "x", Phone: "13800000002"}))
+
+	_, err := tool.Migrations.Up(ctx)
+	require.NoError(t, err)
+	assert.False(t, db.Migrator().HasIndex("users", "email"))
+	assert.False(t, db.Migrator().HasIndex("users", "phone"))
+
+	second := &model.User{Name: "Alice2", Email: "alice@example.com", Password: "x", Phone: "13800000001"}
+	require.NoError(t, repo.Create(ctx, second))
+	found, err := repo.GetByEmail(ctx, "alice@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, found.ID)
+
+	// 盲索引仍保证未删除用户之间的唯一性
+	assert.Error(t, repo.Create(ctx, &model.User{Name: "Alice3", Email: "alice@example.com", Password: "x"}))
+}
+
+func TestRetentionPurgesAndAnonymizesExpiredUsers(t *testing.T) {
+	db := setupSQLiteDB(t)
+	ctx := context.Background()
+	repo := repository.NewUserRepository(db)
+
+	var users []*model.User
+	for i, name := range []string{"Active", "Recent", "ExpiredA", "ExpiredB", "ExpiredC"} {
+		user := &model.User{Name: name, Email: name + "@example.com", Password: "x", Age: 30, Phone: "1380000000" + string(rune('0'+i))}
+		require.NoError(t, repo.Create(ctx, user))
+		users = append(users, user)
+	}
+	for _, user := range users[1:] {
+		require.NoError(t, repo.Delete(ctx, user.ID))
+	}
+	expired := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, db.Unscoped().Model(&model.User{}).Where("id IN ?", []uint{users[2].ID, users[3].ID, users[4].ID}).
+		UpdateColumn("deleted_at", expired).Error)
+
+	cfg := &config.RetentionConfig{
+		BatchSize: 2,
+		Policies: map[string]config.RetentionPolicy{
+			"users": {Action: config.RetentionActionAnonymize, After: 24 * time.Hour},
+		},
+	}
+	enforcer, err := retention.NewEnforcer(db, tool.Registry.Models(), cfg)
+	require.NoError(t, err)
+
+	results, err := enforcer.Enforce(ctx)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.EqualValues(t, 3, results[0].Rows)
+
+	var anonymized model.User
+	require.NoError(t, db.Unscoped().First(&anonymized, users[2].ID).Error)
+	assert.Equal(t, model.AnonymizedName, anonymized.Name)
+	assert.Empty(t, anonymized.Phone)
+	assert.Zero(t, anonymized.Age)
+	assert.NotNil(t, anonymized.AnonymizedAt)
+	assert.NotContains(t, anonymized.Email, "ExpiredA")
+
+	// 已匿名化的记录不重复处理，未超期和未删除的记录不受影响
+	results, err = enforcer.Enforce(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, results[0].Rows)
+
+	var recent model.User
+	require.NoError(t, db.Unscoped().First(&recent, users[1].ID).Error)
+	assert.Equal(t, "Recent", recent.Name)
+	assert.Nil(t, recent.AnonymizedAt)
+
+	// 永久删除策略
+	cfg.Policies["users"] = config.RetentionPolicy{Action: config.RetentionActionPurge, After: 24 * time.Hour}
+	enforcer, err = retention.NewEnforcer(db, tool.Registry.Models(), cfg)
+	require.NoError(t, err)
+	results, err = enforcer.Enforce(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, results[0].Rows)
+
+	var remaining int64
+	require.NoError(t, db.Unscoped().Model(&model.User{}).Count(&remaining).Error)
+	assert.EqualValues(t, 2, remaining)
+}
+
+func TestRetentionPolicyValidation(t *testing.T) {
+	db := setupSQLiteDB(t)
+	models := tool.Registry.Models()
+
+	for name, policies := range map[string]map[string]config.RetentionPolicy{
+		"unknown table":  {"orders": {Action: config.RetentionActionPurge, After: time.Hour}},
+		"no soft delete": {"outbox_messages": {Action: config.RetentionActionPurge, After: time.Hour}},
+		"unknown action": {"users": {Action: "archive", After: time.Hour}},
+		"missing after":  {"users": {Action: config.RetentionActionPurge}},
+	} {
+		_, err := retention.NewEnforcer(db, models, &config.RetentionConfig{Policies: policies})
+		assert.Error(t, err, name)
+	}
+}
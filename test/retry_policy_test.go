@@ -0,0 +1,151 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gin-demo/config"
+	"gin-demo/pkg/queue"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	exponential := queue.RetryPolicy{MaxAttempts: 10, Backoff: config.BackoffExponential, Delay: time.Second, MaxDelay: 10 * time.Second}
+	assert.Equal(t, time.Second, exponential.NextDelay(1))
+	assert.Equal(t, 2*time.Second, exponential.NextDelay(2))
+	assert.Equal(t, 8*time.Second, exponential.NextDelay(4))
+	assert.Equal(t, 10*time.Second, exponential.NextDelay(5))
+	assert.Equal(t, 10*time.Second, exponential.NextDelay(1000))
+
+	unbounded := queue.RetryPolicy{MaxAttempts: 1000, Backoff: config.BackoffExponential, Delay: time.Second}
+	assert.Positive(t, unbounded.NextDelay(1000), "delay must not overflow")
+
+	fixed := queue.RetryPolicy{MaxAttempts: 3, Backoff: config.BackoffFixed, Delay: time.Second}
+	assert.Equal(t, time.Second, fixed.NextDelay(3))
+
+	jitter := queue.RetryPolicy{MaxAttempts: 10, Backoff: config.BackoffExponentialJitter, Delay: time.Second, MaxDelay: 4 * time.Second}
+	for i := 0; i < 100; i++ {
+		delay := jitter.NextDelay(3)
+		assert.GreaterOrEqual(t, delay, time.Duration(0))
+		assert.Less(t, delay, 4*time.Second)
+	}
+}
+
+func TestRetryPolicyClassification(t *testing.T) {
+	policy := queue.RetryPolicy{MaxAttempts: 3, Backoff: config.BackoffFixed}
+	transient := errors.New("timeout")
+	permanent := fmt.Errorf("handle: %w", queue.Permanent(errors.New("bad address")))
+
+	assert.True(t, policy.ShouldRetry(transient, 1))
+	assert.True(t, policy.ShouldRetry(transient, 2))
+	assert.False(t, policy.ShouldRetry(transient, 3))
+	assert.False(t, policy.ShouldRetry(permanent, 1))
+	assert.True(t, queue.IsPermanent(permanent))
+	assert.Nil(t, queue.Permanent(nil))
+
+	// 自定义分类
+	policy.Retryable = func(err error) bool { return errors.Is(err, transient) }
+	assert.False(t, policy.ShouldRetry(errors.New("other"), 1))
+
+	// 永久性错误不受自定义分类影响
+	policy.Retryable = func(err error) bool { return true }
+	assert.False(t, policy.ShouldRetry(permanent, 1))
+
+	// 队列配置
+	cfg := testQueueConfig(2)
+	fromGlobal, err := queue.NewRetryPolicy(cfg, "flaky")
+	require.NoError(t, err)
+	assert.Equal(t, 3, fromGlobal.MaxAttempts)
+
+	item := cfg.Queues["flaky"]
+	item.Retry = &config.RetryPolicyConfig{MaxAttempts: 5, Backoff: "linear"}
+	cfg.Queues["flaky"] = item
+	_, err = queue.NewRetryPolicy(cfg, "flaky")
+	assert.Error(t, err)
+}
+
+// policyHandler 声明自身重试策略的处理器
+type policyHandler struct {
+	flakyHandler
+	policy queue.RetryPolicy
+	err    error
+}
+
+func (h *policyHandler) Handle(ctx context.Context, message *queue.Message) error {
+	h.handled.Add(1)
+	return h.err
+}
+
+func (h *policyHandler) RetryPolicy() queue.RetryPolicy {
+	return h.policy
+}
+
+func TestHandlerRetryPolicy(t *testing.T) {
+	forEachQueueDriver(t, testHandlerRetryPolicy)
+}
+
+func testHandlerRetryPolicy(t *testing.T, driver string) {
+	ctx := context.Background()
+
+	// 永久性错误不重试，直接进入死信队列
+	m, _ := setupQueueManager(t, driver, 5)
+	handler := &policyHandler{
+		policy: queue.RetryPolicy{MaxAttempts: 5, Backoff: config.BackoffFixed, Delay: 10 * time.Millisecond},
+		err:    queue.Permanent(errors.New("invalid recipient")),
+	}
+	require.NoError(t, m.RegisterHandler(handler))
+	message, err := queue.NewMessage("email.send", "x")
+	require.NoError(t, err)
+	require.NoError(t, m.Publish("flaky", message))
+
+	require.Eventually(t, func() bool {
+		count, _ := m.DeadLetters().Count(ctx, "flaky")
+		return count == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.EqualValues(t, 1, handler.handled.Load())
+	dead, err := m.DeadLetters().Get(ctx, "flaky", message.ID)
+	require.NoError(t, err)
+	info, _ := dead.DeadLetterInfo()
+	assert.Equal(t, queue.DeadLetterPermanentError, info.Reason)
+
+	// 处理器策略优先于全局 retry_limit
+	m, _ = setupQueueManager(t, driver, 5)
+	handler = &policyHandler{
+		policy: queue.RetryPolicy{MaxAttempts: 2, Backoff: config.BackoffExponential, Delay: 10 * time.Millisecond},
+		err:    errors.New("smtp unavailable"),
+	}
+	require.NoError(t, m.RegisterHandler(handler))
+	require.NoError(t, m.Publish("flaky", message))
+	require.Eventually(t, func() bool {
+		count, _ := m.DeadLetters().Count(ctx, "flaky")
+		return count == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.EqualValues(t, 2, handler.handled.Load())
+
+	// 自定义分类判定不可重试时不重试，死信原因与次数用尽区分
+	m, _ = setupQueueManager(t, driver, 5)
+	handler = &policyHandler{
+		policy: queue.RetryPolicy{MaxAttempts: 5, Backoff: config.BackoffFixed, Delay: 10 * time.Millisecond,
+			Retryable: func(err error) bool { return false }},
+		err: errors.New("mailbox full"),
+	}
+	require.NoError(t, m.RegisterHandler(handler))
+	require.NoError(t, m.Publish("flaky", message))
+	require.Eventually(t, func() bool {
+		count, _ := m.DeadLetters().Count(ctx, "flaky")
+		return count == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.EqualValues(t, 1, handler.handled.Load())
+	dead, err = m.DeadLetters().Get(ctx, "flaky", message.ID)
+	require.NoError(t, err)
+	info, _ = dead.DeadLetterInfo()
+	assert.Equal(t, queue.DeadLetterNonRetryable, info.Reason)
+
+	// 无效的处理器策略拒绝注册
+	m, _ = setupQueueManager(t, driver, 5)
+	assert.Error(t, m.RegisterHandler(&policyHandler{policy: queue.RetryPolicy{MaxAttempts: 0}}))
+}
@@ -0,0 +1,68 @@
+package test
+
+import (
+	"context"
+	"gin-demo/model"
+	"gin-demo/model/factory"
+	"gin-demo/model/seeder"
+	"gin-demo/model/tool"
+	"gin-demo/pkg/auth"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserFactoryGeneratesUniqueValidUsers(t *testing.T) {
+	users, err := factory.NewUsers(50)
+	require.NoError(t, err)
+
+	phonePattern := regexp.MustCompile(`^1\d{10}$`)
+	emails := make(map[string]bool)
+	phones := make(map[string]bool)
+	for _, user := range users {
+		assert.NotEmpty(t, user.Name)
+		assert.Regexp(t, `^[a-z]+\.[a-z]+\d+@example\.(com|org|net)$`, user.Email)
+		assert.True(t, phonePattern.MatchString(user.Phone), user.Phone)
+		assert.False(t, emails[user.Email], "duplicate email %s", user.Email)
+		assert.False(t, phones[user.Phone], "duplicate phone %s", user.Phone)
+		emails[user.Email] = true
+		phones[user.Phone] = true
+	}
+	assert.True(t, auth.CheckPassword(users[0].Password, factory.DefaultPassword))
+}
+
+func TestUserFactoryOverrides(t *testing.T) {
+	db := setupSQLiteDB(t)
+
+	user, err := factory.CreateUser(context.Background(), db,
+		factory.WithName("测试用户"),
+		factory.WithAge(30),
+		func(u *model.User) { u.Email = "custom@example.com" },
+	)
+	require.NoError(t, err)
+	assert.NotZero(t, user.ID)
+	assert.Equal(t, "测试用户", user.Name)
+	assert.Equal(t, 30, user.Age)
+	assert.Equal(t, "custom@example.com", user.Email)
+}
+
+func TestSeedersRunByClassAndAreRepeatable(t *testing.T) {
+	db := setupSQLiteDB(t)
+	ctx := context.Background()
+
+	assert.Contains(t, tool.Seeders.Names(), "UserSeeder")
+	assert.Error(t, tool.Seeders.Run(ctx, "MissingSeeder"))
+
+	require.NoError(t, tool.Seeders.Run(ctx, "UserSeeder"))
+	require.NoError(t, tool.Seeders.Run(ctx))
+
+	var admins int64
+	require.NoError(t, db.Model(&model.User{}).Where("email = ?", seeder.AdminEmail).Count(&admins).Error)
+	assert.Equal(t, int64(1), admins)
+
+	var total int64
+	require.NoError(t, db.Model(&model.User{}).Count(&total).Error)
+	assert.Equal(t, int64(41), total)
+}
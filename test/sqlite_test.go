@@ -0,0 +1,89 @@
+package test
+
+import (
+	"context"
+	"gin-demo/database"
+	"gin-demo/model"
+	"gin-demo/model/tool"
+	"gin-demo/repository"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// setupSQLiteDB 使用内存SQLite替换全局数据库连接，无需外部服务
+func setupSQLiteDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:?_pragma=foreign_keys(1)"), &gorm.Config{
+		Logger:                                   gormlogger.Discard,
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	require.NoError(t, err)
+
+	// 内存数据库每个连接相互独立，限制为单连接
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		sqlDB.Close()
+	})
+
+	require.NoError(t, tool.Registry.AutoMigrate())
+	return db
+}
+
+func TestSQLiteAutoMigrate(t *testing.T) {
+	db := setupSQLiteDB(t)
+
+	for _, status := range tool.Registry.GetTableStatus() {
+		assert.True(t, status.Exists, status.TableName)
+	}
+	assert.True(t, db.Migrator().HasColumn(&model.User{}, "phone"))
+}
+
+func TestSQLiteSearchEscapesWildcards(t *testing.T) {
+	db := setupSQLiteDB(t)
+	repo := repository.NewUserRepository(db)
+	ctx := context.Background()
+
+	users := []model.User{
+		{Name: "Alice", Email: "alice@example.com", Password: "x", Phone: "13800000001"},
+		{Name: "Bob_100%", Email: "bob@example.com", Password: "x", Phone: "13800000002"},
+		{Name: "Bobby", Email: "bobby@example.com", Password: "x", Phone: "13800000003"},
+	}
+	for i := range users {
+		require.NoError(t, repo.Create(ctx, &users[i]))
+	}
+
+	pagination := &tool.PaginationRequest{}
+
+	// 大小写不敏感
+	found, total, err := repo.GetAllWithPaginationAndSearch(ctx, pagination, "ALICE")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+	require.Len(t, found, 1)
+	assert.Equal(t, "Alice", found[0].Name)
+
+	// 通配符按字面匹配
+	found, total, err = repo.GetAllWithPaginationAndSearch(ctx, pagination, "b_")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+	require.Len(t, found, 1)
+	assert.Equal(t, "Bob_100%", found[0].Name)
+
+	_, total, err = repo.GetAllWithPaginationAndSearch(ctx, pagination, "%")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+
+	// 空关键词返回全部
+	_, total, err = repo.GetAllWithPaginationAndSearch(ctx, pagination, "")
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, total)
+}
@@ -0,0 +1,74 @@
+package test
+
+import (
+	"gin-demo/config"
+	"gin-demo/controller"
+	"gin-demo/pkg/logger"
+	"gin-demo/pkg/middleware"
+	"gin-demo/repository"
+	"gin-demo/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// TestSQLLogsInAccessLog 回归测试：请求链路中的SQL应出现在访问日志的 sql_details 中
+func TestSQLLogsInAccessLog(t *testing.T) {
+	// 使用DryRun模式的MySQL方言，无需真实数据库即可生成SQL并触发GORM日志
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "user:pass@tcp(127.0.0.1:3306)/test?parseTime=true",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{
+		Logger:               logger.NewGormLogger(&config.DatabaseLogConfig{Level: "info"}),
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	require.NoError(t, err)
+
+	// 捕获访问日志
+	core, logs := observer.New(zap.InfoLevel)
+	previous := logger.AccessLogger
+	logger.AccessLogger = zap.New(core)
+	defer func() { logger.AccessLogger = previous }()
+
+	userController := controller.NewUserController(service.NewUserService(repository.NewUserRepository(db)))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.AccessLogMiddleware())
+	r.GET("/api/users/:id", userController.GetUser)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users/42", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// 访问日志是异步写入的，等待日志条目出现
+	var entry observer.LoggedEntry
+	require.Eventually(t, func() bool {
+		entries := logs.FilterMessage("access").All()
+		if len(entries) == 0 {
+			return false
+		}
+		entry = entries[0]
+		return true
+	}, 2*time.Second, 10*time.Millisecond)
+
+	fields := entry.ContextMap()
+	assert.EqualValues(t, 1, fields["sql_count"])
+
+	sqlLogs, ok := fields["sql_details"].([]logger.SQLLog)
+	require.True(t, ok, "sql_details should be recorded")
+	require.Len(t, sqlLogs, 1)
+	assert.True(t, strings.Contains(sqlLogs[0].SQL, "FROM `users`"), sqlLogs[0].SQL)
+	assert.True(t, strings.Contains(sqlLogs[0].SQL, "42"), sqlLogs[0].SQL)
+}
@@ -0,0 +1,124 @@
+package test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"gin-demo/pkg/transaction"
+	"gin-demo/repository"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// newMockDB 创建基于sqlmock的GORM实例
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sqlDB,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{
+		Logger:                 gormlogger.Discard,
+		SkipDefaultTransaction: true,
+	})
+	require.NoError(t, err)
+	return db, mock
+}
+
+func TestTransactionCommitRunsAfterCommitHooks(t *testing.T) {
+	db, mock := newMockDB(t)
+	manager := transaction.NewManager(db)
+	userRepo := repository.NewUserRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT 1 FROM `users`").WillReturnRows(sqlmock.NewRows([]string{"1"}))
+	mock.ExpectCommit()
+
+	var hookCalled bool
+	err := manager.Run(context.Background(), func(ctx context.Context) error {
+		assert.True(t, transaction.InTransaction(ctx))
+		transaction.AfterCommit(ctx, func(ctx context.Context) {
+			hookCalled = true
+		})
+		assert.False(t, hookCalled, "hook must not run before commit")
+
+		// 仓储方法通过ctx自动加入事务
+		_, err := userRepo.EmailExists(ctx, "test@example.com")
+		return err
+	}, transaction.WithIsolation(sql.LevelSerializable))
+
+	require.NoError(t, err)
+	assert.True(t, hookCalled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTransactionRollbackDiscardsHooks(t *testing.T) {
+	db, mock := newMockDB(t)
+	manager := transaction.NewManager(db)
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	var hookCalled bool
+	err := manager.Run(context.Background(), func(ctx context.Context) error {
+		transaction.AfterCommit(ctx, func(ctx context.Context) {
+			hookCalled = true
+		})
+		return errors.New("boom")
+	})
+
+	assert.EqualError(t, err, "boom")
+	assert.False(t, hookCalled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTransactionNestedSavepoint(t *testing.T) {
+	db, mock := newMockDB(t)
+	manager := transaction.NewManager(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SAVEPOINT sp_2").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	var hooks []string
+	err := manager.Run(context.Background(), func(ctx context.Context) error {
+		// 内层失败只回滚到保存点，其回调被丢弃
+		innerErr := manager.Run(ctx, func(ctx context.Context) error {
+			transaction.AfterCommit(ctx, func(ctx context.Context) {
+				hooks = append(hooks, "rolled_back")
+			})
+			return errors.New("inner failed")
+		})
+		assert.Error(t, innerErr)
+
+		// 内层成功时回调随外层事务提交
+		return manager.Run(ctx, func(ctx context.Context) error {
+			transaction.AfterCommit(ctx, func(ctx context.Context) {
+				hooks = append(hooks, "committed")
+			})
+			return nil
+		})
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"committed"}, hooks)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAfterCommitWithoutTransaction(t *testing.T) {
+	var hookCalled bool
+	transaction.AfterCommit(context.Background(), func(ctx context.Context) {
+		hookCalled = true
+	})
+	assert.True(t, hookCalled)
+}
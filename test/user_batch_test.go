@@ -0,0 +1,136 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gin-demo/model"
+	"gin-demo/repository"
+	"gin-demo/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// batchData 序列化批量操作数据
+func batchData(t *testing.T, data interface{}) json.RawMessage {
+	raw, err := json.Marshal(data)
+	require.NoError(t, err)
+	return raw
+}
+
+// setupBatchUsers 创建sqlite用户服务和一个已存在的用户
+func setupBatchUsers(t *testing.T) (*service.UserService, *repository.UserRepository, *model.User) {
+	db := setupSQLiteDB(t)
+	repo := repository.NewUserRepository(db)
+	existing := &model.User{Name: "Alice", Email: "alice@example.com", Password: "x", Phone: "13800000001"}
+	require.NoError(t, repo.Create(context.Background(), existing))
+	return service.NewUserService(repo), repo, existing
+}
+
+// batchStatuses 各操作的结果状态
+func batchStatuses(response *model.BatchUserResponse) []string {
+	statuses := make([]string, 0, len(response.Results))
+	for _, result := range response.Results {
+		statuses = append(statuses, result.Status)
+	}
+	return statuses
+}
+
+func TestBatchUsersAllOrNothingRollsBack(t *testing.T) {
+	userService, repo, existing := setupBatchUsers(t)
+	ctx := context.Background()
+
+	response, err := userService.BatchUsers(ctx, &model.BatchUserRequest{
+		Mode: model.BatchModeAllOrNothing,
+		Operations: []model.BatchUserOperation{
+			{Op: model.BatchOpCreate, Data: batchData(t, model.CreateUserRequest{Name: "Bob", Email: "bob@example.com", Phone: "13800000002"})},
+			{Op: model.BatchOpUpdate, ID: existing.ID, Data: batchData(t, model.UpdateUserRequest{Name: "Alice2"})},
+			{Op: model.BatchOpUpdate, ID: 999, Data: batchData(t, model.UpdateUserRequest{Name: "Ghost"})},
+			{Op: model.BatchOpDelete, ID: existing.ID},
+		},
+	})
+	require.NoError(t, err)
+
+	// 失败操作之前的已回滚，之后的未执行
+	assert.False(t, response.Committed)
+	assert.Equal(t, []string{model.BatchStatusRolledBack, model.BatchStatusRolledBack, model.BatchStatusFailed, model.BatchStatusSkipped},
+		batchStatuses(response))
+	assert.Equal(t, 0, response.Succeeded)
+	assert.Equal(t, 1, response.Failed)
+	assert.Nil(t, response.Results[0].User)
+	assert.NotEmpty(t, response.Results[2].Error)
+
+	exists, err := repo.EmailExists(ctx, "bob@example.com")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	user, err := repo.GetByID(ctx, existing.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", user.Name)
+}
+
+func TestBatchUsersAllOrNothingRejectsInvalidOperations(t *testing.T) {
+	userService, repo, _ := setupBatchUsers(t)
+	ctx := context.Background()
+
+	// 校验失败时不开启事务，其余操作跳过
+	response, err := userService.BatchUsers(ctx, &model.BatchUserRequest{
+		Operations: []model.BatchUserOperation{
+			{Op: model.BatchOpCreate, Data: batchData(t, model.CreateUserRequest{Name: "Bob", Email: "bob@example.com", Phone: "13800000002"})},
+			{Op: model.BatchOpDelete},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, model.BatchModeAllOrNothing, response.Mode)
+	assert.False(t, response.Committed)
+	assert.Equal(t, []string{model.BatchStatusSkipped, model.BatchStatusFailed}, batchStatuses(response))
+	assert.Equal(t, 1, response.Failed)
+
+	exists, err := repo.EmailExists(ctx, "bob@example.com")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestBatchUsersBestEffortIsolatesFailures(t *testing.T) {
+	userService, repo, existing := setupBatchUsers(t)
+	ctx := context.Background()
+	other := &model.User{Name: "Carol", Email: "carol@example.com", Password: "x", Phone: "13800000003"}
+	require.NoError(t, repo.Create(ctx, other))
+
+	response, err := userService.BatchUsers(ctx, &model.BatchUserRequest{
+		Mode: model.BatchModeBestEffort,
+		Operations: []model.BatchUserOperation{
+			{Op: model.BatchOpCreate, Data: batchData(t, model.CreateUserRequest{Name: "Bob", Email: "bob@example.com", Phone: "13800000002"})},
+			// 邮箱已被占用，违反唯一约束，只回滚到该操作的保存点
+			{Op: model.BatchOpCreate, Data: batchData(t, model.CreateUserRequest{Name: "Dup", Email: "alice@example.com", Phone: "13800000004"})},
+			{Op: model.BatchOpUpdate, ID: existing.ID, Data: batchData(t, model.UpdateUserRequest{Name: "Alice2"})},
+			{Op: model.BatchOpDelete, ID: other.ID},
+			{Op: model.BatchOpUpdate, ID: 999, Data: batchData(t, model.UpdateUserRequest{Name: "Ghost"})},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.True(t, response.Committed)
+	assert.Equal(t, []string{model.BatchStatusSuccess, model.BatchStatusFailed, model.BatchStatusSuccess, model.BatchStatusSuccess, model.BatchStatusFailed},
+		batchStatuses(response))
+	assert.Equal(t, 3, response.Succeeded)
+	assert.Equal(t, 2, response.Failed)
+	require.NotNil(t, response.Results[0].User)
+	assert.Equal(t, "bob@example.com", response.Results[0].User.Email)
+	assert.NotEmpty(t, response.Results[1].Error)
+
+	// 成功的操作已提交，失败的操作没有留下数据
+	exists, err := repo.EmailExists(ctx, "bob@example.com")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = repo.PhoneExists(ctx, "13800000004")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	user, err := repo.GetByID(ctx, existing.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice2", user.Name)
+	_, err = repo.GetByID(ctx, other.ID)
+	assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+}
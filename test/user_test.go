@@ -1,8 +1,11 @@
 package test
 
 import (
+	"context"
 	"fmt"
+	"gin-demo/database"
 	"gin-demo/model"
+	"gin-demo/repository"
 	"gin-demo/service"
 	"testing"
 )
@@ -11,8 +14,8 @@ func TestCreateUser(t *testing.T) {
 	cleanup := SetupTest(t)
 	defer cleanup() // 确保测试结束后清理资源
 
-	userService := service.NewUserService()
-	userInfo, err := userService.CreateUser(&model.CreateUserRequest{
+	userService := service.NewUserService(repository.NewUserRepository(database.DB))
+	userInfo, err := userService.CreateUser(context.Background(), &model.CreateUserRequest{
 		Name:  "test",
 		Email: "daichongweb@foxmail.com",
 		Age:   10,
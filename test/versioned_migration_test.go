@@ -0,0 +1,127 @@
+package test
+
+import (
+	"context"
+	"gin-demo/model/tool"
+	"gin-demo/pkg/migration"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// newTestMigrator 创建包含一个SQL迁移和一个Go迁移的执行器
+func newTestMigrator(t *testing.T, files fstest.MapFS) *migration.Migrator {
+	migrator := migration.NewMigrator()
+	migrator.Register(&migration.Migration{
+		Version: "20260102000000",
+		Name:    "backfill_users_age",
+		Up: func(tx *gorm.DB) error {
+			return tx.Exec("UPDATE users SET age = 18 WHERE age = 0").Error
+		},
+	})
+	require.NoError(t, migrator.LoadFS(files))
+	return migrator
+}
+
+func TestVersionedMigrationsUpDownAndStatus(t *testing.T) {
+	db := setupSQLiteDB(t)
+	ctx := context.Background()
+
+	files := fstest.MapFS{
+		"20260101000000_add_users_nickname.up.sql":   {Data: []byte("-- 新增昵称\nALTER TABLE users ADD COLUMN nickname varchar(64);\n")},
+		"20260101000000_add_users_nickname.down.sql": {Data: []byte("ALTER TABLE users DROP COLUMN nickname;\n")},
+		"README.md": {Data: []byte("ignored")},
+	}
+	migrator := newTestMigrator(t, files)
+
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.Equal(t, migration.MigrationStatusPending, statuses[0].Status)
+
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, applied)
+	assert.True(t, db.Migrator().HasColumn("users", "nickname"))
+
+	applied, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, applied)
+
+	// Go迁移没有Down，不可回滚
+	_, err = migrator.Down(ctx, 1)
+	assert.ErrorContains(t, err, "不可回滚")
+
+	// 修改已执行迁移的内容后拒绝执行
+	files["20260101000000_add_users_nickname.up.sql"] = &fstest.MapFile{Data: []byte("ALTER TABLE users ADD COLUMN nick varchar(64);\n")}
+	modified := newTestMigrator(t, files)
+	statuses, err = modified.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, migration.MigrationStatusModified, statuses[0].Status)
+	_, err = modified.Up(ctx)
+	assert.ErrorContains(t, err, "内容已修改")
+}
+
+func TestVersionedMigrationsRedoAndMissing(t *testing.T) {
+	db := setupSQLiteDB(t)
+	ctx := context.Background()
+
+	migrator := migration.NewMigrator()
+	require.NoError(t, migrator.LoadFS(fstest.MapFS{
+		"20260101000000_create_notes.up.sql":   {Data: []byte("CREATE TABLE notes (id integer primary key);\nCREATE INDEX idx_notes_id ON notes (id);\n")},
+		"20260101000000_create_notes.down.sql": {Data: []byte("DROP TABLE notes;\n")},
+	}))
+
+	_, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	require.NoError(t, migrator.Redo(ctx))
+	assert.True(t, db.Migrator().HasTable("notes"))
+
+	rolledBack, err := migrator.Down(ctx, 5)
+	require.NoError(t, err)
+	assert.Equal(t, 1, rolledBack)
+	assert.False(t, db.Migrator().HasTable("notes"))
+
+	// 已执行但文件已删除的迁移显示为missing
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	statuses, err := migration.NewMigrator().Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.Equal(t, migration.MigrationStatusMissing, statuses[0].Status)
+}
+
+func TestBundledMigrationsApplyOnSQLite(t *testing.T) {
+	setupSQLiteDB(t)
+	ctx := context.Background()
+
+	_, err := tool.Migrations.Up(ctx)
+	require.NoError(t, err)
+	_, err = tool.Migrations.Down(ctx, 100)
+	require.NoError(t, err)
+}
+
+func TestCreateMigrationFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	files, err := migration.CreateMigration(dir, "add_users_nickname")
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	for _, file := range files {
+		_, err := os.Stat(file)
+		assert.NoError(t, err)
+	}
+
+	migrator := migration.NewMigrator()
+	require.NoError(t, migrator.LoadFS(os.DirFS(dir)))
+
+	_, err = migration.CreateMigration(dir, "Bad Name")
+	assert.Error(t, err)
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.sql"))
+	assert.Len(t, matches, 2)
+}